@@ -0,0 +1,69 @@
+package configtest
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/yvyrovyi-cinemo/utils/config"
+)
+
+// SecretProvider is a local stand-in of a secret store, e.g. Vault, for config.WithSecretProvider.
+// The secrets are kept by their references without the scheme, "path" or "path#key",
+// they may be rotated by Set.
+type SecretProvider struct {
+	mux     sync.Mutex
+	secrets map[string]string
+	reads   int
+}
+
+func NewSecretProvider(secrets map[string]string) *SecretProvider {
+	p := &SecretProvider{secrets: make(map[string]string, len(secrets))}
+	for ref, value := range secrets {
+		p.secrets[ref] = value
+	}
+
+	return p
+}
+
+// Set adds or rotates the secret.
+func (p *SecretProvider) Set(ref, value string) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	p.secrets[ref] = value
+}
+
+// Delete removes the secret, it is not found anymore.
+func (p *SecretProvider) Delete(ref string) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	delete(p.secrets, ref)
+}
+
+// Reads returns how many times the secrets were resolved.
+func (p *SecretProvider) Reads() int {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	return p.reads
+}
+
+func (p *SecretProvider) Resolve(ref config.SecretRef) (string, error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	p.reads++
+
+	key := ref.Path
+	if ref.Key != "" {
+		key += "#" + ref.Key
+	}
+
+	value, ok := p.secrets[key]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", config.ErrSecretNotFound, key)
+	}
+
+	return value, nil
+}
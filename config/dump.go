@@ -0,0 +1,156 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Redacted replaces the values of the secret fields in the dumps.
+const Redacted = "[REDACTED]"
+
+type dumpField struct {
+	Path   string `json:"path"`
+	Value  any    `json:"value"`
+	Source string `json:"source,omitempty"`
+}
+
+// Dump prints the config as YAML with the source of every field in its line comment, see WithSources.
+// The sources may be nil. The non-empty secret fields, tagged secret:"true", are redacted.
+func Dump(cfg any, sources Sources) ([]byte, error) {
+	fields, err := dumpFields(cfg, sources)
+	if err != nil {
+		return nil, err
+	}
+
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	for _, f := range fields {
+		node := &yaml.Node{}
+		if err := node.Encode(f.Value); err != nil {
+			return nil, fmt.Errorf("encoding %s: %w", f.Path, err)
+		}
+
+		key := addField(root, strings.Split(f.Path, "."), node)
+		// the comment of a block value would be put after its first item, so it is put after the key
+		if node.Kind == yaml.ScalarNode || node.Style&yaml.FlowStyle != 0 {
+			node.LineComment = f.Source
+		} else {
+			key.LineComment = f.Source
+		}
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(root); err != nil {
+		return nil, fmt.Errorf("encoding YAML: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("encoding YAML: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// DumpJSON prints the config fields as a JSON array of their paths, values and sources, see Dump.
+func DumpJSON(cfg any, sources Sources) ([]byte, error) {
+	fields, err := dumpFields(cfg, sources)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := json.MarshalIndent(fields, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encoding JSON: %w", err)
+	}
+
+	return res, nil
+}
+
+// Handler serves the dump of the config, as JSON if requested with ?format=json or the Accept header.
+// The config must be a pointer, so the handler serves its current values.
+func Handler(cfg any, sources Sources) http.Handler {
+	return handler(func() (any, Sources) { return cfg, sources })
+}
+
+func handler(get func() (any, Sources)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		dump, contentType := Dump, "application/yaml"
+		if r.URL.Query().Get("format") == "json" || strings.Contains(r.Header.Get("Accept"), "application/json") {
+			dump, contentType = DumpJSON, "application/json"
+		}
+
+		data, err := dump(get())
+		if err != nil {
+			http.Error(w, fmt.Sprintf("dumping config: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		_, _ = w.Write(data)
+	})
+}
+
+func dumpFields(cfg any, sources Sources) ([]dumpField, error) {
+	value := reflect.ValueOf(cfg)
+	if value.Kind() == reflect.Pointer && !value.IsNil() {
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return nil, ErrNotStructPointer
+	}
+
+	fields := leafFields(value.Type())
+	res := make([]dumpField, 0, len(fields))
+	for _, f := range fields {
+		res = append(res, dumpField{
+			Path:   f.path,
+			Value:  dumpValue(value.FieldByIndex(f.index), f.secret),
+			Source: sources[f.path].String(),
+		})
+	}
+
+	return res, nil
+}
+
+// addField adds the value to the mapping by the path of the keys, the missing mappings are created.
+// It returns the key of the value.
+func addField(mapping *yaml.Node, names []string, value *yaml.Node) *yaml.Node {
+	for _, name := range names[:len(names)-1] {
+		var next *yaml.Node
+		for i := 0; i+1 < len(mapping.Content); i += 2 {
+			if mapping.Content[i].Value == name {
+				next = mapping.Content[i+1]
+				break
+			}
+		}
+
+		if next == nil {
+			next = &yaml.Node{Kind: yaml.MappingNode}
+			mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: name}, next)
+		}
+		mapping = next
+	}
+
+	key := &yaml.Node{Kind: yaml.ScalarNode, Value: names[len(names)-1]}
+	mapping.Content = append(mapping.Content, key, value)
+
+	return key
+}
+
+func dumpValue(value reflect.Value, secret bool) any {
+	switch {
+	case secret && !value.IsZero():
+		return Redacted
+	case value.Type() == durationType:
+		return time.Duration(value.Int()).String()
+	default:
+		return value.Interface()
+	}
+}
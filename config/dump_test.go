@@ -0,0 +1,114 @@
+package config
+
+import (
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type dumpedConfig struct {
+	Layered layeredConfig `yaml:",inline"`
+
+	Password string `env:"PASSWORD" json:"password" yaml:"password" secret:"true"`
+	Token    string `env:"TOKEN" json:"token" yaml:"token" secret:"true"`
+}
+
+func TestSources(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	writeFile(t, path, `{"name": "file", "debug": false, "kafka": {"brokers": ["a:9092"]}}`)
+
+	var cfg layeredConfig
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags := NewFlags(fs, &cfg)
+	require.NoError(t, fs.Parse([]string{"-kafka.brokers", "b:9092"}))
+
+	var sources Sources
+	require.NoError(t, Load(&cfg, WithFiles(path), WithFlags(flags), WithSources(&sources), WithEnvironment(map[string]string{
+		"KAFKA_GROUP_ID": "env",
+	})))
+
+	require.Equal(t, Sources{
+		"name":           {Layer: LayerFile, Name: path},
+		"timeout":        {Layer: LayerDefaults},
+		"debug":          {Layer: LayerFile, Name: path},
+		"kafka.brokers":  {Layer: LayerFlags, Name: "-kafka.brokers"},
+		"kafka.group_id": {Layer: LayerEnv, Name: "KAFKA_GROUP_ID"},
+	}, sources)
+}
+
+func TestDump(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, path, "name: file\nkafka:\n  brokers: [a:9092]\nlabels:\n  app: file\npassword: secret\n")
+
+	var (
+		cfg     dumpedConfig
+		sources Sources
+	)
+	require.NoError(t, Load(&cfg, WithFiles(path), WithSources(&sources), WithEnvironment(map[string]string{
+		"KAFKA_GROUP_ID": "env",
+	})))
+
+	data, err := Dump(&cfg, sources)
+	require.NoError(t, err)
+	require.Equal(t, `name: file # file `+path+`
+timeout: 1s # defaults
+debug: false
+kafka:
+  brokers: # file `+path+`
+    - a:9092
+  group_id: env # env KAFKA_GROUP_ID
+labels: # file `+path+`
+  app: file
+password: '[REDACTED]' # file `+path+`
+token: ""
+`, string(data))
+
+	data, err = DumpJSON(&cfg, sources)
+	require.NoError(t, err)
+
+	var fields []dumpField
+	require.NoError(t, json.Unmarshal(data, &fields))
+	require.Len(t, fields, 8)
+	require.Equal(t, dumpField{Path: "timeout", Value: "1s", Source: "defaults"}, fields[1])
+	require.Equal(t, dumpField{Path: "password", Value: Redacted, Source: "file " + path}, fields[6])
+
+	_, err = Dump("config", nil)
+	require.ErrorIs(t, err, ErrNotStructPointer)
+}
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+
+	var (
+		cfg     dumpedConfig
+		sources Sources
+	)
+	require.NoError(t, Load(&cfg, WithSources(&sources), WithEnvironment(map[string]string{"TOKEN": "secret"})))
+
+	srv := httptest.NewServer(Handler(&cfg, sources))
+	defer srv.Close()
+
+	for query, contentType := range map[string]string{"": "application/yaml", "?format=json": "application/json"} {
+		resp, err := http.Get(srv.URL + query) //nolint:noctx // test
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, contentType, resp.Header.Get("Content-Type"))
+	}
+
+	rec := httptest.NewRecorder()
+	Handler(&cfg, sources).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/config", nil))
+	require.Contains(t, rec.Body.String(), "token: '[REDACTED]' # env TOKEN\n")
+	require.NotContains(t, rec.Body.String(), "secret")
+}
@@ -0,0 +1,205 @@
+package config
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// field is a config leaf field, the structs are walked through.
+type field struct {
+	// path is the dot-separated path of the yaml names
+	path string
+	// envKey is the env var name with the envPrefix of the parent structs, empty without the env tag
+	envKey string
+	// secret fields, tagged secret:"true", are redacted by Dump and not registered by NewFlags
+	secret bool
+	index  []int
+	typ    reflect.Type
+}
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// leafFields lists the leaf fields of the struct type in the field order.
+func leafFields(structType reflect.Type) []field {
+	var res []field
+	walkFields(structType, "", "", nil, &res)
+
+	return res
+}
+
+func walkFields(structType reflect.Type, path, envPrefix string, index []int, res *[]field) {
+	for i := 0; i < structType.NumField(); i++ {
+		structField := structType.Field(i)
+		// the exported fields of the unexported embedded structs can be set
+		if !structField.IsExported() && !(structField.Anonymous && isStruct(structField.Type)) {
+			continue
+		}
+
+		f := field{
+			path:   joinPath(path, structField),
+			secret: structField.Tag.Get("secret") == "true",
+			index:  append(append([]int(nil), index...), i),
+			typ:    structField.Type,
+		}
+
+		if isStruct(f.typ) {
+			walkFields(f.typ, f.path, envPrefix+structField.Tag.Get("envPrefix"), f.index, res)
+			continue
+		}
+
+		if key, _, _ := strings.Cut(structField.Tag.Get("env"), ","); key != "" {
+			f.envKey = envPrefix + key
+		}
+
+		*res = append(*res, f)
+	}
+}
+
+// isStruct reports whether the fields of the type are walked through.
+func isStruct(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && !reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+// canSetString reports whether setString supports the type.
+func canSetString(t reflect.Type) bool {
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return true
+	}
+
+	switch t.Kind() {
+	case reflect.String, reflect.Bool, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	case reflect.Slice:
+		return t.Elem().Kind() != reflect.Slice && canSetString(t.Elem())
+	default:
+		return false
+	}
+}
+
+// setString parses s into the value as the env vars are parsed, the slices are comma-separated.
+func setString(value reflect.Value, s string) error {
+	if value.CanAddr() {
+		if u, ok := value.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			return u.UnmarshalText([]byte(s)) //nolint:wrapcheck // the caller adds the field path
+		}
+	}
+
+	switch {
+	case value.Type() == durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err //nolint:wrapcheck // the caller adds the field path
+		}
+		value.SetInt(int64(d))
+		return nil
+
+	case value.Kind() == reflect.Slice:
+		return setSlice(value, s)
+
+	default:
+		return setScalar(value, s)
+	}
+}
+
+func setSlice(value reflect.Value, s string) error {
+	items := strings.Split(s, ",")
+
+	res := reflect.MakeSlice(value.Type(), len(items), len(items))
+	for i, item := range items {
+		if err := setString(res.Index(i), strings.TrimSpace(item)); err != nil {
+			return err
+		}
+	}
+	value.Set(res)
+
+	return nil
+}
+
+func setScalar(value reflect.Value, s string) error {
+	var err error
+
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(s)
+	case reflect.Bool:
+		var b bool
+		b, err = strconv.ParseBool(s)
+		value.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var n int64
+		n, err = strconv.ParseInt(s, 10, value.Type().Bits())
+		value.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var n uint64
+		n, err = strconv.ParseUint(s, 10, value.Type().Bits())
+		value.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		var n float64
+		n, err = strconv.ParseFloat(s, value.Type().Bits())
+		value.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported type %s", value.Type())
+	}
+
+	return err //nolint:wrapcheck // the caller adds the field path
+}
+
+// deepCopy copies the value with its maps and slices, so that the copy can be changed independently.
+func deepCopy(value reflect.Value) reflect.Value {
+	res := reflect.New(value.Type()).Elem()
+	copyValue(res, value)
+
+	return res
+}
+
+func copyValue(dst, src reflect.Value) {
+	switch src.Kind() {
+	case reflect.Struct:
+		dst.Set(src)
+		for i := 0; i < src.NumField(); i++ {
+			if dst.Field(i).CanSet() {
+				copyValue(dst.Field(i), src.Field(i))
+			}
+		}
+
+	case reflect.Slice:
+		if src.IsNil() {
+			return
+		}
+		dst.Set(reflect.MakeSlice(src.Type(), src.Len(), src.Len()))
+		for i := 0; i < src.Len(); i++ {
+			copyValue(dst.Index(i), src.Index(i))
+		}
+
+	case reflect.Map:
+		if src.IsNil() {
+			return
+		}
+		dst.Set(reflect.MakeMapWithSize(src.Type(), src.Len()))
+		iter := src.MapRange()
+		for iter.Next() {
+			value := reflect.New(src.Type().Elem()).Elem()
+			copyValue(value, iter.Value())
+			dst.SetMapIndex(iter.Key(), value)
+		}
+
+	case reflect.Pointer:
+		if src.IsNil() {
+			return
+		}
+		dst.Set(reflect.New(src.Type().Elem()))
+		copyValue(dst.Elem(), src.Elem())
+
+	default:
+		dst.Set(src)
+	}
+}
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+type (
+	// Flags collects the config fields set on the command line, they are applied by Load with WithFlags.
+	Flags struct {
+		fields map[string]field
+
+		mux    sync.Mutex
+		values map[string]string
+	}
+
+	flagValue struct {
+		flags *Flags
+		path  string
+		bool  bool
+	}
+)
+
+// NewFlags registers a flag for every config field of a string, number, bool or slice type
+// on the flag set. The flags are named after the yaml paths of the fields, e.g. -kafka.brokers.
+// The secret fields are skipped, the command line is visible to other processes.
+func NewFlags(fs *flag.FlagSet, cfg any) *Flags {
+	f := &Flags{
+		fields: make(map[string]field),
+		values: make(map[string]string),
+	}
+
+	for _, leaf := range leafFields(reflect.TypeOf(cfg).Elem()) {
+		if leaf.secret || !canSetString(leaf.typ) || fs.Lookup(leaf.path) != nil {
+			continue
+		}
+
+		usage := fmt.Sprintf("config %s (%s)", leaf.path, leaf.typ)
+		if leaf.envKey != "" {
+			usage += ", overrides $" + leaf.envKey
+		}
+
+		f.fields[leaf.path] = leaf
+		fs.Var(&flagValue{flags: f, path: leaf.path, bool: leaf.typ.Kind() == reflect.Bool}, leaf.path, usage)
+	}
+
+	return f
+}
+
+func (v *flagValue) String() string {
+	if v.flags == nil {
+		return ""
+	}
+
+	v.flags.mux.Lock()
+	defer v.flags.mux.Unlock()
+
+	return v.flags.values[v.path]
+}
+
+func (v *flagValue) Set(s string) error {
+	// the value is checked now to report it with the flag
+	if err := setString(reflect.New(v.flags.fields[v.path].typ).Elem(), s); err != nil {
+		return err
+	}
+
+	v.flags.mux.Lock()
+	defer v.flags.mux.Unlock()
+
+	v.flags.values[v.path] = s
+
+	return nil
+}
+
+func (v *flagValue) IsBoolFlag() bool {
+	return v.bool
+}
+
+// apply sets the fields in the order of their paths.
+func (f *Flags) apply(value reflect.Value, sources Sources) error {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	paths := make([]string, 0, len(f.values))
+	for path := range f.values {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		if err := setString(value.FieldByIndex(f.fields[path].index), f.values[path]); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		sources[path] = Source{Layer: LayerFlags, Name: "-" + path}
+	}
+
+	return nil
+}
@@ -4,5 +4,11 @@ go 1.22
 
 require (
 	github.com/caarlos0/env/v10 v10.0.0
+	github.com/stretchr/testify v1.9.0
 	gopkg.in/yaml.v3 v3.0.1
 )
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+)
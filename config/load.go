@@ -0,0 +1,311 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/caarlos0/env/v10"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	LayerDefaults = "defaults"
+	LayerFile     = "file"
+	LayerEnv      = "env"
+	LayerFlags    = "flags"
+	LayerSecrets  = "secrets"
+)
+
+type (
+	// Option configures Load.
+	Option func(*loader)
+
+	// LayerError is the error of a config layer, Source is the file path of the file layers.
+	LayerError struct {
+		Layer  string
+		Source string
+		Err    error
+	}
+
+	// LoadError lists the errors of all the failed layers in the order of their precedence.
+	LoadError struct {
+		Errors []*LayerError
+	}
+
+	loader struct {
+		files           []string
+		environment     map[string]string
+		flags           *Flags
+		secretProviders map[string]SecretProvider
+		sources         *Sources
+	}
+)
+
+var ErrNotStructPointer = errors.New("config must be a pointer to a struct")
+
+// WithFiles adds the JSON or YAML files, every file overrides the fields set by the previous ones.
+// The CONFIG_FILE env var replaces the files, it may list several comma-separated ones.
+func WithFiles(paths ...string) Option {
+	return func(l *loader) {
+		for _, path := range paths {
+			if path = strings.TrimSpace(path); path != "" {
+				l.files = append(l.files, path)
+			}
+		}
+	}
+}
+
+// WithEnvironment replaces the process env vars, e.g. in tests.
+func WithEnvironment(environment map[string]string) Option {
+	return func(l *loader) {
+		l.environment = environment
+	}
+}
+
+// WithFlags applies the flags set on the command line, see NewFlags.
+func WithFlags(flags *Flags) Option {
+	return func(l *loader) {
+		l.flags = flags
+	}
+}
+
+func (e *LayerError) Error() string {
+	if e.Source == "" {
+		return e.Layer + ": " + e.Err.Error()
+	}
+
+	return e.Layer + " " + e.Source + ": " + e.Err.Error()
+}
+
+func (e *LayerError) Unwrap() error {
+	return e.Err
+}
+
+func (e *LoadError) Error() string {
+	messages := make([]string, 0, len(e.Errors))
+	for _, layerErr := range e.Errors {
+		messages = append(messages, layerErr.Error())
+	}
+
+	return "loading config: " + strings.Join(messages, "; ")
+}
+
+func (e *LoadError) Unwrap() []error {
+	res := make([]error, 0, len(e.Errors))
+	for _, layerErr := range e.Errors {
+		res = append(res, layerErr)
+	}
+
+	return res
+}
+
+// Load fills cfg from the layers, every layer overrides the fields set by the previous ones:
+// the envDefault tags, the files, the env vars and the flags. The fields set by none of the layers
+// keep their values. The secret references in the secret string fields, e.g. env://OTHER_VAR,
+// are resolved last, see WithSecretProvider. Then the config is validated, see Validate.
+// The layer that set every field is recorded, see WithSources.
+//
+// All the layers are applied even if some of them fail, cfg is changed only when all of them succeed.
+// Otherwise *LoadError is returned listing the errors of every failed layer.
+func Load(cfg any, opts ...Option) error {
+	target := reflect.ValueOf(cfg)
+	if target.Kind() != reflect.Pointer || target.IsNil() || target.Elem().Kind() != reflect.Struct {
+		return ErrNotStructPointer
+	}
+
+	l := newLoader(opts)
+
+	res := deepCopy(target.Elem())
+	fields := leafFields(res.Type())
+	sources := make(Sources)
+
+	var errs []*LayerError
+	addErr := func(layer, source string, err error) {
+		if err != nil {
+			errs = append(errs, &LayerError{Layer: layer, Source: source, Err: err})
+		}
+	}
+
+	addErr(LayerDefaults, "", applyEnv(res, fields, map[string]string{}, true, sources))
+	for _, path := range l.files {
+		addErr(LayerFile, path, applyFile(res, fields, path, sources))
+	}
+	addErr(LayerEnv, "", applyEnv(res, fields, l.environment, false, sources))
+	if l.flags != nil {
+		addErr(LayerFlags, "", l.flags.apply(res, sources))
+	}
+	addErr(LayerSecrets, "", l.resolveSecrets(res, fields, sources))
+
+	if len(errs) > 0 {
+		return &LoadError{Errors: errs}
+	}
+
+	target.Elem().Set(res)
+	if l.sources != nil {
+		*l.sources = sources
+	}
+
+	return Validate(cfg)
+}
+
+func newLoader(opts []Option) *loader {
+	l := &loader{secretProviders: map[string]SecretProvider{SecretSchemeFile: fileSecretProvider{}}}
+	for _, opt := range opts {
+		opt(l)
+	}
+	if l.environment == nil {
+		l.environment = environ()
+	}
+	if _, ok := l.secretProviders[SecretSchemeEnv]; !ok {
+		l.secretProviders[SecretSchemeEnv] = envSecretProvider(l.environment)
+	}
+	if files := l.environment[configFileEnvVarName]; files != "" {
+		l.files = nil
+		WithFiles(strings.Split(files, ",")...)(l)
+	}
+
+	return l
+}
+
+// applyEnv sets the fields of the env vars, or the envDefault values of the zero fields with defaults.
+func applyEnv(
+	value reflect.Value, fields []field, environment map[string]string, defaults bool, sources Sources,
+) error {
+	set := make(map[string]bool)
+	parsed := reflect.New(value.Type())
+
+	err := env.ParseWithOptions(parsed.Interface(), env.Options{
+		Environment: environment,
+		OnSet: func(key string, v interface{}, isDefault bool) {
+			if isDefault == defaults && v != "" {
+				set[key] = true
+			}
+		},
+	})
+	if defaults {
+		// the required and notEmpty options are checked by the env layer
+		err = withoutMissingEnvErrors(err)
+	}
+	if err != nil {
+		return err //nolint:wrapcheck // the layer is added by Load
+	}
+
+	for _, f := range fields {
+		if f.envKey == "" || !set[f.envKey] {
+			continue
+		}
+
+		dst := value.FieldByIndex(f.index)
+		if defaults && !dst.IsZero() {
+			continue
+		}
+		dst.Set(parsed.Elem().FieldByIndex(f.index))
+
+		if defaults {
+			sources[f.path] = Source{Layer: LayerDefaults}
+		} else {
+			sources[f.path] = Source{Layer: LayerEnv, Name: f.envKey}
+		}
+	}
+
+	return nil
+}
+
+func withoutMissingEnvErrors(err error) error {
+	var aggregateErr env.AggregateError
+	if !errors.As(err, &aggregateErr) {
+		return err
+	}
+
+	var errs []error
+	for _, fieldErr := range aggregateErr.Errors {
+		var notSetErr env.EnvVarIsNotSetError
+		var emptyErr env.EmptyEnvVarError
+		if !errors.As(fieldErr, &notSetErr) && !errors.As(fieldErr, &emptyErr) {
+			errs = append(errs, fieldErr)
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return env.AggregateError{Errors: errs}
+}
+
+// applyFile decodes the file over the value. The JSON files are tried to be decoded as YAML too,
+// so a failed attempt does not leave the value partially changed.
+func applyFile(value reflect.Value, fields []field, path string, sources Sources) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading file: %w", err)
+	}
+
+	res, err := decodeFile(value, data)
+	if err != nil {
+		return err
+	}
+
+	// the fields are set by the file if they are present in it, or changed by it when their json names are used
+	var raw any
+	_ = yaml.Unmarshal(data, &raw)
+
+	for _, f := range fields {
+		before, after := value.FieldByIndex(f.index).Interface(), res.FieldByIndex(f.index).Interface()
+		if hasPath(raw, f.path) || !reflect.DeepEqual(before, after) {
+			sources[f.path] = Source{Layer: LayerFile, Name: path}
+		}
+	}
+	value.Set(res)
+
+	return nil
+}
+
+func decodeFile(value reflect.Value, data []byte) (reflect.Value, error) {
+	var errs []error
+
+	if json.Valid(data) {
+		res := deepCopy(value)
+		err := json.Unmarshal(data, res.Addr().Interface())
+		if err == nil {
+			return res, nil
+		}
+		errs = append(errs, fmt.Errorf("parsing JSON: %w", err))
+	}
+
+	res := deepCopy(value)
+	if err := yaml.Unmarshal(data, res.Addr().Interface()); err != nil {
+		return reflect.Value{}, errors.Join(append(errs, fmt.Errorf("parsing YAML: %w", err))...)
+	}
+
+	return res, nil
+}
+
+func hasPath(raw any, path string) bool {
+	for _, name := range strings.Split(path, ".") {
+		m, ok := raw.(map[string]any)
+		if !ok {
+			return false
+		}
+		if raw, ok = m[name]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+func environ() map[string]string {
+	res := make(map[string]string)
+	for _, kv := range os.Environ() {
+		if key, value, ok := strings.Cut(kv, "="); ok {
+			res[key] = value
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,140 @@
+package config
+
+import (
+	"flag"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type (
+	layeredConfig struct {
+		Name    string            `env:"NAME" envDefault:"default" json:"name" yaml:"name"`
+		Timeout time.Duration     `env:"TIMEOUT" envDefault:"1s" json:"timeout" yaml:"timeout"`
+		Debug   bool              `env:"DEBUG" json:"debug" yaml:"debug"`
+		Kafka   layeredKafka      `envPrefix:"KAFKA_" json:"kafka" yaml:"kafka"`
+		Labels  map[string]string `json:"labels" yaml:"labels"`
+	}
+
+	layeredKafka struct {
+		Brokers []string `env:"BROKERS" json:"brokers" yaml:"brokers"`
+		GroupID string   `env:"GROUP_ID" json:"group_id" yaml:"group_id"`
+	}
+)
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	base := filepath.Join(dir, "base.yaml")
+	writeFile(t, base, "name: base\nkafka:\n  brokers: [a:9092]\n  group_id: base\nlabels:\n  app: base\n  team: core\n")
+	overlay := filepath.Join(dir, "prod.json")
+	writeFile(t, overlay, `{"kafka": {"group_id": "prod"}, "labels": {"app": "prod"}}`)
+
+	var cfg layeredConfig
+	require.NoError(t, Load(&cfg, WithFiles(base, overlay), WithEnvironment(map[string]string{
+		"NAME":          "env",
+		"KAFKA_BROKERS": "",
+	})))
+
+	require.Equal(t, layeredConfig{
+		Name:    "env",
+		Timeout: time.Second,
+		Kafka:   layeredKafka{Brokers: []string{"a:9092"}, GroupID: "prod"},
+		Labels:  map[string]string{"app": "prod", "team": "core"},
+	}, cfg)
+}
+
+func TestLoad_Flags(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, path, "timeout: 5s\n")
+
+	var cfg layeredConfig
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	flags := NewFlags(fs, &cfg)
+	require.NoError(t, fs.Parse([]string{"-debug", "-kafka.brokers", "a:9092, b:9092", "-name", "flag"}))
+	require.Error(t, fs.Parse([]string{"-timeout", "soon"}))
+	require.Nil(t, fs.Lookup("labels"))
+
+	require.NoError(t, Load(&cfg, WithFiles(path), WithFlags(flags), WithEnvironment(map[string]string{
+		"NAME":           "env",
+		"KAFKA_GROUP_ID": "env",
+	})))
+
+	require.Equal(t, layeredConfig{
+		Name:    "flag",
+		Timeout: 5 * time.Second,
+		Debug:   true,
+		Kafka:   layeredKafka{Brokers: []string{"a:9092", "b:9092"}, GroupID: "env"},
+	}, cfg)
+}
+
+func TestLoad_Errors(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	invalid := filepath.Join(dir, "invalid.json")
+	writeFile(t, invalid, `{"name": "x", "timeout": "soon"}`)
+	missing := filepath.Join(dir, "missing.yaml")
+
+	cfg := layeredConfig{Name: "initial", Labels: map[string]string{"app": "initial"}}
+	err := Load(&cfg, WithFiles(invalid, missing), WithEnvironment(map[string]string{"DEBUG": "maybe"}))
+
+	var loadErr *LoadError
+	require.ErrorAs(t, err, &loadErr)
+
+	layers := make([]string, 0, len(loadErr.Errors))
+	for _, layerErr := range loadErr.Errors {
+		layers = append(layers, layerErr.Layer+" "+layerErr.Source)
+	}
+	require.Equal(t, []string{"file " + invalid, "file " + missing, "env "}, layers)
+
+	// the config is not changed partially
+	require.Equal(t, layeredConfig{Name: "initial", Labels: map[string]string{"app": "initial"}}, cfg)
+
+	require.ErrorIs(t, Load(cfg), ErrNotStructPointer)
+}
+
+func TestLoad_Required(t *testing.T) {
+	t.Parallel()
+
+	var cfg struct {
+		Host string `env:"HOST,required"`
+		Port string `env:"PORT,notEmpty" envDefault:"8080"`
+		Name string `env:"NAME,notEmpty"`
+	}
+
+	require.NoError(t, Load(&cfg, WithEnvironment(map[string]string{"HOST": "example", "NAME": "app"})))
+	require.Equal(t, "example", cfg.Host)
+	require.Equal(t, "8080", cfg.Port)
+
+	err := Load(&cfg, WithEnvironment(map[string]string{}))
+	require.ErrorContains(t, err, `loading config: env: `)
+	require.ErrorContains(t, err, `required environment variable "HOST" is not set`)
+	require.NotContains(t, err.Error(), "defaults")
+}
+
+func TestLoad_ConfigFileEnv(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	base := filepath.Join(dir, "base.yaml")
+	writeFile(t, base, "name: base\ndebug: true\n")
+	overlay := filepath.Join(dir, "overlay.yaml")
+	writeFile(t, overlay, "name: overlay\n")
+
+	var cfg layeredConfig
+	require.NoError(t, Load(&cfg, WithFiles(filepath.Join(dir, "ignored.yaml")), WithEnvironment(map[string]string{
+		"CONFIG_FILE": base + "," + overlay,
+	})))
+
+	require.Equal(t, "overlay", cfg.Name)
+	require.True(t, cfg.Debug)
+}
@@ -0,0 +1,137 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	SecretSchemeFile = "file"
+	SecretSchemeEnv  = "env"
+)
+
+type (
+	// SecretProvider resolves the secret references of a scheme, e.g. vault://path#key, see WithSecretProvider.
+	SecretProvider interface {
+		Resolve(ref SecretRef) (string, error)
+	}
+
+	// SecretRef is a reference to a secret, scheme://path#key.
+	SecretRef struct {
+		Scheme string
+		Path   string
+		Key    string
+	}
+
+	// fileSecretProvider reads the file of file:///run/secrets/x. With the key, file:///run/secrets/x#key,
+	// the file is a JSON or YAML object and the secret is its field.
+	fileSecretProvider struct{}
+
+	// envSecretProvider reads the env var of env://OTHER_VAR.
+	envSecretProvider map[string]string
+)
+
+var ErrSecretNotFound = errors.New("secret not found")
+
+// WithSecretProvider adds the provider of the secret references of the scheme, e.g. "vault".
+// The providers of the file and env schemes are built in, they may be replaced.
+func WithSecretProvider(scheme string, provider SecretProvider) Option {
+	return func(l *loader) {
+		l.secretProviders[scheme] = provider
+	}
+}
+
+// ParseSecretRef parses scheme://path#key, ok is false if the value is not a reference.
+func ParseSecretRef(value string) (SecretRef, bool) {
+	scheme, rest, ok := strings.Cut(value, "://")
+	if !ok || scheme == "" || rest == "" {
+		return SecretRef{}, false
+	}
+
+	path, key, _ := strings.Cut(rest, "#")
+
+	return SecretRef{Scheme: scheme, Path: path, Key: key}, true
+}
+
+func (r SecretRef) String() string {
+	if r.Key == "" {
+		return r.Scheme + "://" + r.Path
+	}
+
+	return r.Scheme + "://" + r.Path + "#" + r.Key
+}
+
+func (fileSecretProvider) Resolve(ref SecretRef) (string, error) {
+	data, err := os.ReadFile(ref.Path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("%w: %w", ErrSecretNotFound, err)
+		}
+		return "", fmt.Errorf("reading file: %w", err)
+	}
+
+	if ref.Key == "" {
+		// the files written by editors and the most of tools end with a newline
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+
+	var fields map[string]any
+	if err := yaml.Unmarshal(data, &fields); err != nil {
+		return "", fmt.Errorf("parsing file: %w", err)
+	}
+
+	value, ok := fields[ref.Key]
+	if !ok {
+		return "", fmt.Errorf("%w: no key %q", ErrSecretNotFound, ref.Key)
+	}
+
+	return fmt.Sprint(value), nil
+}
+
+func (p envSecretProvider) Resolve(ref SecretRef) (string, error) {
+	value := p[ref.Path]
+	if value == "" {
+		return "", fmt.Errorf("%w: env var %s is not set", ErrSecretNotFound, ref.Path)
+	}
+
+	return value, nil
+}
+
+// resolveSecrets replaces the references in the secret string fields with the secrets.
+// The values of the schemes without a provider are kept.
+func (l *loader) resolveSecrets(value reflect.Value, fields []field, sources Sources) error {
+	var errs []error
+
+	for _, f := range fields {
+		if !f.secret || f.typ.Kind() != reflect.String {
+			continue
+		}
+
+		dst := value.FieldByIndex(f.index)
+
+		ref, ok := ParseSecretRef(dst.String())
+		if !ok {
+			continue
+		}
+		provider, ok := l.secretProviders[ref.Scheme]
+		if !ok {
+			continue
+		}
+
+		secret, err := provider.Resolve(ref)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: resolving %s: %w", f.path, ref, err))
+			continue
+		}
+
+		dst.SetString(secret)
+		sources[f.path] = Source{Layer: LayerSecrets, Name: ref.String()}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,127 @@
+package config_test
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/yvyrovyi-cinemo/utils/config"
+	"github.com/yvyrovyi-cinemo/utils/config/configtest"
+)
+
+type securedConfig struct {
+	User     string `env:"USER" json:"user" yaml:"user"`
+	Password string `env:"PASSWORD" json:"password" yaml:"password" secret:"true"`
+	Token    string `env:"TOKEN" json:"token" yaml:"token" secret:"true"`
+	APIKey   string `env:"API_KEY" json:"api_key" yaml:"api_key" secret:"true"`
+	Endpoint string `env:"ENDPOINT" json:"endpoint" yaml:"endpoint" secret:"true"`
+}
+
+func TestParseSecretRef(t *testing.T) {
+	t.Parallel()
+
+	ref, ok := config.ParseSecretRef("file:///run/secrets/x")
+	require.True(t, ok)
+	require.Equal(t, config.SecretRef{Scheme: "file", Path: "/run/secrets/x"}, ref)
+
+	ref, ok = config.ParseSecretRef("vault://kv/kafka#password")
+	require.True(t, ok)
+	require.Equal(t, config.SecretRef{Scheme: "vault", Path: "kv/kafka", Key: "password"}, ref)
+	require.Equal(t, "vault://kv/kafka#password", ref.String())
+
+	_, ok = config.ParseSecretRef("plain")
+	require.False(t, ok)
+}
+
+func TestLoad_Secrets(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	passwordPath := filepath.Join(dir, "password")
+	writeFile(t, passwordPath, "from-file\n")
+	keysPath := filepath.Join(dir, "keys.json")
+	writeFile(t, keysPath, `{"api_key": "from-json"}`)
+
+	vault := configtest.NewSecretProvider(map[string]string{"kv/app#token": "from-vault"})
+
+	var (
+		cfg     securedConfig
+		sources config.Sources
+	)
+	require.NoError(t, config.Load(&cfg, config.WithSecretProvider("vault", vault), config.WithSources(&sources), config.WithEnvironment(map[string]string{
+		"USER":     "env://OTHER_USER",
+		"PASSWORD": "file://" + passwordPath,
+		"TOKEN":    "vault://kv/app#token",
+		"API_KEY":  "file://" + keysPath + "#api_key",
+		"ENDPOINT": "https://example.com",
+	})))
+
+	// only the secret fields are resolved, the schemes without a provider are kept
+	require.Equal(t, securedConfig{
+		User:     "env://OTHER_USER",
+		Password: "from-file",
+		Token:    "from-vault",
+		APIKey:   "from-json",
+		Endpoint: "https://example.com",
+	}, cfg)
+	require.Equal(t, config.Source{Layer: config.LayerSecrets, Name: "vault://kv/app#token"}, sources["token"])
+
+	require.NoError(t, config.Load(&cfg, config.WithEnvironment(map[string]string{
+		"PASSWORD":       "env://OTHER_PASSWORD",
+		"OTHER_PASSWORD": "from-env",
+	})))
+	require.Equal(t, "from-env", cfg.Password)
+
+	err := config.Load(&cfg, config.WithSecretProvider("vault", vault), config.WithEnvironment(map[string]string{
+		"PASSWORD": "env://OTHER_PASSWORD",
+		"TOKEN":    "vault://kv/app#missing",
+	}))
+	require.ErrorIs(t, err, config.ErrSecretNotFound)
+	require.EqualError(t, err, "loading config: secrets: "+
+		"password: resolving env://OTHER_PASSWORD: secret not found: env var OTHER_PASSWORD is not set\n"+
+		"token: resolving vault://kv/app#missing: secret not found: kv/app#missing")
+}
+
+func TestWatcher_Secrets(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, path, "password: vault://kv/app#password\n")
+
+	vault := configtest.NewSecretProvider(map[string]string{"kv/app#password": "first"})
+
+	watcher, err := config.NewWatcher[securedConfig](
+		config.WatcherConfig{Path: path, PollInterval: 5 * time.Millisecond, SecretsInterval: 10 * time.Millisecond},
+		nil, slog.Default(), config.WithSecretProvider("vault", vault),
+	)
+	require.NoError(t, err)
+	require.Equal(t, "first", watcher.Get().Password)
+
+	var rotated atomic.Value
+	watcher.OnChange(func(_, new securedConfig) {
+		rotated.Store(new.Password)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = watcher.Run(ctx) }()
+
+	vault.Set("kv/app#password", "second")
+	require.Eventually(t, func() bool {
+		return rotated.Load() == "second"
+	}, time.Second, time.Millisecond)
+	require.Equal(t, "second", watcher.Get().Password)
+	require.GreaterOrEqual(t, vault.Reads(), 2)
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+}
@@ -0,0 +1,26 @@
+package config
+
+// Source is the layer that set a config field, Name is the file path, the env var or the flag.
+type Source struct {
+	Layer string `json:"layer" yaml:"layer"`
+	Name  string `json:"name,omitempty" yaml:"name,omitempty"`
+}
+
+// Sources are the sources of the config fields by their yaml paths, see WithSources.
+// The fields set by none of the layers are missing.
+type Sources map[string]Source
+
+// WithSources stores the sources of the config fields filled by Load.
+func WithSources(sources *Sources) Option {
+	return func(l *loader) {
+		l.sources = sources
+	}
+}
+
+func (s Source) String() string {
+	if s.Name == "" {
+		return s.Layer
+	}
+
+	return s.Layer + " " + s.Name
+}
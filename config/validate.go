@@ -0,0 +1,392 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const validateTag = "validate"
+
+type (
+	// Validator is implemented by the config structs checking more than the validate tags allow,
+	// e.g. the fields depending on each other. It is called after the tags of the struct are checked.
+	// The FieldError paths it returns are relative to the struct.
+	Validator interface {
+		Validate() error
+	}
+
+	// FieldError is the error of the config field at the dot-separated path of its yaml names,
+	// e.g. "kafka.consumer.group_id".
+	FieldError struct {
+		Path string
+		Err  error
+	}
+
+	// ValidationError lists all the invalid fields of the config.
+	ValidationError struct {
+		Errors []*FieldError
+	}
+)
+
+var ErrRequired = errors.New("required")
+
+// NewFieldError returns the error of the field, the path is relative to the validated struct.
+func NewFieldError(path string, err error) *FieldError {
+	return &FieldError{Path: path, Err: err}
+}
+
+func (e *FieldError) Error() string {
+	if e.Path == "" {
+		return e.Err.Error()
+	}
+
+	return e.Path + ": " + e.Err.Error()
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Errors))
+	for _, fieldErr := range e.Errors {
+		messages = append(messages, fieldErr.Error())
+	}
+
+	return "invalid config: " + strings.Join(messages, "; ")
+}
+
+func (e *ValidationError) Unwrap() []error {
+	res := make([]error, 0, len(e.Errors))
+	for _, fieldErr := range e.Errors {
+		res = append(res, fieldErr)
+	}
+
+	return res
+}
+
+// Validate checks the cfg struct fields recursively and returns *ValidationError listing all the invalid ones.
+//
+// The validate tag holds the comma-separated rules:
+//   - required: the value is not zero, e.g. a non-empty string or slice
+//   - min=N, max=N: the number bounds or the string, slice and map length bounds,
+//     the time.Duration bounds are durations, e.g. min=1s
+//   - oneof=a b c: the value is one of the space-separated ones
+//   - url: the absolute URL
+//   - hostport: the host:port
+//   - regex=EXPR: the whole value matches the expression, it must be the last rule as it may contain commas
+//   - -: the field and its nested fields are skipped, e.g. the config of an optional client
+//     checked by the Validate of the parent struct only when the client is enabled
+//
+// The rules other than required, min and max skip the empty values. The url and hostport strings
+// may be comma-separated lists, the rules of a string slice are checked for every element.
+func Validate(cfg any) error {
+	v := &validation{}
+	v.value("", reflect.ValueOf(cfg))
+
+	if len(v.errors) == 0 {
+		return nil
+	}
+
+	return &ValidationError{Errors: v.errors}
+}
+
+type validation struct {
+	errors []*FieldError
+}
+
+func (v *validation) add(path string, err error) {
+	v.errors = append(v.errors, &FieldError{Path: path, Err: err})
+}
+
+func (v *validation) value(path string, value reflect.Value) {
+	for value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return
+		}
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.Struct:
+		v.structFields(path, value)
+		v.validator(path, value)
+
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			v.value(fmt.Sprintf("%s[%d]", path, i), value.Index(i))
+		}
+
+	default:
+	}
+}
+
+func (v *validation) structFields(path string, value reflect.Value) {
+	valueType := value.Type()
+
+	for i := 0; i < valueType.NumField(); i++ {
+		field := valueType.Field(i)
+		if !field.IsExported() && !field.Anonymous {
+			continue
+		}
+
+		fieldPath := joinPath(path, field)
+
+		rules, ok := field.Tag.Lookup(validateTag)
+		if rules == "-" {
+			continue
+		}
+		if ok {
+			for _, err := range checkRules(value.Field(i), rules) {
+				v.add(fieldPath, err)
+			}
+		}
+
+		v.value(fieldPath, value.Field(i))
+	}
+}
+
+// validator calls Validate of the struct with either the value or the pointer receiver.
+func (v *validation) validator(path string, value reflect.Value) {
+	var validator Validator
+
+	switch {
+	case value.CanAddr() && value.Addr().CanInterface():
+		validator, _ = value.Addr().Interface().(Validator)
+	case value.CanInterface():
+		validator, _ = value.Interface().(Validator)
+	}
+
+	if validator == nil {
+		return
+	}
+
+	if err := validator.Validate(); err != nil {
+		v.validatorError(path, err)
+	}
+}
+
+// validatorError prefixes the paths of the field errors returned by Validate with the struct path.
+func (v *validation) validatorError(path string, err error) {
+	switch e := err.(type) { //nolint:errorlint // only the errors returned by Validate itself are unpacked
+	case *ValidationError:
+		for _, fieldErr := range e.Errors {
+			v.add(joinPaths(path, fieldErr.Path), fieldErr.Err)
+		}
+	case *FieldError:
+		// the field error of a nested Validate call keeps the paths of its fields
+		if nested, ok := e.Err.(*ValidationError); ok { //nolint:errorlint // only the error of Validate itself
+			v.validatorError(joinPaths(path, e.Path), nested)
+			return
+		}
+		v.add(joinPaths(path, e.Path), e.Err)
+	case interface{ Unwrap() []error }:
+		for _, joined := range e.Unwrap() {
+			v.validatorError(path, joined)
+		}
+	default:
+		v.add(path, err)
+	}
+}
+
+// joinPath appends the field name to the path, the embedded and yaml inline structs add nothing.
+func joinPath(path string, field reflect.StructField) string {
+	name, opts, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	if name == "" && (field.Anonymous || slices.Contains(strings.Split(opts, ","), "inline")) {
+		return path
+	}
+
+	if name == "" || name == "-" {
+		name, _, _ = strings.Cut(field.Tag.Get("json"), ",")
+	}
+	if name == "" || name == "-" {
+		name = field.Name
+	}
+
+	return joinPaths(path, name)
+}
+
+func joinPaths(path, name string) string {
+	switch {
+	case path == "":
+		return name
+	case name == "":
+		return path
+	default:
+		return path + "." + name
+	}
+}
+
+func checkRules(value reflect.Value, rules string) []error {
+	var res []error
+
+	for rules != "" {
+		var rule string
+		if strings.HasPrefix(rules, "regex=") {
+			rule, rules = rules, ""
+		} else {
+			rule, rules, _ = strings.Cut(rules, ",")
+		}
+
+		name, arg, _ := strings.Cut(strings.TrimSpace(rule), "=")
+
+		if err := checkRule(value, name, arg); err != nil {
+			res = append(res, err)
+			if name == "required" {
+				break
+			}
+		}
+	}
+
+	return res
+}
+
+func checkRule(value reflect.Value, name, arg string) error {
+	switch name {
+	case "required":
+		if value.IsZero() {
+			return ErrRequired
+		}
+		return nil
+	case "min", "max":
+		return checkBound(value, name, arg)
+	case "oneof", "url", "hostport", "regex":
+		return checkStrings(value, name, arg)
+	default:
+		return fmt.Errorf("unknown validate rule %q", name)
+	}
+}
+
+func checkBound(value reflect.Value, name, arg string) error {
+	var (
+		actual, bound float64
+		err           error
+		subject       = "value"
+	)
+
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+
+	switch {
+	case value.Type() == reflect.TypeOf(time.Duration(0)):
+		var d time.Duration
+		d, err = time.ParseDuration(arg)
+		actual, bound = float64(value.Int()), float64(d)
+	case value.CanInt():
+		actual = float64(value.Int())
+		bound, err = strconv.ParseFloat(arg, 64)
+	case value.CanUint():
+		actual = float64(value.Uint())
+		bound, err = strconv.ParseFloat(arg, 64)
+	case value.CanFloat():
+		actual = value.Float()
+		bound, err = strconv.ParseFloat(arg, 64)
+	case value.Kind() == reflect.String || value.Kind() == reflect.Slice || value.Kind() == reflect.Map:
+		actual, subject = float64(value.Len()), "length"
+		bound, err = strconv.ParseFloat(arg, 64)
+	default:
+		return fmt.Errorf("%s is not applicable to %s", name, value.Type())
+	}
+	if err != nil {
+		return fmt.Errorf("invalid %s bound %q", name, arg)
+	}
+
+	if name == "min" && actual < bound {
+		return fmt.Errorf("%s must be at least %s", subject, arg)
+	}
+	if name == "max" && actual > bound {
+		return fmt.Errorf("%s must be at most %s", subject, arg)
+	}
+
+	return nil
+}
+
+// checkStrings checks the string, the number or every element of the slice, the empty values are skipped.
+func checkStrings(value reflect.Value, name, arg string) error {
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+
+	if value.Kind() == reflect.Slice || value.Kind() == reflect.Array {
+		for i := 0; i < value.Len(); i++ {
+			if err := checkStrings(value.Index(i), name, arg); err != nil {
+				return fmt.Errorf("[%d]: %w", i, err)
+			}
+		}
+		return nil
+	}
+
+	if value.IsZero() {
+		return nil
+	}
+
+	s := fmt.Sprint(value)
+
+	switch name {
+	case "oneof":
+		if !slices.Contains(strings.Fields(arg), s) {
+			return fmt.Errorf("%q must be one of %s", s, strings.Join(strings.Fields(arg), ", "))
+		}
+	case "url":
+		return checkList(s, checkURL)
+	case "hostport":
+		return checkList(s, checkHostPort)
+	case "regex":
+		re, err := regexp.Compile("^(?:" + arg + ")$")
+		if err != nil {
+			return fmt.Errorf("invalid regex %q: %w", arg, err)
+		}
+		if !re.MatchString(s) {
+			return fmt.Errorf("%q must match %s", s, arg)
+		}
+	}
+
+	return nil
+}
+
+func checkList(s string, check func(string) error) error {
+	for _, item := range strings.Split(s, ",") {
+		if err := check(strings.TrimSpace(item)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func checkURL(s string) error {
+	u, err := url.Parse(s)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("%q is not a valid url", s)
+	}
+
+	return nil
+}
+
+func checkHostPort(s string) error {
+	host, port, err := net.SplitHostPort(s)
+	if err != nil || host == "" {
+		return fmt.Errorf("%q is not a valid host:port", s)
+	}
+
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return fmt.Errorf("%q has an invalid port", s)
+	}
+
+	return nil
+}
@@ -0,0 +1,158 @@
+package config
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type (
+	validatedConfig struct {
+		Kafka  validatedKafka   `json:"kafka" yaml:"kafka"`
+		Server validatedServer  `json:"server" yaml:"server"`
+		Routes []validatedRoute `json:"routes" yaml:"routes"`
+	}
+
+	validatedKafka struct {
+		validatedConnection `yaml:",inline"`
+
+		Consumer struct {
+			GroupID       string `json:"group_id" yaml:"group_id" validate:"required"`
+			InitialOffset string `json:"initial_offset" yaml:"initial_offset" validate:"oneof=newest oldest"`
+		} `json:"consumer" yaml:"consumer"`
+	}
+
+	validatedConnection struct {
+		Brokers string `json:"brokers" yaml:"brokers" validate:"required,hostport"`
+	}
+
+	validatedServer struct {
+		Port     int           `json:"port" yaml:"port" validate:"min=0,max=65535"`
+		URL      string        `json:"url" yaml:"url" validate:"url"`
+		Timeout  time.Duration `json:"timeout" yaml:"timeout" validate:"min=1s"`
+		Name     string        `json:"name" yaml:"name" validate:"max=5,regex=[a-z]{1,3}|x,y"`
+		Subjects []string      `json:"subjects" yaml:"subjects" validate:"min=1,oneof=a b"`
+	}
+
+	validatedRoute struct {
+		From string `json:"from" yaml:"from"`
+		To   string `json:"to" yaml:"to"`
+	}
+
+	// optionalConfig checks the connection only when it is enabled.
+	optionalConfig struct {
+		Enabled    bool                `json:"enabled" yaml:"enabled"`
+		Connection validatedConnection `json:"connection" yaml:"connection" validate:"-"`
+	}
+)
+
+func (c optionalConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	if err := Validate(c.Connection); err != nil {
+		return NewFieldError("connection", err)
+	}
+	return nil
+}
+
+func (r validatedRoute) Validate() error {
+	if r.From == r.To {
+		return NewFieldError("to", errors.New("must differ from from"))
+	}
+	return nil
+}
+
+func (s *validatedServer) Validate() error {
+	if s.URL != "" && s.Port != 0 {
+		return errors.Join(
+			NewFieldError("url", errors.New("conflicts with port")),
+			errors.New("either url or port must be set"),
+		)
+	}
+	return nil
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	valid := validatedConfig{
+		Server: validatedServer{Port: 8080, Timeout: time.Second, Name: "ab", Subjects: []string{"a"}},
+		Routes: []validatedRoute{{From: "a", To: "b"}},
+	}
+	valid.Kafka.Brokers = "localhost:9092,kafka:9093"
+	valid.Kafka.Consumer.GroupID = "group"
+	valid.Kafka.Consumer.InitialOffset = "oldest"
+
+	require.NoError(t, Validate(&valid))
+	require.NoError(t, Validate(valid))
+
+	cfg := validatedConfig{
+		Server: validatedServer{
+			Port:     -1,
+			URL:      "localhost",
+			Timeout:  time.Millisecond,
+			Name:     "abcdef",
+			Subjects: []string{"a", "c"},
+		},
+		Routes: []validatedRoute{{From: "a", To: "b"}, {From: "c", To: "c"}},
+	}
+	cfg.Kafka.Brokers = "localhost:9092,kafka"
+	cfg.Kafka.Consumer.InitialOffset = "latest"
+
+	err := Validate(&cfg)
+
+	var validationErr *ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	require.ErrorIs(t, err, ErrRequired)
+
+	messages := make([]string, 0, len(validationErr.Errors))
+	for _, fieldErr := range validationErr.Errors {
+		messages = append(messages, fieldErr.Error())
+	}
+
+	require.Equal(t, []string{
+		`kafka.brokers: "kafka" is not a valid host:port`,
+		`kafka.consumer.group_id: required`,
+		`kafka.consumer.initial_offset: "latest" must be one of newest, oldest`,
+		`server.port: value must be at least 0`,
+		`server.url: "localhost" is not a valid url`,
+		`server.timeout: value must be at least 1s`,
+		`server.name: length must be at most 5`,
+		`server.name: "abcdef" must match [a-z]{1,3}|x,y`,
+		`server.subjects: [1]: "c" must be one of a, b`,
+		`server.url: conflicts with port`,
+		`server: either url or port must be set`,
+		`routes[1].to: must differ from from`,
+	}, messages)
+}
+
+func TestValidate_Skipped(t *testing.T) {
+	t.Parallel()
+
+	type parent struct {
+		Optional optionalConfig `json:"optional" yaml:"optional"`
+	}
+
+	require.NoError(t, Validate(parent{}))
+
+	err := Validate(parent{Optional: optionalConfig{Enabled: true}})
+	require.ErrorIs(t, err, ErrRequired)
+	require.EqualError(t, err, "invalid config: optional.connection.brokers: required")
+}
+
+func TestParseEnvsAndFile_Validate(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, path, "kafka:\n  brokers: localhost:9092\n")
+
+	var cfg validatedConfig
+	err := ParseEnvsAndFile(&cfg, path)
+	require.EqualError(t, err, "invalid config: kafka.consumer.group_id: required; server.timeout: value must be at least 1s; "+
+		"server.subjects: length must be at least 1")
+}
@@ -0,0 +1,245 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	LogsLabelComponent  = "component"
+	DefaultPollInterval = 10 * time.Second
+)
+
+type (
+	// Watcher keeps the config loaded by Load up to date with its files and secrets.
+	// An invalid file is reported and the last good config is kept.
+	Watcher[T any] struct {
+		paths           []string
+		pollInterval    time.Duration
+		secretsInterval time.Duration
+		validate        func(*T) error
+		opts            []Option
+		logger          *slog.Logger
+
+		current atomic.Pointer[watched[T]]
+
+		// reloadMux serializes the reloads and the notifications
+		reloadMux sync.Mutex
+		sum       [sha256.Size]byte
+		reloadAt  time.Time
+
+		mux      sync.Mutex
+		err      error
+		handlers []func(old, new T)
+	}
+
+	// watched is the config with its sources.
+	watched[T any] struct {
+		cfg     *T
+		sources Sources
+	}
+
+	WatcherConfig struct {
+		// Path is the JSON or YAML config file, it may list several comma-separated ones.
+		// The CONFIG_FILE env var overrides it as in Load.
+		Path string `env:"PATH" json:"path" yaml:"path"`
+		// PollInterval is how often the files are checked for changes.
+		PollInterval time.Duration `env:"POLL_INTERVAL" envDefault:"10s" json:"poll_interval" yaml:"poll_interval" default:"10s"`
+		// SecretsInterval is how often the config is reloaded even if the files are not changed,
+		// so the rotated secrets are re-read, see WithSecretProvider. Zero disables it.
+		SecretsInterval time.Duration `env:"SECRETS_INTERVAL" json:"secrets_interval" yaml:"secrets_interval"`
+	}
+)
+
+var ErrNoConfigFile = errors.New("no config file to watch")
+
+// NewWatcher parses the config and fails if it is invalid. The validate func may be nil.
+// The options are passed to Load on every reload, e.g. WithSecretProvider. All the files are watched,
+// the ones of config.Path and of WithFiles.
+func NewWatcher[T any](
+	config WatcherConfig, validate func(*T) error, logger *slog.Logger, opts ...Option,
+) (*Watcher[T], error) {
+	opts = append([]Option{WithFiles(strings.Split(config.Path, ",")...)}, opts...)
+
+	paths := newLoader(opts).files
+	if len(paths) == 0 {
+		return nil, ErrNoConfigFile
+	}
+
+	if config.PollInterval <= 0 {
+		config.PollInterval = DefaultPollInterval
+	}
+
+	w := &Watcher[T]{
+		paths:           paths,
+		pollInterval:    config.PollInterval,
+		secretsInterval: config.SecretsInterval,
+		validate:        validate,
+		opts:            opts,
+		logger:          logger.With(LogsLabelComponent, "config-watcher", "paths", paths),
+	}
+
+	if err := w.Reload(); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// Get returns the current config, it must not be modified.
+func (w *Watcher[T]) Get() T {
+	return *w.current.Load().cfg
+}
+
+// Handler serves the dump of the current config, see config.Handler.
+func (w *Watcher[T]) Handler() http.Handler {
+	return handler(func() (any, Sources) {
+		current := w.current.Load()
+		return current.cfg, current.sources
+	})
+}
+
+// OnChange registers the handler called with the old and the new config after every change.
+// The handlers are called one by one by the reload, they must not block.
+func (w *Watcher[T]) OnChange(handler func(old, new T)) {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	w.handlers = append(w.handlers, handler)
+}
+
+// Err returns the error of the last reload, nil if the current config is up to date with the file.
+func (w *Watcher[T]) Err() error {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	return w.err
+}
+
+// Run checks the file every PollInterval until ctx is done.
+func (w *Watcher[T]) Run(ctx context.Context) error {
+	ticker := time.NewTicker(w.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := w.check(); err != nil {
+				w.logger.Error("failed to reload config, keeping the last good one", "error", err)
+			}
+		}
+	}
+}
+
+// Reload parses and validates the files, then swaps the config and notifies the handlers if it changed.
+func (w *Watcher[T]) Reload() error {
+	w.reloadMux.Lock()
+	defer w.reloadMux.Unlock()
+
+	sum, err := w.sumFiles()
+	if err != nil {
+		return w.setErr(err)
+	}
+
+	return w.reload(sum)
+}
+
+// check reloads the config only if the content of the files changed or the secrets are to be re-read,
+// so an invalid file is reported once.
+func (w *Watcher[T]) check() error {
+	w.reloadMux.Lock()
+	defer w.reloadMux.Unlock()
+
+	sum, err := w.sumFiles()
+	if err != nil {
+		// a file may be missing for a moment while it is replaced, it is reloaded when it is back
+		w.sum = [sha256.Size]byte{}
+		_ = w.setErr(err)
+		return nil
+	}
+
+	if sum == w.sum && (w.secretsInterval <= 0 || time.Since(w.reloadAt) < w.secretsInterval) {
+		return nil
+	}
+
+	return w.reload(sum)
+}
+
+// sumFiles hashes the content of all the files.
+func (w *Watcher[T]) sumFiles() ([sha256.Size]byte, error) {
+	hash := sha256.New()
+	for _, path := range w.paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return [sha256.Size]byte{}, fmt.Errorf("reading file: %w", err)
+		}
+
+		sum := sha256.Sum256(data)
+		hash.Write(sum[:])
+	}
+
+	return [sha256.Size]byte(hash.Sum(nil)), nil
+}
+
+// reload remembers the files content sum, it must be called with reloadMux locked.
+func (w *Watcher[T]) reload(sum [sha256.Size]byte) error {
+	w.sum = sum
+	w.reloadAt = time.Now()
+
+	cfg := new(T)
+	var sources Sources
+	if err := Load(cfg, append(w.opts[:len(w.opts):len(w.opts)], WithSources(&sources))...); err != nil {
+		return w.setErr(err)
+	}
+
+	if w.validate != nil {
+		if err := w.validate(cfg); err != nil {
+			return w.setErr(fmt.Errorf("validating config: %w", err))
+		}
+	}
+
+	_ = w.setErr(nil)
+
+	current := w.current.Swap(&watched[T]{cfg: cfg, sources: sources})
+	if current == nil {
+		return nil
+	}
+
+	old := current.cfg
+	if reflect.DeepEqual(*old, *cfg) {
+		return nil
+	}
+
+	w.logger.Info("config reloaded")
+
+	w.mux.Lock()
+	handlers := w.handlers
+	w.mux.Unlock()
+
+	for _, handler := range handlers {
+		handler(*old, *cfg)
+	}
+
+	return nil
+}
+
+func (w *Watcher[T]) setErr(err error) error {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	w.err = err
+
+	return err
+}
@@ -0,0 +1,161 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type watchedConfig struct {
+	Name  string `json:"name" yaml:"name"`
+	Limit int    `json:"limit" yaml:"limit"`
+}
+
+func validateWatchedConfig(cfg *watchedConfig) error {
+	if cfg.Limit <= 0 {
+		return errors.New("limit must be positive")
+	}
+	return nil
+}
+
+func TestWatcher(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, path, "name: first\nlimit: 1\n")
+
+	watcher, err := NewWatcher(WatcherConfig{Path: path, PollInterval: 10 * time.Millisecond}, validateWatchedConfig, slog.Default())
+	require.NoError(t, err)
+	require.Equal(t, watchedConfig{Name: "first", Limit: 1}, watcher.Get())
+
+	var (
+		mux     sync.Mutex
+		changes [][2]watchedConfig
+	)
+	watcher.OnChange(func(old, new watchedConfig) {
+		mux.Lock()
+		defer mux.Unlock()
+		changes = append(changes, [2]watchedConfig{old, new})
+	})
+	getChanges := func() [][2]watchedConfig {
+		mux.Lock()
+		defer mux.Unlock()
+		return append([][2]watchedConfig(nil), changes...)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	chanErr := make(chan error, 1)
+	go func() { chanErr <- watcher.Run(ctx) }()
+
+	writeFile(t, path, "name: second\nlimit: 2\n")
+	require.Eventually(t, func() bool {
+		return len(getChanges()) == 1
+	}, time.Second, time.Millisecond)
+	require.Equal(t, [2]watchedConfig{{Name: "first", Limit: 1}, {Name: "second", Limit: 2}}, getChanges()[0])
+	require.Equal(t, watchedConfig{Name: "second", Limit: 2}, watcher.Get())
+
+	// the invalid config is rejected and the last good one is kept
+	writeFile(t, path, "name: third\nlimit: 0\n")
+	require.Eventually(t, func() bool {
+		return watcher.Err() != nil
+	}, time.Second, time.Millisecond)
+	require.Equal(t, watchedConfig{Name: "second", Limit: 2}, watcher.Get())
+
+	writeFile(t, path, "name: fourth\nlimit: 4\n")
+	require.Eventually(t, func() bool {
+		return watcher.Err() == nil && len(getChanges()) == 2
+	}, time.Second, time.Millisecond)
+	require.Equal(t, [2]watchedConfig{{Name: "second", Limit: 2}, {Name: "fourth", Limit: 4}}, getChanges()[1])
+
+	cancel()
+	require.NoError(t, <-chanErr)
+}
+
+func TestWatcher_Reload(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	writeFile(t, path, `{"name": "first", "limit": 1}`)
+
+	watcher, err := NewWatcher[watchedConfig](WatcherConfig{Path: path}, nil, slog.Default())
+	require.NoError(t, err)
+
+	notified := 0
+	watcher.OnChange(func(_, _ watchedConfig) { notified++ })
+
+	// the same config is not notified
+	writeFile(t, path, `{"limit": 1, "name": "first"}`)
+	require.NoError(t, watcher.Reload())
+	require.Zero(t, notified)
+
+	writeFile(t, path, `{"name": "second", "limit": 1}`)
+	require.NoError(t, watcher.Reload())
+	require.Equal(t, 1, notified)
+
+	require.NoError(t, os.Remove(path))
+	require.Error(t, watcher.Reload())
+	require.Error(t, watcher.Err())
+	require.Equal(t, "second", watcher.Get().Name)
+}
+
+func TestWatcher_Files(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	base := filepath.Join(dir, "base.yaml")
+	writeFile(t, base, "name: base\nlimit: 1\n")
+	prod := filepath.Join(dir, "prod.yaml")
+	writeFile(t, prod, "limit: 2\n")
+	local := filepath.Join(dir, "local.yaml")
+	writeFile(t, local, "name: local\n")
+
+	watcher, err := NewWatcher[watchedConfig](WatcherConfig{Path: base + ", " + prod}, nil, slog.Default(), WithFiles(local))
+	require.NoError(t, err)
+	require.Equal(t, watchedConfig{Name: "local", Limit: 2}, watcher.Get())
+
+	// every file is watched
+	writeFile(t, local, "name: changed\n")
+	require.NoError(t, watcher.check())
+	require.Equal(t, watchedConfig{Name: "changed", Limit: 2}, watcher.Get())
+
+	writeFile(t, prod, "limit: 3\n")
+	require.NoError(t, watcher.check())
+	require.Equal(t, watchedConfig{Name: "changed", Limit: 3}, watcher.Get())
+
+	// the CONFIG_FILE env var replaces the files
+	watcher, err = NewWatcher[watchedConfig](WatcherConfig{Path: local}, nil, slog.Default(), WithEnvironment(map[string]string{
+		"CONFIG_FILE": base + "," + prod,
+	}))
+	require.NoError(t, err)
+	require.Equal(t, watchedConfig{Name: "base", Limit: 3}, watcher.Get())
+	require.Equal(t, []string{base, prod}, watcher.paths)
+
+	_, err = NewWatcher[watchedConfig](WatcherConfig{Path: " , "}, nil, slog.Default(), WithEnvironment(map[string]string{}))
+	require.ErrorIs(t, err, ErrNoConfigFile)
+}
+
+func TestNewWatcher_Invalid(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, path, "limit: 0\n")
+
+	_, err := NewWatcher(WatcherConfig{Path: path}, validateWatchedConfig, slog.Default())
+	require.Error(t, err)
+
+	_, err = NewWatcher(WatcherConfig{Path: filepath.Join(t.TempDir(), "missing.yaml")}, validateWatchedConfig, slog.Default())
+	require.Error(t, err)
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+}
@@ -15,16 +15,19 @@ const (
 	DefaultMetricsEndpoint   = "/metrics"
 	DefaultReadinessEndpoint = "/ready"
 	DefaultLivenessEndpoint  = "/alive"
+	// DefaultConfigEndpoint is where the services mount the config dump, e.g. config.Handler.
+	DefaultConfigEndpoint = "/config"
 )
 
 type (
 	Server struct {
 		Config
-		server *http.Server
+		server   *http.Server
+		handlers map[string]http.Handler
 	}
 
 	Config struct {
-		Port              int           `env:"PORT"  json:"port" json:"port" yaml:"port"`
+		Port              int           `env:"PORT" json:"port" yaml:"port" validate:"min=0,max=65535"`
 		MetricsEndpoint   string        `env:"METRICS_ENDPOINT" json:"metrics_endpoint" yaml:"metrics_endpoint"`
 		ReadinessEndpoint string        `env:"READINESS_ENDPOINT" json:"readiness_endpoint" yaml:"readiness_endpoint"`
 		LivenessEndpoint  string        `env:"LIVENESS_ENDPOINT" json:"liveness_endpoint" yaml:"liveness_endpoint"`
@@ -52,15 +55,24 @@ func New(config Config) *Server {
 	}
 
 	return &Server{
-		Config: config,
+		Config:   config,
+		handlers: make(map[string]http.Handler),
 	}
 }
 
+// Handle adds the handler for the pattern to the server, it must be called before Run.
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.handlers[pattern] = handler
+}
+
 func (s *Server) Run(ctx context.Context, readiness []func(context.Context) error, liveness []func(context.Context) error) error {
 	mux := http.NewServeMux()
 	mux.Handle(s.MetricsEndpoint, promhttp.Handler())
 	mux.Handle(s.ReadinessEndpoint, s.createHandler(readiness))
 	mux.Handle(s.LivenessEndpoint, s.createHandler(liveness))
+	for pattern, handler := range s.handlers {
+		mux.Handle(pattern, handler)
+	}
 
 	s.server = &http.Server{
 		Addr:         fmt.Sprintf(":%d", s.Port),
@@ -0,0 +1,264 @@
+package kafkalib
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/IBM/sarama"
+)
+
+type (
+	// Admin performs cluster metadata operations.
+	Admin struct {
+		client sarama.Client
+		admin  sarama.ClusterAdmin
+		logger *slog.Logger
+	}
+
+	TopicInfo struct {
+		Name              string
+		Partitions        int32
+		ReplicationFactor int16
+	}
+
+	TopicDescription struct {
+		Name       string
+		Partitions []PartitionInfo
+	}
+
+	PartitionInfo struct {
+		ID           int32
+		Leader       int32
+		Replicas     []int32
+		ISR          []int32
+		OldestOffset int64
+		NewestOffset int64
+	}
+
+	GroupDescription struct {
+		GroupID      string
+		State        string
+		ProtocolType string
+		Protocol     string
+		Members      []GroupMember
+	}
+
+	GroupMember struct {
+		MemberID   string
+		ClientID   string
+		ClientHost string
+		// Assignment is a map of topic to the assigned partitions.
+		Assignment map[string][]int32
+	}
+
+	PartitionLag struct {
+		Topic           string
+		Partition       int32
+		CommittedOffset int64
+		NewestOffset    int64
+		Lag             int64
+	}
+)
+
+func NewAdmin(config Config, logger *slog.Logger) (*Admin, error) {
+	adminConfig, err := newSaramaConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.DebugLog {
+		sarama.Logger = NewSaramaLogger(logger.With(LogsLabelComponent, "sarama"))
+	}
+
+	client, err := sarama.NewClient(strings.Split(config.Brokers, ","), adminConfig)
+	if err != nil {
+		return nil, fmt.Errorf("creating client: %w", err)
+	}
+
+	admin, err := sarama.NewClusterAdminFromClient(client)
+	if err != nil {
+		_ = client.Close()
+		return nil, fmt.Errorf("creating cluster admin: %w", err)
+	}
+
+	return &Admin{
+		client: client,
+		admin:  admin,
+		logger: logger.With(LogsLabelComponent, "kafkalib-admin"),
+	}, nil
+}
+
+// Close closes the admin together with its underlying client.
+func (a *Admin) Close() error {
+	return a.admin.Close()
+}
+
+// ListTopics returns all topics of the cluster sorted by name.
+func (a *Admin) ListTopics() ([]TopicInfo, error) {
+	topics, err := a.admin.ListTopics()
+	if err != nil {
+		return nil, fmt.Errorf("listing topics: %w", err)
+	}
+
+	res := make([]TopicInfo, 0, len(topics))
+	for name, detail := range topics {
+		res = append(res, TopicInfo{
+			Name:              name,
+			Partitions:        detail.NumPartitions,
+			ReplicationFactor: detail.ReplicationFactor,
+		})
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+
+	return res, nil
+}
+
+// DescribeTopic returns partition replicas and offsets of the topic.
+func (a *Admin) DescribeTopic(topic string) (*TopicDescription, error) {
+	metadata, err := a.admin.DescribeTopics([]string{topic})
+	if err != nil {
+		return nil, fmt.Errorf("describing topic: %w", err)
+	}
+
+	if len(metadata) == 0 {
+		return nil, fmt.Errorf("describing topic: %w", sarama.ErrUnknownTopicOrPartition)
+	}
+
+	if !errors.Is(metadata[0].Err, sarama.ErrNoError) {
+		return nil, fmt.Errorf("describing topic: %w", metadata[0].Err)
+	}
+
+	res := TopicDescription{Name: topic}
+	for _, p := range metadata[0].Partitions {
+		oldest, err := a.client.GetOffset(topic, p.ID, sarama.OffsetOldest)
+		if err != nil {
+			return nil, fmt.Errorf("getting oldest offset of partition %d: %w", p.ID, err)
+		}
+
+		newest, err := a.client.GetOffset(topic, p.ID, sarama.OffsetNewest)
+		if err != nil {
+			return nil, fmt.Errorf("getting newest offset of partition %d: %w", p.ID, err)
+		}
+
+		res.Partitions = append(res.Partitions, PartitionInfo{
+			ID:           p.ID,
+			Leader:       p.Leader,
+			Replicas:     p.Replicas,
+			ISR:          p.Isr,
+			OldestOffset: oldest,
+			NewestOffset: newest,
+		})
+	}
+
+	sort.Slice(res.Partitions, func(i, j int) bool { return res.Partitions[i].ID < res.Partitions[j].ID })
+
+	return &res, nil
+}
+
+// ListGroups returns IDs of all consumer groups sorted.
+func (a *Admin) ListGroups() ([]string, error) {
+	groups, err := a.admin.ListConsumerGroups()
+	if err != nil {
+		return nil, fmt.Errorf("listing consumer groups: %w", err)
+	}
+
+	res := make([]string, 0, len(groups))
+	for group := range groups {
+		res = append(res, group)
+	}
+	sort.Strings(res)
+
+	return res, nil
+}
+
+// DescribeGroup returns the state and members of the consumer group.
+func (a *Admin) DescribeGroup(group string) (*GroupDescription, error) {
+	descriptions, err := a.admin.DescribeConsumerGroups([]string{group})
+	if err != nil {
+		return nil, fmt.Errorf("describing consumer group: %w", err)
+	}
+
+	if len(descriptions) == 0 {
+		return nil, fmt.Errorf("describing consumer group: %w", sarama.ErrGroupIDNotFound)
+	}
+
+	description := descriptions[0]
+	if !errors.Is(description.Err, sarama.ErrNoError) {
+		return nil, fmt.Errorf("describing consumer group: %w", description.Err)
+	}
+
+	res := GroupDescription{
+		GroupID:      description.GroupId,
+		State:        description.State,
+		ProtocolType: description.ProtocolType,
+		Protocol:     description.Protocol,
+	}
+
+	for _, m := range description.Members {
+		member := GroupMember{
+			MemberID:   m.MemberId,
+			ClientID:   m.ClientId,
+			ClientHost: m.ClientHost,
+		}
+
+		assignment, err := m.GetMemberAssignment()
+		if err != nil {
+			return nil, fmt.Errorf("decoding assignment of member %s: %w", m.MemberId, err)
+		}
+		if assignment != nil {
+			member.Assignment = assignment.Topics
+		}
+
+		res.Members = append(res.Members, member)
+	}
+
+	return &res, nil
+}
+
+// GroupLag returns committed offsets and lag of every partition the group has committed to.
+func (a *Admin) GroupLag(group string) ([]PartitionLag, error) {
+	offsets, err := a.admin.ListConsumerGroupOffsets(group, nil)
+	if err != nil {
+		return nil, fmt.Errorf("listing consumer group offsets: %w", err)
+	}
+
+	if !errors.Is(offsets.Err, sarama.ErrNoError) {
+		return nil, fmt.Errorf("listing consumer group offsets: %w", offsets.Err)
+	}
+
+	var res []PartitionLag
+	for topic, partitions := range offsets.Blocks {
+		for partition, block := range partitions {
+			if block.Offset < 0 {
+				// nothing committed
+				continue
+			}
+
+			newest, err := a.client.GetOffset(topic, partition, sarama.OffsetNewest)
+			if err != nil {
+				return nil, fmt.Errorf("getting newest offset of %s/%d: %w", topic, partition, err)
+			}
+
+			res = append(res, PartitionLag{
+				Topic:           topic,
+				Partition:       partition,
+				CommittedOffset: block.Offset,
+				NewestOffset:    newest,
+				Lag:             max(newest-block.Offset, 0),
+			})
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Topic != res[j].Topic {
+			return res[i].Topic < res[j].Topic
+		}
+		return res[i].Partition < res[j].Partition
+	})
+
+	return res, nil
+}
@@ -2,7 +2,7 @@ package kafkalib
 
 type Config struct {
 	ClientID string `env:"CLIENT_ID" json:"client_id" yaml:"client_id"`
-	Brokers  string `env:"BROKERS" json:"brokers" yaml:"brokers"`
+	Brokers  string `env:"BROKERS" json:"brokers" yaml:"brokers" validate:"required,hostport"`
 
 	DebugLog bool `env:"DEBUG_LOG" envDefault:"false" json:"debug_log" yaml:"debug_log" default:"false"`
 
@@ -12,5 +12,10 @@ type Config struct {
 	CaFilePath             string `env:"CA_FILE_PATH" json:"ca_file_path" yaml:"ca_file_path"`
 	EnableCertVerification bool   `env:"ENABLE_CERT_VERIFICATION" json:"enable_cert_verification" yaml:"enable_cert_verification"`
 	SaslUser               string `env:"SAASL_USER" json:"sasl_user" yaml:"sasl_user"`
-	SaslPassword           string `env:"SAASL_PASSWORD" json:"sasl_password" yaml:"sasl_password"`
+	// SaslPassword may be a secret reference, e.g. file:///run/secrets/kafka, resolved by config.Load.
+	SaslPassword string `env:"SAASL_PASSWORD" json:"sasl_password" yaml:"sasl_password" secret:"true"`
+
+	// SaslMechanism is one of PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512.
+	// SASL is enabled only when SaslUser is set.
+	SaslMechanism string `env:"SASL_MECHANISM" envDefault:"SCRAM-SHA-512" json:"sasl_mechanism" yaml:"sasl_mechanism" default:"SCRAM-SHA-512" validate:"oneof=PLAIN SCRAM-SHA-256 SCRAM-SHA-512"`
 }
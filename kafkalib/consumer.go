@@ -12,23 +12,42 @@ import (
 
 type Consumer struct {
 	ConsumerConfig
-	consumerGroup              sarama.ConsumerGroup
-	topics                     []string
-	handler                    MessageHandler
-	logger                     *slog.Logger
-	metrics                    *kafkaMetrics
-	onAssignPartitionHandler   RebalanceHandler
-	onUnassignPartitionHandler RebalanceHandler
+	consumerGroup               sarama.ConsumerGroup
+	topics                      []string
+	handler                     MessageHandler
+	logger                      *slog.Logger
+	metrics                     *kafkaMetrics
+	onAssignPartitionHandler    RebalanceHandler
+	onUnassignPartitionHandler  RebalanceHandler
+	onAssignPartitionsHandler   PartitionsRebalanceHandler
+	onUnassignPartitionsHandler PartitionsRebalanceHandler
+	session                     sarama.ConsumerGroupSession
+	sessionMux                  sync.Mutex
 }
 
 type ConsumerConfig struct {
 	Config  `yaml:",inline"`
-	GroupID string   `env:"GROUP_ID" yaml:"group_id"`
+	GroupID string   `env:"GROUP_ID" yaml:"group_id" validate:"required"`
 	Topics  []string `env:"TOPICS" envSeparator:"," yaml:"topics"`
+
+	// InitialOffset is used when the group has no committed offset for a partition:
+	// "newest" (default) or "oldest".
+	InitialOffset string `env:"INITIAL_OFFSET" envDefault:"newest" yaml:"initial_offset" default:"newest" validate:"oneof=newest oldest"`
+
+	// ManualCommit disables committing offsets after every handled message.
+	// Offsets are committed only by Consumer.Commit then.
+	ManualCommit bool `env:"MANUAL_COMMIT" yaml:"manual_commit"`
 }
 
+const (
+	InitialOffsetNewest = "newest"
+	InitialOffsetOldest = "oldest"
+)
+
 func (c ConsumerConfig) WithDefaults() ConsumerConfig {
-	// Default values could be added here
+	if c.InitialOffset == "" {
+		c.InitialOffset = InitialOffsetNewest
+	}
 
 	return c
 }
@@ -36,15 +55,32 @@ func (c ConsumerConfig) WithDefaults() ConsumerConfig {
 type MessageHandler func(context.Context, *Message) error
 type RebalanceHandler func(ctx context.Context, topic string) error
 
+// PartitionsRebalanceHandler is called with the partitions of the topic assigned to or revoked from the consumer.
+type PartitionsRebalanceHandler func(ctx context.Context, topic string, partitions []int32) error
+
 func NewConsumer(config ConsumerConfig, logger *slog.Logger) (*Consumer, error) {
-	consumerConfig := sarama.NewConfig()
+	config = config.WithDefaults()
+
+	consumerConfig, err := newSaramaConfig(config.Config)
+	if err != nil {
+		return nil, err
+	}
+
 	if config.DebugLog {
 		sarama.Logger = NewSaramaLogger(logger.With(LogsLabelComponent, "sarama"))
 	}
 
 	logger = logger.With(LogsLabelComponent, "kafkalib-consumer")
 
-	consumerConfig.ClientID = config.ClientID
+	switch config.InitialOffset {
+	case InitialOffsetNewest:
+		consumerConfig.Consumer.Offsets.Initial = sarama.OffsetNewest
+	case InitialOffsetOldest:
+		consumerConfig.Consumer.Offsets.Initial = sarama.OffsetOldest
+	default:
+		return nil, fmt.Errorf("unsupported initial offset %q", config.InitialOffset)
+	}
+
 	consumerConfig.Consumer.Group.Rebalance.GroupStrategies =
 		[]sarama.BalanceStrategy{
 			sarama.NewBalanceStrategyRoundRobin(),
@@ -61,7 +97,7 @@ func NewConsumer(config ConsumerConfig, logger *slog.Logger) (*Consumer, error)
 	}
 
 	return &Consumer{
-		ConsumerConfig: config.WithDefaults(),
+		ConsumerConfig: config,
 		consumerGroup:  cg,
 		topics:         config.Topics,
 		logger:         logger,
@@ -77,6 +113,26 @@ func (c *Consumer) SetOnUnassignHandler(h RebalanceHandler) {
 	c.onUnassignPartitionHandler = h
 }
 
+func (c *Consumer) SetOnAssignPartitionsHandler(h PartitionsRebalanceHandler) {
+	c.onAssignPartitionsHandler = h
+}
+
+func (c *Consumer) SetOnUnassignPartitionsHandler(h PartitionsRebalanceHandler) {
+	c.onUnassignPartitionsHandler = h
+}
+
+// Commit marks the offset of the next message to consume from the partition with ManualCommit.
+// Marked offsets are committed periodically and when the partition is revoked.
+// Offsets of the partitions not assigned to the consumer are ignored.
+func (c *Consumer) Commit(topic string, partition int32, offset int64) {
+	c.sessionMux.Lock()
+	defer c.sessionMux.Unlock()
+
+	if c.session != nil {
+		c.session.MarkOffset(topic, partition, offset, "")
+	}
+}
+
 func (c *Consumer) Run(ctx context.Context, handler MessageHandler) error {
 	chanErr := make(chan error)
 
@@ -149,6 +205,10 @@ func (c *Consumer) Run(ctx context.Context, handler MessageHandler) error {
 func (c *Consumer) Setup(session sarama.ConsumerGroupSession) error {
 	ctx := session.Context()
 
+	c.sessionMux.Lock()
+	c.session = session
+	c.sessionMux.Unlock()
+
 	if c.onAssignPartitionHandler != nil {
 		for topic := range session.Claims() {
 			if err := c.onAssignPartitionHandler(ctx, topic); err != nil {
@@ -157,6 +217,14 @@ func (c *Consumer) Setup(session sarama.ConsumerGroupSession) error {
 		}
 	}
 
+	if c.onAssignPartitionsHandler != nil {
+		for topic, partitions := range session.Claims() {
+			if err := c.onAssignPartitionsHandler(ctx, topic, partitions); err != nil {
+				return fmt.Errorf("on assign partitions: %w", err)
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -172,6 +240,18 @@ func (c *Consumer) Cleanup(session sarama.ConsumerGroupSession) error {
 		}
 	}
 
+	if c.onUnassignPartitionsHandler != nil {
+		for topic, partitions := range session.Claims() {
+			if err := c.onUnassignPartitionsHandler(ctx, topic, partitions); err != nil {
+				return fmt.Errorf("on unassign partitions: %w", err)
+			}
+		}
+	}
+
+	c.sessionMux.Lock()
+	c.session = nil
+	c.sessionMux.Unlock()
+
 	return nil
 }
 
@@ -189,11 +269,13 @@ func (c *Consumer) ConsumeClaim(session sarama.ConsumerGroupSession, claim saram
 	for {
 		select {
 		case message := <-claim.Messages():
-			if err := c.messageHandler(session.Context(), message); err != nil {
+			if err := c.messageHandler(session.Context(), message, claim.HighWaterMarkOffset()); err != nil {
 				return err
 			}
 
-			session.MarkMessage(message, "")
+			if !c.ManualCommit {
+				session.MarkMessage(message, "")
+			}
 
 			c.metrics.setConsumerLag(
 				claim.Topic(), claim.Partition(), c.GroupID,
@@ -213,17 +295,13 @@ func (c *Consumer) ConsumeClaim(session sarama.ConsumerGroupSession, claim saram
 	}
 }
 
-func (c *Consumer) messageHandler(ctx context.Context, msg *sarama.ConsumerMessage) error {
+func (c *Consumer) messageHandler(ctx context.Context, msg *sarama.ConsumerMessage, highWaterMark int64) error {
 	if msg == nil {
 		return nil
 	}
 
-	svcMessage := Message{
-		Topic:     msg.Topic,
-		Key:       msg.Key,
-		Payload:   msg.Value,
-		Partition: &msg.Partition,
-	}
+	res := toMessage(msg)
+	res.HighWaterMark = highWaterMark
 
-	return c.handler(ctx, &svcMessage)
+	return c.handler(ctx, res)
 }
@@ -7,7 +7,6 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 
 	"github.com/yvyrovyi-cinemo/utils/kafkalib"
 )
@@ -30,10 +29,8 @@ func run() error {
 			Brokers:  "localhost:9092",
 			DebugLog: false,
 		},
-		GroupID:                 "example-group",
-		Topics:                  []string{"test_topic_unexisting"},
-		FaultToleranceThreshold: 3,
-		FaultTolerancePeriod:    30 * time.Second,
+		GroupID: "example-group",
+		Topics:  []string{"test_topic_unexisting"},
 	}
 
 	client, err := kafkalib.NewConsumer(config.WithDefaults(), logger)
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/yvyrovyi-cinemo/utils/kafkalib"
+	"github.com/yvyrovyi-cinemo/utils/kafkalib/scheduler"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Println("ERR:", err)
+	}
+}
+
+func run() error {
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	kafkaConfig := kafkalib.Config{
+		ClientID: "example-scheduler",
+		Brokers:  "localhost:9092",
+	}
+
+	config := scheduler.Config{
+		ConsumerConfig: kafkalib.ConsumerConfig{
+			Config:  kafkaConfig,
+			GroupID: "example-scheduler",
+			Topics:  []string{"delayed"},
+		},
+	}.WithDefaults()
+
+	consumer, err := kafkalib.NewConsumer(config.ConsumerConfig, logger)
+	if err != nil {
+		return fmt.Errorf("creating kafka consumer: %w", err)
+	}
+
+	producer, err := kafkalib.NewProducer(kafkalib.ProducerConfig{Config: kafkaConfig}, logger)
+	if err != nil {
+		return fmt.Errorf("creating kafka producer: %w", err)
+	}
+	defer producer.Close()
+
+	// a reminder delivered to the "reminders" topic in a minute
+	reminder := &kafkalib.Message{Topic: "reminders", Payload: []byte(`{"text": "stand up"}`)}
+	if _, _, err := producer.ProduceSync(ctx, scheduler.Schedule(reminder, "delayed", time.Now().Add(time.Minute))); err != nil {
+		return fmt.Errorf("scheduling reminder: %w", err)
+	}
+
+	return scheduler.New(config, consumer, producer, logger).Run(ctx)
+}
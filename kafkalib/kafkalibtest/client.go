@@ -0,0 +1,274 @@
+package kafkalibtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/yvyrovyi-cinemo/utils/kafkalib"
+)
+
+var ErrProducerClosed = kafkalib.ErrProducerClosed
+
+type (
+	// Producer writes to the Cluster.
+	// Message.Partition is always honoured when set, like kafkalib.Producer with ManualPartitioning.
+	Producer struct {
+		cluster *Cluster
+		closed  atomic.Bool
+	}
+
+	// Consumer reads the Cluster as a member of a consumer group that owns all partitions of its topics.
+	Consumer struct {
+		kafkalib.ConsumerConfig
+		cluster                     *Cluster
+		onAssignPartitionHandler    kafkalib.RebalanceHandler
+		onUnassignPartitionHandler  kafkalib.RebalanceHandler
+		onAssignPartitionsHandler   kafkalib.PartitionsRebalanceHandler
+		onUnassignPartitionsHandler kafkalib.PartitionsRebalanceHandler
+	}
+
+	// Reader reads the Cluster like kafkalib.Reader.
+	Reader struct {
+		cluster *Cluster
+	}
+)
+
+func (c *Cluster) NewProducer() *Producer {
+	return &Producer{cluster: c}
+}
+
+func (p *Producer) Close() error {
+	p.closed.Store(true)
+	return nil
+}
+
+func (p *Producer) Produce(msg *kafkalib.Message) error {
+	_, _, err := p.ProduceSync(context.Background(), msg)
+	return err
+}
+
+func (p *Producer) ProduceSync(ctx context.Context, msg *kafkalib.Message) (int32, int64, error) {
+	if p.closed.Load() {
+		return 0, 0, ErrProducerClosed
+	}
+
+	if err := ctx.Err(); err != nil {
+		return 0, 0, err
+	}
+
+	return p.cluster.append(msg)
+}
+
+func (c *Cluster) NewConsumer(config kafkalib.ConsumerConfig) *Consumer {
+	return &Consumer{
+		ConsumerConfig: config.WithDefaults(),
+		cluster:        c,
+	}
+}
+
+func (c *Consumer) SetOnAssignHandler(h kafkalib.RebalanceHandler) {
+	c.onAssignPartitionHandler = h
+}
+
+func (c *Consumer) SetOnUnassignHandler(h kafkalib.RebalanceHandler) {
+	c.onUnassignPartitionHandler = h
+}
+
+func (c *Consumer) SetOnAssignPartitionsHandler(h kafkalib.PartitionsRebalanceHandler) {
+	c.onAssignPartitionsHandler = h
+}
+
+func (c *Consumer) SetOnUnassignPartitionsHandler(h kafkalib.PartitionsRebalanceHandler) {
+	c.onUnassignPartitionsHandler = h
+}
+
+// Commit commits the offset like kafkalib.Consumer.Commit, but immediately.
+func (c *Consumer) Commit(topic string, partition int32, offset int64) {
+	c.cluster.commit(c.GroupID, topicPartition{topic: topic, partition: partition}, offset)
+}
+
+// Run delivers messages to the handler until ctx is done or the handler returns an error.
+// Offsets are committed after every successfully handled message unless ManualCommit is set.
+func (c *Consumer) Run(ctx context.Context, handler kafkalib.MessageHandler) (resErr error) {
+	var tps []topicPartition
+	for _, topic := range c.Topics {
+		partitions, err := c.cluster.partitions(topic)
+		if err != nil {
+			return err
+		}
+		for p := int32(0); p < partitions; p++ {
+			tps = append(tps, topicPartition{topic: topic, partition: p})
+		}
+	}
+
+	positions := make([]int64, len(tps))
+	for i, tp := range tps {
+		positions[i] = c.cluster.startOffset(c.GroupID, tp, c.InitialOffset)
+	}
+
+	if err := c.assign(ctx, tps); err != nil {
+		return err
+	}
+	defer func() {
+		resErr = errors.Join(resErr, c.unassign(context.WithoutCancel(ctx), tps))
+	}()
+
+	for {
+		var chanNotify <-chan struct{}
+		delivered := false
+
+		for i, tp := range tps {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			msg, notify := c.cluster.fetch(tp, positions[i])
+			if chanNotify == nil {
+				chanNotify = notify
+			}
+			if msg == nil {
+				continue
+			}
+
+			if err := handler(ctx, msg); err != nil {
+				return err
+			}
+
+			positions[i]++
+			if !c.ManualCommit {
+				c.cluster.commit(c.GroupID, tp, positions[i])
+			}
+			delivered = true
+		}
+
+		if delivered {
+			continue
+		}
+
+		if chanNotify == nil {
+			// no partitions to consume
+			<-ctx.Done()
+			return nil
+		}
+
+		if !waitNotify(ctx, chanNotify) {
+			return nil
+		}
+	}
+}
+
+func (c *Consumer) assign(ctx context.Context, tps []topicPartition) error {
+	if c.onAssignPartitionHandler != nil {
+		for _, topic := range c.Topics {
+			if err := c.onAssignPartitionHandler(ctx, topic); err != nil {
+				return fmt.Errorf("on assign partition: %w", err)
+			}
+		}
+	}
+
+	if c.onAssignPartitionsHandler != nil {
+		for topic, partitions := range groupPartitions(tps) {
+			if err := c.onAssignPartitionsHandler(ctx, topic, partitions); err != nil {
+				return fmt.Errorf("on assign partitions: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *Consumer) unassign(ctx context.Context, tps []topicPartition) error {
+	var resErrs []error
+
+	if c.onUnassignPartitionHandler != nil {
+		for _, topic := range c.Topics {
+			if err := c.onUnassignPartitionHandler(ctx, topic); err != nil {
+				resErrs = append(resErrs, fmt.Errorf("on unassign partition: %w", err))
+			}
+		}
+	}
+
+	if c.onUnassignPartitionsHandler != nil {
+		for topic, partitions := range groupPartitions(tps) {
+			if err := c.onUnassignPartitionsHandler(ctx, topic, partitions); err != nil {
+				resErrs = append(resErrs, fmt.Errorf("on unassign partitions: %w", err))
+			}
+		}
+	}
+
+	return errors.Join(resErrs...)
+}
+
+func groupPartitions(tps []topicPartition) map[string][]int32 {
+	res := make(map[string][]int32)
+	for _, tp := range tps {
+		res[tp.topic] = append(res[tp.topic], tp.partition)
+	}
+
+	return res
+}
+
+func (c *Cluster) NewReader() *Reader {
+	return &Reader{cluster: c}
+}
+
+func (r *Reader) Close() error {
+	return nil
+}
+
+// Read calls the handler for the topic messages like kafkalib.Reader.Read.
+// Partitions are read one after another.
+func (r *Reader) Read(
+	ctx context.Context,
+	topic string,
+	opts kafkalib.ReadOptions,
+	handler kafkalib.MessageHandler,
+) error {
+	partitions := opts.Partitions
+	if len(partitions) == 0 {
+		cnt, err := r.cluster.partitions(topic)
+		if err != nil {
+			return err
+		}
+		for p := int32(0); p < cnt; p++ {
+			partitions = append(partitions, p)
+		}
+	}
+
+	positions := make([]int64, len(partitions))
+	ends := make([]int64, len(partitions))
+	for i, p := range partitions {
+		positions[i], ends[i] = r.cluster.readRange(topicPartition{topic: topic, partition: p}, opts.Start)
+	}
+
+	for {
+		var chanNotify <-chan struct{}
+
+		for i, p := range partitions {
+			for !opts.StopAtEnd || positions[i] < ends[i] {
+				if ctx.Err() != nil {
+					return nil
+				}
+
+				msg, notify := r.cluster.fetch(topicPartition{topic: topic, partition: p}, positions[i])
+				if chanNotify == nil {
+					chanNotify = notify
+				}
+				if msg == nil {
+					break
+				}
+
+				if err := handler(ctx, msg); err != nil {
+					return err
+				}
+				positions[i]++
+			}
+		}
+
+		if opts.StopAtEnd || !waitNotify(ctx, chanNotify) {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,269 @@
+// Package kafkalibtest provides an in-memory Kafka stand-in for testing code built on kafkalib.
+// Its Producer and Consumer have the same method sets as kafkalib.Producer and kafkalib.Consumer.
+package kafkalibtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/yvyrovyi-cinemo/utils/kafkalib"
+)
+
+var (
+	ErrUnknownTopic     = errors.New("unknown topic")
+	ErrTopicExists      = errors.New("topic already exists")
+	ErrInvalidPartition = errors.New("invalid partition")
+)
+
+type (
+	// Cluster keeps topics and committed group offsets in memory.
+	Cluster struct {
+		mux        sync.Mutex
+		topics     map[string][][]kafkalib.Message
+		committed  map[string]map[topicPartition]int64
+		chanNotify chan struct{}
+		roundRobin int
+	}
+
+	topicPartition struct {
+		topic     string
+		partition int32
+	}
+)
+
+func NewCluster() *Cluster {
+	return &Cluster{
+		topics:     make(map[string][][]kafkalib.Message),
+		committed:  make(map[string]map[topicPartition]int64),
+		chanNotify: make(chan struct{}),
+	}
+}
+
+// CreateTopic creates an empty topic with the given number of partitions.
+func (c *Cluster) CreateTopic(name string, partitions int32) error {
+	if partitions <= 0 {
+		return fmt.Errorf("%w: topic %s must have at least one partition", ErrInvalidPartition, name)
+	}
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if _, ok := c.topics[name]; ok {
+		return fmt.Errorf("%w: %s", ErrTopicExists, name)
+	}
+	c.topics[name] = make([][]kafkalib.Message, partitions)
+
+	return nil
+}
+
+// ListTopics returns all topics sorted by name.
+func (c *Cluster) ListTopics() ([]kafkalib.TopicInfo, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	res := make([]kafkalib.TopicInfo, 0, len(c.topics))
+	for name, partitions := range c.topics {
+		res = append(res, kafkalib.TopicInfo{
+			Name:              name,
+			Partitions:        int32(len(partitions)),
+			ReplicationFactor: 1,
+		})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+
+	return res, nil
+}
+
+// Messages returns copies of all messages of the topic ordered by partition and offset.
+func (c *Cluster) Messages(topic string) []kafkalib.Message {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	var res []kafkalib.Message
+	for _, partition := range c.topics[topic] {
+		res = append(res, partition...)
+	}
+
+	return res
+}
+
+// CommittedOffset returns the next offset the group will consume from the partition.
+func (c *Cluster) CommittedOffset(groupID, topic string, partition int32) (int64, bool) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	offset, ok := c.committed[groupID][topicPartition{topic: topic, partition: partition}]
+	return offset, ok
+}
+
+func (c *Cluster) append(msg *kafkalib.Message) (int32, int64, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	partitions, ok := c.topics[msg.Topic]
+	if !ok {
+		return 0, 0, fmt.Errorf("%w: %s", ErrUnknownTopic, msg.Topic)
+	}
+
+	var partition int32
+	switch {
+	case msg.Partition != nil:
+		partition = *msg.Partition
+		if partition < 0 || int(partition) >= len(partitions) {
+			return 0, 0, fmt.Errorf("%w: %s/%d", ErrInvalidPartition, msg.Topic, partition)
+		}
+
+	case msg.Key != nil:
+		hasher := fnv.New32a()
+		_, _ = hasher.Write(msg.Key)
+		partition = int32(hasher.Sum32() % uint32(len(partitions)))
+
+	default:
+		partition = int32(c.roundRobin % len(partitions))
+		c.roundRobin++
+	}
+
+	offset := int64(len(partitions[partition]))
+
+	stored := kafkalib.Message{
+		Topic:     msg.Topic,
+		Key:       cloneBytes(msg.Key),
+		Payload:   cloneBytes(msg.Payload),
+		Headers:   cloneHeaders(msg.Headers),
+		Partition: &partition,
+		Offset:    offset,
+		Timestamp: msg.Timestamp,
+	}
+	if stored.Timestamp.IsZero() {
+		stored.Timestamp = time.Now()
+	}
+
+	partitions[partition] = append(partitions[partition], stored)
+
+	close(c.chanNotify)
+	c.chanNotify = make(chan struct{})
+
+	return partition, offset, nil
+}
+
+// fetch returns the message at the offset and a channel closed on the next write to the cluster.
+func (c *Cluster) fetch(tp topicPartition, offset int64) (*kafkalib.Message, <-chan struct{}) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	partitions := c.topics[tp.topic]
+	if int(tp.partition) >= len(partitions) || offset >= int64(len(partitions[tp.partition])) {
+		return nil, c.chanNotify
+	}
+
+	msg := partitions[tp.partition][offset]
+	msg.HighWaterMark = int64(len(partitions[tp.partition]))
+	msg.Key = cloneBytes(msg.Key)
+	msg.Payload = cloneBytes(msg.Payload)
+	msg.Headers = cloneHeaders(msg.Headers)
+
+	return &msg, c.chanNotify
+}
+
+func (c *Cluster) commit(groupID string, tp topicPartition, offset int64) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if c.committed[groupID] == nil {
+		c.committed[groupID] = make(map[topicPartition]int64)
+	}
+	c.committed[groupID][tp] = offset
+}
+
+// startOffset returns the committed offset of the group or the initial offset when nothing is committed.
+func (c *Cluster) startOffset(groupID string, tp topicPartition, initialOffset string) int64 {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if offset, ok := c.committed[groupID][tp]; ok {
+		return offset
+	}
+
+	if initialOffset == kafkalib.InitialOffsetOldest {
+		return 0
+	}
+
+	partitions := c.topics[tp.topic]
+	if int(tp.partition) >= len(partitions) {
+		return 0
+	}
+
+	return int64(len(partitions[tp.partition]))
+}
+
+// readRange returns the offset to start reading from and the current end offset of the partition.
+func (c *Cluster) readRange(tp topicPartition, start kafkalib.StartPosition) (int64, int64) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	var messages []kafkalib.Message
+	if partitions := c.topics[tp.topic]; int(tp.partition) < len(partitions) {
+		messages = partitions[tp.partition]
+	}
+	end := int64(len(messages))
+
+	switch {
+	case start == kafkalib.StartOldest:
+		return 0, end
+	case !start.Time().IsZero():
+		for i, msg := range messages {
+			if !msg.Timestamp.Before(start.Time()) {
+				return int64(i), end
+			}
+		}
+	}
+
+	return end, end
+}
+
+func (c *Cluster) partitions(topic string) (int32, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	partitions, ok := c.topics[topic]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrUnknownTopic, topic)
+	}
+
+	return int32(len(partitions)), nil
+}
+
+func cloneBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	return append([]byte{}, b...)
+}
+
+func cloneHeaders(headers []kafkalib.Header) []kafkalib.Header {
+	if headers == nil {
+		return nil
+	}
+
+	res := make([]kafkalib.Header, 0, len(headers))
+	for _, h := range headers {
+		res = append(res, kafkalib.Header{Key: h.Key, Value: cloneBytes(h.Value)})
+	}
+
+	return res
+}
+
+// waitNotify waits for the next write to the cluster or the context cancellation.
+func waitNotify(ctx context.Context, chanNotify <-chan struct{}) bool {
+	select {
+	case <-chanNotify:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -1,8 +1,66 @@
 package kafkalib
 
+import (
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
 type Message struct {
 	Topic     string
 	Key       []byte
 	Payload   []byte
+	Headers   []Header
 	Partition *int32
+	Offset    int64
+	Timestamp time.Time
+
+	// HighWaterMark is the offset of the next message of the partition when the message was consumed
+	// by Consumer, 0 if unknown.
+	HighWaterMark int64
+}
+
+type Header struct {
+	Key   string
+	Value []byte
+}
+
+// Header returns the value of the first header with the given key.
+func (m *Message) Header(key string) ([]byte, bool) {
+	for _, h := range m.Headers {
+		if h.Key == key {
+			return h.Value, true
+		}
+	}
+
+	return nil, false
+}
+
+func toSaramaHeaders(headers []Header) []sarama.RecordHeader {
+	if len(headers) == 0 {
+		return nil
+	}
+
+	res := make([]sarama.RecordHeader, 0, len(headers))
+	for _, h := range headers {
+		res = append(res, sarama.RecordHeader{Key: []byte(h.Key), Value: h.Value})
+	}
+
+	return res
+}
+
+func fromSaramaHeaders(headers []*sarama.RecordHeader) []Header {
+	if len(headers) == 0 {
+		return nil
+	}
+
+	res := make([]Header, 0, len(headers))
+	for _, h := range headers {
+		if h == nil {
+			continue
+		}
+		res = append(res, Header{Key: string(h.Key), Value: h.Value})
+	}
+
+	return res
 }
@@ -4,14 +4,24 @@ import (
 	"fmt"
 
 	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/yvyrovyi-cinemo/utils/metrics"
 )
 
 type kafkaMetrics struct {
 	consumerLagGaugeVec *prometheus.GaugeVec
 }
 
+type producerMetrics struct {
+	rateLimitedCounterVec        *prometheus.CounterVec
+	rateLimitWaitCounterVec      *prometheus.CounterVec
+	throttledResponsesCounterVec *prometheus.CounterVec
+	throttleTimeGaugeVec         *prometheus.GaugeVec
+	adaptiveFactorGauge          prometheus.Gauge
+}
+
 func initMetrics() (*kafkaMetrics, error) {
-	consumerLagGaugeVec := prometheus.NewGaugeVec(
+	consumerLagGaugeVec, err := metrics.Register(prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Name: "kafka_partition_lag",
 			Help: "a lag of partition consumer",
@@ -20,9 +30,8 @@ func initMetrics() (*kafkaMetrics, error) {
 			},
 		},
 		[]string{"topic", "partition", "consumer_group"},
-	)
-
-	if err := prometheus.Register(consumerLagGaugeVec); err != nil {
+	))
+	if err != nil {
 		return nil, fmt.Errorf("failed to register consumer lag kafkaMetrics: %w", err)
 	}
 
@@ -31,11 +40,118 @@ func initMetrics() (*kafkaMetrics, error) {
 	}, nil
 }
 
+func initProducerMetrics() (*producerMetrics, error) {
+	constLabels := map[string]string{
+		"service_name": "sarama",
+	}
+
+	rateLimitedCounterVec, err := metrics.Register(prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name:        "kafka_producer_rate_limited_total",
+			Help:        "a number of messages delayed or rejected by the producer rate limits",
+			ConstLabels: constLabels,
+		},
+		[]string{"topic", "outcome"},
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to register producer rate limited metrics: %w", err)
+	}
+
+	rateLimitWaitCounterVec, err := metrics.Register(prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name:        "kafka_producer_rate_limit_wait_seconds_total",
+			Help:        "a time spent waiting for the producer rate limits",
+			ConstLabels: constLabels,
+		},
+		[]string{"topic"},
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to register producer rate limit wait metrics: %w", err)
+	}
+
+	throttledResponsesCounterVec, err := metrics.Register(prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name:        "kafka_producer_throttled_responses_total",
+			Help:        "a number of broker responses with non-zero throttle time",
+			ConstLabels: constLabels,
+		},
+		[]string{"broker"},
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to register producer throttled responses metrics: %w", err)
+	}
+
+	throttleTimeGaugeVec, err := metrics.Register(prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "kafka_producer_throttle_time_ms",
+			Help:        "a mean broker throttle time of the recent responses, 0 when not throttled",
+			ConstLabels: constLabels,
+		},
+		[]string{"broker"},
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to register producer throttle time metrics: %w", err)
+	}
+
+	adaptiveFactorGauge, err := metrics.Register(prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name:        "kafka_producer_adaptive_rate_factor",
+			Help:        "a factor the producer rate limits are scaled by because of broker throttling",
+			ConstLabels: constLabels,
+		},
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to register producer adaptive factor metrics: %w", err)
+	}
+
+	return &producerMetrics{
+		rateLimitedCounterVec:        rateLimitedCounterVec,
+		rateLimitWaitCounterVec:      rateLimitWaitCounterVec,
+		throttledResponsesCounterVec: throttledResponsesCounterVec,
+		throttleTimeGaugeVec:         throttleTimeGaugeVec,
+		adaptiveFactorGauge:          adaptiveFactorGauge,
+	}, nil
+}
+
 func (m *kafkaMetrics) setConsumerLag(
 	topic string,
 	partition int32,
 	consumerGroup string,
 	val int64,
 ) {
+	if m == nil {
+		return
+	}
+
 	m.consumerLagGaugeVec.WithLabelValues(topic, fmt.Sprintf("%d", partition), consumerGroup).Set(float64(val))
 }
+
+func (m *producerMetrics) rateLimited(topic, outcome string, waitSec float64) {
+	if m == nil {
+		return
+	}
+
+	m.rateLimitedCounterVec.WithLabelValues(topic, outcome).Inc()
+	if waitSec > 0 {
+		m.rateLimitWaitCounterVec.WithLabelValues(topic).Add(waitSec)
+	}
+}
+
+func (m *producerMetrics) throttled(broker string, responses int64, meanMs float64) {
+	if m == nil {
+		return
+	}
+
+	if responses > 0 {
+		m.throttledResponsesCounterVec.WithLabelValues(broker).Add(float64(responses))
+	}
+	m.throttleTimeGaugeVec.WithLabelValues(broker).Set(meanMs)
+}
+
+func (m *producerMetrics) setAdaptiveFactor(factor float64) {
+	if m == nil {
+		return
+	}
+
+	m.adaptiveFactorGauge.Set(factor)
+}
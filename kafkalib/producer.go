@@ -1,6 +1,8 @@
 package kafkalib
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"strings"
@@ -10,57 +12,215 @@ import (
 
 type (
 	Producer struct {
-		producer sarama.AsyncProducer
-		logger   *slog.Logger
+		producer    sarama.AsyncProducer
+		rateLimiter *rateLimiter
+		// ctx is cancelled on Close, it bounds the rate limit waits of Produce
+		ctx    context.Context
+		cancel func()
+		logger *slog.Logger
 	}
 
 	ProducerConfig struct {
 		Config `yaml:",inline"`
+
+		// ManualPartitioning makes the producer write a message to Message.Partition when it is set.
+		// Messages without partition are still distributed by key hash.
+		ManualPartitioning bool `env:"MANUAL_PARTITIONING" yaml:"manual_partitioning"`
+
+		RateLimit RateLimitConfig `envPrefix:"RATE_LIMIT_" yaml:"rate_limit"`
+	}
+
+	// produceResult is delivered to ProduceSync once the broker acknowledged or rejected the message.
+	produceResult struct {
+		partition int32
+		offset    int64
+		err       error
+	}
+
+	produceMetadata struct {
+		partition *int32
+		chanRes   chan produceResult
 	}
 )
 
+// ErrProducerClosed is returned when producing to a closed producer.
+var ErrProducerClosed = errors.New("producer is closed")
+
 func NewProducer(config ProducerConfig, logger *slog.Logger) (*Producer, error) {
-	producerConfig := sarama.NewConfig()
-	producerConfig.ClientID = config.ClientID
+	producerConfig, err := newSaramaConfig(config.Config)
+	if err != nil {
+		return nil, err
+	}
 	producerConfig.Metadata.AllowAutoTopicCreation = false
 
-	// we MUST read producer.Errors() chan !!
+	// we MUST read producer.Errors() and producer.Successes() chans !!
 	producerConfig.Producer.Return.Errors = true
+	producerConfig.Producer.Return.Successes = true
+
+	if config.ManualPartitioning {
+		producerConfig.Producer.Partitioner = newExplicitPartitioner
+	}
 
 	if config.DebugLog {
 		sarama.Logger = NewSaramaLogger(logger.With(LogsLabelComponent, "sarama"))
 	}
 
+	logger = logger.With(LogsLabelComponent, "kafkalib-producer")
+
+	metrics, err := initProducerMetrics()
+	if err != nil {
+		logger.Error("failed to init producer metrics", "error", err)
+	}
+
+	rateLimiter, err := newRateLimiter(config.RateLimit, metrics)
+	if err != nil {
+		return nil, err
+	}
+
 	producer, err := sarama.NewAsyncProducer(strings.Split(config.Brokers, ","), producerConfig)
 	if err != nil {
 		return nil, fmt.Errorf("creating producer: %w", err)
 	}
 
-	logger = logger.With(LogsLabelComponent, "kafkalib-producer")
+	ctx, cancel := context.WithCancel(context.Background())
+	if config.RateLimit.Adaptive {
+		go rateLimiter.watchThrottling(ctx, producerConfig.MetricRegistry)
+	}
 
 	go func() {
 		for err := range producer.Errors() {
+			if md, ok := err.Msg.Metadata.(*produceMetadata); ok && md.chanRes != nil {
+				md.chanRes <- produceResult{err: err.Err}
+				continue
+			}
 			logger.Error("kafka producer error", "error", err)
 		}
 	}()
 
+	go func() {
+		for msg := range producer.Successes() {
+			if md, ok := msg.Metadata.(*produceMetadata); ok && md.chanRes != nil {
+				md.chanRes <- produceResult{partition: msg.Partition, offset: msg.Offset}
+			}
+		}
+	}()
+
 	return &Producer{
-		producer: producer,
-		logger:   logger,
+		producer:    producer,
+		rateLimiter: rateLimiter,
+		ctx:         ctx,
+		cancel:      cancel,
+		logger:      logger,
 	}, nil
 }
 
 func (p *Producer) Close() error {
+	p.cancel()
 	return p.producer.Close()
 }
 
+// Produce enqueues the message without waiting for the broker acknowledgement.
+// Delivery errors are logged.
+// It blocks while the rate limits are exceeded or fails with ErrRateLimited, depending on RateLimit.OnExceed.
+// The blocked call fails with ErrProducerClosed when the producer is closed.
 func (p *Producer) Produce(msg *Message) error {
-	p.producer.Input() <- &sarama.ProducerMessage{
-		Topic:    msg.Topic,
-		Key:      sarama.ByteEncoder(msg.Key),
-		Value:    sarama.ByteEncoder(msg.Payload),
-		Metadata: nil,
+	return p.ProduceContext(context.Background(), msg)
+}
+
+// ProduceContext is Produce with ctx bounding the rate limit wait, it returns the ctx error when ctx is done first.
+func (p *Producer) ProduceContext(ctx context.Context, msg *Message) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stop := context.AfterFunc(p.ctx, cancel)
+	defer stop()
+
+	if err := p.rateLimiter.wait(ctx, msg.Topic, len(msg.Key)+len(msg.Payload)); err != nil {
+		if p.ctx.Err() != nil {
+			return ErrProducerClosed
+		}
+		return err
 	}
 
+	p.producer.Input() <- toProducerMessage(msg, nil)
+
 	return nil
 }
+
+// ProduceSync sends the message and waits until the broker acknowledges it.
+// It returns the partition and the offset the message was written to.
+func (p *Producer) ProduceSync(ctx context.Context, msg *Message) (int32, int64, error) {
+	if err := p.rateLimiter.wait(ctx, msg.Topic, len(msg.Key)+len(msg.Payload)); err != nil {
+		return 0, 0, err
+	}
+
+	chanRes := make(chan produceResult, 1)
+
+	select {
+	case p.producer.Input() <- toProducerMessage(msg, chanRes):
+	case <-ctx.Done():
+		return 0, 0, ctx.Err()
+	}
+
+	select {
+	case res := <-chanRes:
+		if res.err != nil {
+			if errors.Is(res.err, sarama.ErrShuttingDown) {
+				return 0, 0, ErrProducerClosed
+			}
+			return 0, 0, fmt.Errorf("producing message: %w", res.err)
+		}
+		return res.partition, res.offset, nil
+
+	case <-ctx.Done():
+		return 0, 0, ctx.Err()
+	}
+}
+
+func toProducerMessage(msg *Message, chanRes chan produceResult) *sarama.ProducerMessage {
+	res := &sarama.ProducerMessage{
+		Topic:     msg.Topic,
+		Key:       sarama.ByteEncoder(msg.Key),
+		Value:     sarama.ByteEncoder(msg.Payload),
+		Headers:   toSaramaHeaders(msg.Headers),
+		Timestamp: msg.Timestamp,
+	}
+
+	if msg.Key == nil {
+		res.Key = nil
+	}
+
+	if msg.Partition != nil || chanRes != nil {
+		res.Metadata = &produceMetadata{
+			partition: msg.Partition,
+			chanRes:   chanRes,
+		}
+	}
+
+	return res
+}
+
+// explicitPartitioner sends a message to the partition requested in Message.Partition
+// and falls back to the hash partitioner otherwise.
+type explicitPartitioner struct {
+	hash sarama.Partitioner
+}
+
+func newExplicitPartitioner(topic string) sarama.Partitioner {
+	return &explicitPartitioner{hash: sarama.NewHashPartitioner(topic)}
+}
+
+func (p *explicitPartitioner) Partition(msg *sarama.ProducerMessage, numPartitions int32) (int32, error) {
+	if md, ok := msg.Metadata.(*produceMetadata); ok && md.partition != nil {
+		if *md.partition < 0 || *md.partition >= numPartitions {
+			return -1, sarama.ErrInvalidPartition
+		}
+		return *md.partition, nil
+	}
+
+	return p.hash.Partition(msg, numPartitions)
+}
+
+func (p *explicitPartitioner) RequiresConsistency() bool {
+	return true
+}
@@ -0,0 +1,354 @@
+package kafkalib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+	"golang.org/x/time/rate"
+)
+
+const (
+	RateLimitOnExceedBlock = "block"
+	RateLimitOnExceedError = "error"
+
+	// throttlePollPeriod is how often broker throttle times are checked.
+	throttlePollPeriod = time.Second
+
+	// minAdaptiveFactor is the lowest share of the configured rate the producer is slowed down to.
+	minAdaptiveFactor = 0.1
+
+	throttleMetricPrefix = "throttle-time-in-ms-for-broker-"
+)
+
+// ErrRateLimited is returned by a producer with the "error" OnExceed policy when a rate limit is exceeded.
+var ErrRateLimited = errors.New("producer rate limit exceeded")
+
+type (
+	RateLimitConfig struct {
+		// MessagesPerSec and BytesPerSec limit all topics together, 0 means unlimited.
+		// Bytes are counted as key plus payload size.
+		MessagesPerSec float64 `env:"MESSAGES_PER_SEC" json:"messages_per_sec" yaml:"messages_per_sec"`
+		BytesPerSec    float64 `env:"BYTES_PER_SEC" json:"bytes_per_sec" yaml:"bytes_per_sec"`
+
+		// Topics limits single topics in addition to the global limits.
+		Topics map[string]TopicRateLimit `json:"topics" yaml:"topics"`
+
+		// OnExceed is "block" to wait until the limit allows the message or "error" to fail with ErrRateLimited.
+		OnExceed string `env:"ON_EXCEED" envDefault:"block" json:"on_exceed" yaml:"on_exceed" default:"block"`
+
+		// Adaptive slows the producer down while brokers respond with a throttle time:
+		// new messages wait for the throttle time and the configured rates are scaled down.
+		Adaptive bool `env:"ADAPTIVE" envDefault:"true" json:"adaptive" yaml:"adaptive" default:"true"`
+	}
+
+	TopicRateLimit struct {
+		MessagesPerSec float64 `json:"messages_per_sec" yaml:"messages_per_sec"`
+		BytesPerSec    float64 `json:"bytes_per_sec" yaml:"bytes_per_sec"`
+	}
+
+	rateLimiter struct {
+		failOnExceed bool
+		global       *topicLimiter
+		topics       map[string]*topicLimiter
+		metrics      *producerMetrics
+
+		mux        sync.Mutex
+		factor     float64
+		pauseUntil time.Time
+
+		// throttleCounts keeps the last seen throttle histogram counts per broker metric.
+		throttleCounts map[string]int64
+	}
+
+	topicLimiter struct {
+		messages     *rate.Limiter
+		bytes        *rate.Limiter
+		baseMessages float64
+		baseBytes    float64
+	}
+
+	limit struct {
+		limiter *rate.Limiter
+		isBytes bool
+	}
+)
+
+func newRateLimiter(config RateLimitConfig, metrics *producerMetrics) (*rateLimiter, error) {
+	switch config.OnExceed {
+	case RateLimitOnExceedBlock, RateLimitOnExceedError, "":
+	default:
+		return nil, fmt.Errorf("unsupported rate limit on exceed policy %q", config.OnExceed)
+	}
+
+	l := &rateLimiter{
+		failOnExceed:   config.OnExceed == RateLimitOnExceedError,
+		global:         newTopicLimiter(config.MessagesPerSec, config.BytesPerSec),
+		topics:         make(map[string]*topicLimiter, len(config.Topics)),
+		metrics:        metrics,
+		factor:         1,
+		throttleCounts: make(map[string]int64),
+	}
+
+	for topic, limit := range config.Topics {
+		l.topics[topic] = newTopicLimiter(limit.MessagesPerSec, limit.BytesPerSec)
+	}
+
+	return l, nil
+}
+
+func newTopicLimiter(messagesPerSec, bytesPerSec float64) *topicLimiter {
+	return &topicLimiter{
+		messages:     newLimiter(messagesPerSec),
+		bytes:        newLimiter(bytesPerSec),
+		baseMessages: messagesPerSec,
+		baseBytes:    bytesPerSec,
+	}
+}
+
+// newLimiter returns nil for no limit. The burst is one second worth of the rate.
+func newLimiter(perSec float64) *rate.Limiter {
+	if perSec <= 0 {
+		return nil
+	}
+
+	return rate.NewLimiter(rate.Limit(perSec), int(math.Max(1, math.Ceil(perSec))))
+}
+
+// wait blocks until the message is allowed by the limits and the broker throttling,
+// or returns ErrRateLimited with the "error" policy.
+func (l *rateLimiter) wait(ctx context.Context, topic string, size int) error {
+	started := time.Now()
+
+	if err := l.waitThrottle(ctx); err != nil {
+		return err
+	}
+
+	limits := l.limits(topic)
+	if len(limits) == 0 {
+		return nil
+	}
+
+	if l.failOnExceed {
+		return l.reserve(topic, size, limits)
+	}
+
+	if err := l.reserveWait(ctx, topic, size, limits); err != nil {
+		return err
+	}
+
+	if waited := time.Since(started); waited > time.Millisecond {
+		l.metrics.rateLimited(topic, "delayed", waited.Seconds())
+	}
+
+	return nil
+}
+
+// reserve takes the tokens from all limiters at once or from none of them.
+func (l *rateLimiter) reserve(topic string, size int, limits []limit) error {
+	now := time.Now()
+
+	reservations, delay, ok := reserveAll(now, size, limits)
+	if !ok || delay > 0 {
+		cancelAll(reservations, now)
+		l.metrics.rateLimited(topic, "rejected", 0)
+		return fmt.Errorf("%w: topic %s", ErrRateLimited, topic)
+	}
+
+	return nil
+}
+
+// reserveWait takes the tokens from all limiters at once and waits for the longest delay of them.
+// The tokens are given back when the wait fails, so that a failed message does not slow down the next ones.
+func (l *rateLimiter) reserveWait(ctx context.Context, topic string, size int, limits []limit) error {
+	now := time.Now()
+
+	reservations, delay, ok := reserveAll(now, size, limits)
+	if !ok {
+		cancelAll(reservations, now)
+		return fmt.Errorf("%w: topic %s message exceeds the burst", ErrRateLimited, topic)
+	}
+	if delay == 0 {
+		return nil
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && deadline.Before(now.Add(delay)) {
+		cancelAll(reservations, now)
+		return fmt.Errorf("waiting for rate limit: %w: the wait of %s exceeds the deadline", context.DeadlineExceeded, delay)
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		// cancelled at the reservation time, as a later cancel keeps the tokens of the reservations without delay
+		cancelAll(reservations, now)
+		return ctx.Err()
+	}
+}
+
+// reserveAll reserves the tokens of the message in every limiter and returns the longest delay.
+// ok is false when a limiter can never allow the message, the reservations are to be cancelled then.
+func reserveAll(now time.Time, size int, limits []limit) ([]*rate.Reservation, time.Duration, bool) {
+	var delay time.Duration
+	reservations := make([]*rate.Reservation, 0, len(limits))
+
+	for _, lim := range limits {
+		r := lim.limiter.ReserveN(now, lim.tokens(size))
+		if !r.OK() {
+			return reservations, 0, false
+		}
+
+		reservations = append(reservations, r)
+		delay = max(delay, r.DelayFrom(now))
+	}
+
+	return reservations, delay, true
+}
+
+func cancelAll(reservations []*rate.Reservation, now time.Time) {
+	for _, r := range reservations {
+		r.CancelAt(now)
+	}
+}
+
+func (l *rateLimiter) limits(topic string) []limit {
+	var res []limit
+
+	for _, tl := range []*topicLimiter{l.global, l.topics[topic]} {
+		if tl == nil {
+			continue
+		}
+		if tl.messages != nil {
+			res = append(res, limit{limiter: tl.messages})
+		}
+		if tl.bytes != nil {
+			res = append(res, limit{limiter: tl.bytes, isBytes: true})
+		}
+	}
+
+	return res
+}
+
+// tokens returns the number of tokens the message takes from the limiter.
+// A message bigger than the bytes burst takes the whole burst, so it is delayed but never rejected forever.
+func (lim limit) tokens(size int) int {
+	if !lim.isBytes {
+		return 1
+	}
+
+	return max(1, min(size, lim.limiter.Burst()))
+}
+
+func (l *rateLimiter) waitThrottle(ctx context.Context) error {
+	l.mux.Lock()
+	pause := time.Until(l.pauseUntil)
+	l.mux.Unlock()
+
+	if pause <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(pause)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// watchThrottling polls sarama broker throttle metrics and adapts the rate until ctx is done.
+func (l *rateLimiter) watchThrottling(ctx context.Context, registry metrics.Registry) {
+	ticker := time.NewTicker(throttlePollPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			l.adapt(l.collectThrottle(registry))
+		}
+	}
+}
+
+// collectThrottle returns the longest mean throttle time of brokers that throttled since the last call.
+func (l *rateLimiter) collectThrottle(registry metrics.Registry) time.Duration {
+	var throttle time.Duration
+
+	registry.Each(func(name string, metric interface{}) {
+		broker, ok := strings.CutPrefix(name, throttleMetricPrefix)
+		if !ok {
+			return
+		}
+
+		histogram, ok := metric.(metrics.Histogram)
+		if !ok {
+			return
+		}
+
+		snapshot := histogram.Snapshot()
+		responses := snapshot.Count() - l.throttleCounts[name]
+		l.throttleCounts[name] = snapshot.Count()
+
+		meanMs := 0.0
+		if responses > 0 {
+			meanMs = snapshot.Mean()
+			throttle = max(throttle, time.Duration(meanMs*float64(time.Millisecond)))
+		}
+
+		l.metrics.throttled(broker, responses, meanMs)
+	})
+
+	return throttle
+}
+
+// adapt halves the rates on throttling and restores them gradually after it stops.
+func (l *rateLimiter) adapt(throttle time.Duration) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	factor := l.factor
+	if throttle > 0 {
+		l.pauseUntil = time.Now().Add(throttle)
+		factor = math.Max(minAdaptiveFactor, factor/2)
+	} else {
+		factor = math.Min(1, factor*1.25)
+	}
+
+	if factor == l.factor {
+		return
+	}
+	l.factor = factor
+
+	for _, tl := range append([]*topicLimiter{l.global}, mapValues(l.topics)...) {
+		if tl.messages != nil {
+			tl.messages.SetLimit(rate.Limit(tl.baseMessages * factor))
+		}
+		if tl.bytes != nil {
+			tl.bytes.SetLimit(rate.Limit(tl.baseBytes * factor))
+		}
+	}
+
+	l.metrics.setAdaptiveFactor(factor)
+}
+
+func mapValues[K comparable, V any](m map[K]V) []V {
+	res := make([]V, 0, len(m))
+	for _, v := range m {
+		res = append(res, v)
+	}
+
+	return res
+}
@@ -0,0 +1,142 @@
+package kafkalib
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestRateLimiter_Error(t *testing.T) {
+	t.Parallel()
+
+	limiter, err := newRateLimiter(RateLimitConfig{
+		MessagesPerSec: 2,
+		OnExceed:       RateLimitOnExceedError,
+		Topics: map[string]TopicRateLimit{
+			"small": {BytesPerSec: 10},
+		},
+	}, nil)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	require.NoError(t, limiter.wait(ctx, "small", 8))
+	// the topic bytes limit rejects without taking the global message token
+	require.ErrorIs(t, limiter.wait(ctx, "small", 8), ErrRateLimited)
+	require.NoError(t, limiter.wait(ctx, "other", 1000))
+	require.ErrorIs(t, limiter.wait(ctx, "other", 1), ErrRateLimited)
+}
+
+func TestRateLimiter_Block(t *testing.T) {
+	t.Parallel()
+
+	limiter, err := newRateLimiter(RateLimitConfig{MessagesPerSec: 1}, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, limiter.wait(context.Background(), "t", 1))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	require.ErrorIs(t, limiter.wait(ctx, "t", 1), context.DeadlineExceeded)
+}
+
+func TestRateLimiter_Block_Cancelled(t *testing.T) {
+	t.Parallel()
+
+	limiter, err := newRateLimiter(RateLimitConfig{
+		MessagesPerSec: 10,
+		Topics:         map[string]TopicRateLimit{"small": {BytesPerSec: 10}},
+	}, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, limiter.wait(context.Background(), "small", 10))
+
+	// the wait beyond the deadline fails at once without taking the global message token
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, limiter.wait(ctx, "small", 8), context.DeadlineExceeded)
+	require.InDelta(t, 9, limiter.global.messages.Tokens(), 0.5)
+
+	// the cancelled wait gives the tokens back
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	require.ErrorIs(t, limiter.wait(ctx, "small", 8), context.Canceled)
+	require.Greater(t, limiter.global.messages.Tokens(), 8.5)
+	require.Greater(t, limiter.topics["small"].bytes.Tokens(), 0.0)
+}
+
+func TestProducer_ProduceContext(t *testing.T) {
+	t.Parallel()
+
+	limiter, err := newRateLimiter(RateLimitConfig{MessagesPerSec: 1}, nil)
+	require.NoError(t, err)
+	require.NoError(t, limiter.wait(context.Background(), "t", 1))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	producer := &Producer{rateLimiter: limiter, ctx: ctx, cancel: cancel}
+
+	// the blocked produce is released by the caller ctx
+	callerCtx, callerCancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, callerCancel)
+	require.ErrorIs(t, producer.ProduceContext(callerCtx, &Message{Topic: "t"}), context.Canceled)
+}
+
+func TestProducer_Produce_Closed(t *testing.T) {
+	t.Parallel()
+
+	limiter, err := newRateLimiter(RateLimitConfig{MessagesPerSec: 1}, nil)
+	require.NoError(t, err)
+	require.NoError(t, limiter.wait(context.Background(), "t", 1))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	producer := &Producer{rateLimiter: limiter, ctx: ctx, cancel: cancel}
+
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	// the blocked produce is released by close
+	require.ErrorIs(t, producer.Produce(&Message{Topic: "t"}), ErrProducerClosed)
+}
+
+func TestRateLimiter_Adaptive(t *testing.T) {
+	t.Parallel()
+
+	limiter, err := newRateLimiter(RateLimitConfig{
+		MessagesPerSec: 100,
+		Topics:         map[string]TopicRateLimit{"t": {BytesPerSec: 1000}},
+	}, nil)
+	require.NoError(t, err)
+
+	registry := metrics.NewRegistry()
+	histogram := metrics.GetOrRegisterHistogram(throttleMetricPrefix+"1", registry, metrics.NewUniformSample(10))
+
+	require.Zero(t, limiter.collectThrottle(registry))
+
+	histogram.Update(200)
+	throttle := limiter.collectThrottle(registry)
+	require.Equal(t, 200*time.Millisecond, throttle)
+
+	limiter.adapt(throttle)
+	require.Equal(t, rate.Limit(50), limiter.global.messages.Limit())
+	require.Equal(t, rate.Limit(500), limiter.topics["t"].bytes.Limit())
+
+	// throttled messages wait for the throttle time
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, limiter.wait(ctx, "t", 1), context.DeadlineExceeded)
+
+	// no new throttled responses restore the rate gradually
+	require.Zero(t, limiter.collectThrottle(registry))
+	limiter.adapt(0)
+	require.Equal(t, rate.Limit(62.5), limiter.global.messages.Limit())
+
+	for i := 0; i < 5; i++ {
+		limiter.adapt(0)
+	}
+	require.Equal(t, rate.Limit(100), limiter.global.messages.Limit())
+}
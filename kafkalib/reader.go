@@ -0,0 +1,240 @@
+package kafkalib
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+type (
+	// Reader reads topic partitions directly, without joining a consumer group and committing offsets.
+	Reader struct {
+		client   sarama.Client
+		consumer sarama.Consumer
+		logger   *slog.Logger
+	}
+
+	ReadOptions struct {
+		Start StartPosition
+		// Partitions to read, all partitions of the topic when empty.
+		Partitions []int32
+		// StopAtEnd stops reading once the newest offsets observed at the start are reached.
+		StopAtEnd bool
+	}
+
+	// StartPosition is the position reading of every partition starts from.
+	StartPosition struct {
+		offset int64
+		tm     time.Time
+	}
+)
+
+var (
+	StartOldest = StartPosition{offset: sarama.OffsetOldest}
+	StartNewest = StartPosition{offset: sarama.OffsetNewest}
+)
+
+// StartAt starts reading from the first message with timestamp not before tm.
+func StartAt(tm time.Time) StartPosition {
+	return StartPosition{tm: tm}
+}
+
+// Time returns the time passed to StartAt or zero time for other positions.
+func (s StartPosition) Time() time.Time {
+	return s.tm
+}
+
+func NewReader(config Config, logger *slog.Logger) (*Reader, error) {
+	readerConfig, err := newSaramaConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.DebugLog {
+		sarama.Logger = NewSaramaLogger(logger.With(LogsLabelComponent, "sarama"))
+	}
+
+	client, err := sarama.NewClient(strings.Split(config.Brokers, ","), readerConfig)
+	if err != nil {
+		return nil, fmt.Errorf("creating client: %w", err)
+	}
+
+	consumer, err := sarama.NewConsumerFromClient(client)
+	if err != nil {
+		_ = client.Close()
+		return nil, fmt.Errorf("creating consumer: %w", err)
+	}
+
+	return &Reader{
+		client:   client,
+		consumer: consumer,
+		logger:   logger.With(LogsLabelComponent, "kafkalib-reader"),
+	}, nil
+}
+
+func (r *Reader) Close() error {
+	if err := r.consumer.Close(); err != nil {
+		return fmt.Errorf("closing consumer: %w", err)
+	}
+
+	return r.client.Close()
+}
+
+// Read calls the handler for every message of the topic until ctx is done, the handler returns an error
+// or, with StopAtEnd, the end of all partitions is reached.
+// The handler is called sequentially, messages of different partitions are interleaved.
+func (r *Reader) Read(ctx context.Context, topic string, opts ReadOptions, handler MessageHandler) error {
+	partitions := opts.Partitions
+	if len(partitions) == 0 {
+		var err error
+		if partitions, err = r.client.Partitions(topic); err != nil {
+			return fmt.Errorf("getting partitions: %w", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	chanMessages := make(chan *sarama.ConsumerMessage)
+	wg := &sync.WaitGroup{}
+
+	for _, partition := range partitions {
+		pc, end, err := r.consumePartition(topic, partition, opts)
+		if err != nil {
+			cancel()
+			wg.Wait()
+			return err
+		}
+
+		if pc == nil {
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.pipePartition(ctx, pc, end, chanMessages)
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(chanMessages)
+	}()
+
+	for msg := range chanMessages {
+		if err := handler(ctx, toMessage(msg)); err != nil {
+			cancel()
+			for range chanMessages {
+				// drain until all partition consumers exit
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// consumePartition starts the partition consumer and returns the offset to stop at (-1 to never stop).
+// It returns nil consumer if there is nothing to read with StopAtEnd.
+func (r *Reader) consumePartition(
+	topic string,
+	partition int32,
+	opts ReadOptions,
+) (sarama.PartitionConsumer, int64, error) {
+	start := opts.Start.offset
+	if !opts.Start.tm.IsZero() {
+		offset, err := r.client.GetOffset(topic, partition, opts.Start.tm.UnixMilli())
+		if err != nil {
+			return nil, 0, fmt.Errorf("getting offset by time of partition %d: %w", partition, err)
+		}
+
+		// -1 means there are no messages after the time
+		start = offset
+		if offset < 0 {
+			start = sarama.OffsetNewest
+		}
+	}
+
+	end := int64(-1)
+	if opts.StopAtEnd {
+		newest, err := r.client.GetOffset(topic, partition, sarama.OffsetNewest)
+		if err != nil {
+			return nil, 0, fmt.Errorf("getting newest offset of partition %d: %w", partition, err)
+		}
+
+		if start == sarama.OffsetNewest || (start >= 0 && start >= newest) {
+			return nil, 0, nil
+		}
+
+		oldest, err := r.client.GetOffset(topic, partition, sarama.OffsetOldest)
+		if err != nil {
+			return nil, 0, fmt.Errorf("getting oldest offset of partition %d: %w", partition, err)
+		}
+
+		if oldest >= newest {
+			return nil, 0, nil
+		}
+
+		end = newest
+	}
+
+	pc, err := r.consumer.ConsumePartition(topic, partition, start)
+	if err != nil {
+		return nil, 0, fmt.Errorf("consuming partition %d: %w", partition, err)
+	}
+
+	return pc, end, nil
+}
+
+func (r *Reader) pipePartition(
+	ctx context.Context,
+	pc sarama.PartitionConsumer,
+	end int64,
+	chanMessages chan<- *sarama.ConsumerMessage,
+) {
+	defer func() {
+		if err := pc.Close(); err != nil {
+			r.logger.Error("closing partition consumer", "error", err)
+		}
+	}()
+
+	for {
+		select {
+		case msg, ok := <-pc.Messages():
+			if !ok {
+				return
+			}
+
+			select {
+			case chanMessages <- msg:
+			case <-ctx.Done():
+				return
+			}
+
+			if end >= 0 && msg.Offset+1 >= end {
+				return
+			}
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func toMessage(msg *sarama.ConsumerMessage) *Message {
+	return &Message{
+		Topic:     msg.Topic,
+		Key:       msg.Key,
+		Payload:   msg.Value,
+		Headers:   fromSaramaHeaders(msg.Headers),
+		Partition: &msg.Partition,
+		Offset:    msg.Offset,
+		Timestamp: msg.Timestamp,
+	}
+}
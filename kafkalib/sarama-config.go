@@ -0,0 +1,52 @@
+package kafkalib
+
+import (
+	"fmt"
+
+	"github.com/IBM/sarama"
+)
+
+// newSaramaConfig builds sarama config with the client ID, TLS and SASL settings of the config applied.
+func newSaramaConfig(config Config) (*sarama.Config, error) {
+	saramaConfig := sarama.NewConfig()
+	saramaConfig.ClientID = config.ClientID
+
+	if config.TLSEnabled {
+		tlsConfig, err := BuildTLSConfig(config)
+		if err != nil {
+			return nil, fmt.Errorf("building TLS config: %w", err)
+		}
+		tlsConfig.InsecureSkipVerify = !config.EnableCertVerification
+
+		saramaConfig.Net.TLS.Enable = true
+		saramaConfig.Net.TLS.Config = tlsConfig
+	}
+
+	if config.SaslUser != "" {
+		saramaConfig.Net.SASL.Enable = true
+		saramaConfig.Net.SASL.User = config.SaslUser
+		saramaConfig.Net.SASL.Password = config.SaslPassword
+
+		switch config.SaslMechanism {
+		case sarama.SASLTypePlaintext:
+			saramaConfig.Net.SASL.Mechanism = sarama.SASLTypePlaintext
+
+		case sarama.SASLTypeSCRAMSHA256:
+			saramaConfig.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA256
+			saramaConfig.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
+				return &XDGSCRAMClient{HashGeneratorFcn: SHA256}
+			}
+
+		case sarama.SASLTypeSCRAMSHA512, "":
+			saramaConfig.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA512
+			saramaConfig.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
+				return &XDGSCRAMClient{HashGeneratorFcn: SHA512}
+			}
+
+		default:
+			return nil, fmt.Errorf("unsupported SASL mechanism %q", config.SaslMechanism)
+		}
+	}
+
+	return saramaConfig, nil
+}
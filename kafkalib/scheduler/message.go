@@ -0,0 +1,69 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/yvyrovyi-cinemo/utils/kafkalib"
+)
+
+const (
+	// HeaderDeliverAt is the RFC 3339 time to deliver the message at.
+	HeaderDeliverAt = "deliver-at"
+	// HeaderTargetTopic is the topic to deliver the message to.
+	HeaderTargetTopic = "target-topic"
+)
+
+var ErrInvalidMessage = errors.New("invalid scheduled message")
+
+// Schedule returns a copy of the message addressed to the delay topic,
+// which the scheduler delivers to the message topic at the time.
+func Schedule(msg *kafkalib.Message, delayTopic string, at time.Time) *kafkalib.Message {
+	headers := make([]kafkalib.Header, 0, len(msg.Headers)+2)
+	headers = append(headers, msg.Headers...)
+	headers = append(headers,
+		kafkalib.Header{Key: HeaderDeliverAt, Value: []byte(at.UTC().Format(time.RFC3339Nano))},
+		kafkalib.Header{Key: HeaderTargetTopic, Value: []byte(msg.Topic)},
+	)
+
+	return &kafkalib.Message{
+		Topic:     delayTopic,
+		Key:       msg.Key,
+		Payload:   msg.Payload,
+		Headers:   headers,
+		Timestamp: msg.Timestamp,
+	}
+}
+
+// parseScheduled returns the message to deliver and the time to deliver it at.
+func parseScheduled(msg *kafkalib.Message) (*kafkalib.Message, time.Time, error) {
+	deliverAt, ok := msg.Header(HeaderDeliverAt)
+	if !ok {
+		return nil, time.Time{}, fmt.Errorf("%w: no %s header", ErrInvalidMessage, HeaderDeliverAt)
+	}
+
+	at, err := time.Parse(time.RFC3339Nano, string(deliverAt))
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("%w: parsing %s header: %v", ErrInvalidMessage, HeaderDeliverAt, err)
+	}
+
+	target, ok := msg.Header(HeaderTargetTopic)
+	if !ok || len(target) == 0 {
+		return nil, time.Time{}, fmt.Errorf("%w: no %s header", ErrInvalidMessage, HeaderTargetTopic)
+	}
+
+	res := &kafkalib.Message{
+		Topic:   string(target),
+		Key:     msg.Key,
+		Payload: msg.Payload,
+	}
+
+	for _, h := range msg.Headers {
+		if h.Key != HeaderDeliverAt && h.Key != HeaderTargetTopic {
+			res.Headers = append(res.Headers, h)
+		}
+	}
+
+	return res, at, nil
+}
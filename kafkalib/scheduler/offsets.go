@@ -0,0 +1,39 @@
+package scheduler
+
+// partitionOffsets tracks consumed messages of a partition until they are delivered.
+// The committed offset is the offset of the oldest undelivered message,
+// so after a restart the messages still held are consumed again.
+type partitionOffsets struct {
+	// pending keeps offsets of consumed messages in the consumed order
+	pending   []int64
+	delivered map[int64]struct{}
+	// next is the offset to commit when nothing is pending
+	next int64
+}
+
+func newPartitionOffsets() *partitionOffsets {
+	return &partitionOffsets{delivered: make(map[int64]struct{})}
+}
+
+func (o *partitionOffsets) consumed(offset int64) {
+	o.pending = append(o.pending, offset)
+}
+
+// done marks the message delivered and returns the offset to commit, if it changed.
+func (o *partitionOffsets) done(offset int64) (int64, bool) {
+	o.delivered[offset] = struct{}{}
+
+	changed := false
+	for len(o.pending) > 0 {
+		if _, ok := o.delivered[o.pending[0]]; !ok {
+			break
+		}
+
+		delete(o.delivered, o.pending[0])
+		o.next = o.pending[0] + 1
+		o.pending = o.pending[1:]
+		changed = true
+	}
+
+	return o.next, changed
+}
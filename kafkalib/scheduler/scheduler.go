@@ -0,0 +1,278 @@
+// Package scheduler delivers Kafka messages at a given time.
+//
+// Producers write messages created by Schedule to a delay topic.
+// The Scheduler consumes the delay topics, holds the messages in memory until they are due
+// and produces them to their target topics.
+// Offsets of the delay topics are committed only up to the oldest message not delivered yet,
+// so a restarted scheduler consumes the held messages again and delivery is at-least-once.
+package scheduler
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/yvyrovyi-cinemo/utils/kafkalib"
+)
+
+type (
+	Config struct {
+		// ConsumerConfig.Topics are the delay topics.
+		kafkalib.ConsumerConfig `yaml:",inline"`
+
+		// BucketSize is the delivery precision: due messages are looked for once per bucket.
+		BucketSize time.Duration `env:"BUCKET_SIZE" envDefault:"1s" yaml:"bucket_size" default:"1s"`
+
+		// MaxPending limits the number of held messages, consuming pauses while the limit is reached.
+		MaxPending int `env:"MAX_PENDING" envDefault:"100000" yaml:"max_pending" default:"100000"`
+	}
+
+	// Consumer is implemented by kafkalib.Consumer created with Config.WithDefaults().ConsumerConfig.
+	Consumer interface {
+		SetOnUnassignPartitionsHandler(h kafkalib.PartitionsRebalanceHandler)
+		Commit(topic string, partition int32, offset int64)
+		Run(ctx context.Context, handler kafkalib.MessageHandler) error
+	}
+
+	// Producer is implemented by kafkalib.Producer.
+	Producer interface {
+		ProduceSync(ctx context.Context, msg *kafkalib.Message) (int32, int64, error)
+	}
+
+	Scheduler struct {
+		config   Config
+		consumer Consumer
+		producer Producer
+		logger   *slog.Logger
+
+		store   *bucketStore
+		offsets map[topicPartition]*partitionOffsets
+		mux     sync.Mutex
+
+		// chanWake wakes the delivery loop when an already due message is consumed
+		chanWake chan struct{}
+		// chanFree is closed when held messages are delivered or dropped
+		chanFree chan struct{}
+	}
+
+	topicPartition struct {
+		topic     string
+		partition int32
+	}
+)
+
+// WithDefaults sets the defaults and enables ConsumerConfig.ManualCommit, which the scheduler relies on.
+func (c Config) WithDefaults() Config {
+	c.ConsumerConfig = c.ConsumerConfig.WithDefaults()
+	c.ManualCommit = true
+
+	if c.BucketSize <= 0 {
+		c.BucketSize = time.Second
+	}
+
+	if c.MaxPending <= 0 {
+		c.MaxPending = 100000
+	}
+
+	return c
+}
+
+func New(config Config, consumer Consumer, producer Producer, logger *slog.Logger) *Scheduler {
+	config = config.WithDefaults()
+
+	s := &Scheduler{
+		config:   config,
+		consumer: consumer,
+		producer: producer,
+		logger:   logger.With(kafkalib.LogsLabelComponent, "kafkalib-scheduler"),
+		store:    newBucketStore(config.BucketSize),
+		offsets:  make(map[topicPartition]*partitionOffsets),
+		chanWake: make(chan struct{}, 1),
+		chanFree: make(chan struct{}),
+	}
+
+	consumer.SetOnUnassignPartitionsHandler(s.unassign)
+
+	return s
+}
+
+// Run consumes and delivers messages until ctx is done or the consumer fails.
+func (s *Scheduler) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s.deliverLoop(ctx)
+	}()
+
+	err := s.consumer.Run(ctx, s.handle)
+
+	cancel()
+	wg.Wait()
+
+	return err
+}
+
+func (s *Scheduler) handle(ctx context.Context, msg *kafkalib.Message) error {
+	if msg.Partition == nil {
+		return nil
+	}
+
+	if !s.waitFree(ctx) {
+		// the message is not committed and is consumed again after restart
+		return nil
+	}
+
+	target, deliverAt, err := parseScheduled(msg)
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	tp := topicPartition{topic: msg.Topic, partition: *msg.Partition}
+	offsets, ok := s.offsets[tp]
+	if !ok {
+		offsets = newPartitionOffsets()
+		s.offsets[tp] = offsets
+	}
+	offsets.consumed(msg.Offset)
+
+	if err != nil {
+		s.logger.Warn("skipping scheduled message",
+			"topic", msg.Topic, "partition", *msg.Partition, "offset", msg.Offset, "error", err)
+		s.commit(tp, msg.Offset)
+		return nil
+	}
+
+	s.store.add(&scheduled{
+		msg:       target,
+		deliverAt: deliverAt,
+		topic:     tp.topic,
+		partition: tp.partition,
+		offset:    msg.Offset,
+	})
+
+	if !deliverAt.After(time.Now()) {
+		select {
+		case s.chanWake <- struct{}{}:
+		default:
+		}
+	}
+
+	return nil
+}
+
+// waitFree waits while MaxPending messages are held. It returns false if ctx is done.
+func (s *Scheduler) waitFree(ctx context.Context) bool {
+	for {
+		s.mux.Lock()
+		if s.store.count < s.config.MaxPending {
+			s.mux.Unlock()
+			return true
+		}
+		chanFree := s.chanFree
+		s.mux.Unlock()
+
+		select {
+		case <-chanFree:
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+func (s *Scheduler) deliverLoop(ctx context.Context) {
+	ticker := time.NewTicker(s.config.BucketSize)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-s.chanWake:
+		case <-ctx.Done():
+			return
+		}
+
+		s.deliverDue(ctx)
+	}
+}
+
+func (s *Scheduler) deliverDue(ctx context.Context) {
+	s.mux.Lock()
+	due := s.store.due(time.Now())
+	s.mux.Unlock()
+
+	for i, msg := range due {
+		if _, _, err := s.producer.ProduceSync(ctx, msg.msg); err != nil {
+			if ctx.Err() == nil {
+				s.logger.Error("delivering scheduled message, will retry",
+					"topic", msg.msg.Topic, "deliver_at", msg.deliverAt, "error", err)
+			}
+
+			s.hold(due[i:])
+			return
+		}
+
+		s.mux.Lock()
+		s.commit(topicPartition{topic: msg.topic, partition: msg.partition}, msg.offset)
+		s.mux.Unlock()
+	}
+
+	if len(due) > 0 {
+		s.mux.Lock()
+		s.free()
+		s.mux.Unlock()
+	}
+}
+
+// free wakes the handlers waiting in waitFree, it must be called with s.mux locked.
+func (s *Scheduler) free() {
+	close(s.chanFree)
+	s.chanFree = make(chan struct{})
+}
+
+// hold returns undelivered messages to the store, unless their partitions were revoked meanwhile.
+func (s *Scheduler) hold(messages []*scheduled) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	for _, msg := range messages {
+		if _, ok := s.offsets[topicPartition{topic: msg.topic, partition: msg.partition}]; ok {
+			s.store.add(msg)
+		}
+	}
+}
+
+// commit must be called with s.mux locked.
+func (s *Scheduler) commit(tp topicPartition, offset int64) {
+	offsets, ok := s.offsets[tp]
+	if !ok {
+		// the partition was revoked while the message was delivered
+		return
+	}
+
+	if next, changed := offsets.done(offset); changed {
+		s.consumer.Commit(tp.topic, tp.partition, next)
+	}
+}
+
+// unassign drops the messages of the revoked partitions, their new owner consumes them again.
+func (s *Scheduler) unassign(_ context.Context, topic string, partitions []int32) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	count := s.store.count
+	for _, partition := range partitions {
+		delete(s.offsets, topicPartition{topic: topic, partition: partition})
+		s.store.removePartition(topic, partition)
+	}
+
+	if s.store.count < count {
+		s.free()
+	}
+
+	return nil
+}
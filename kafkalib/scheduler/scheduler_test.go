@@ -0,0 +1,188 @@
+package scheduler
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/yvyrovyi-cinemo/utils/kafkalib"
+	"github.com/yvyrovyi-cinemo/utils/kafkalib/kafkalibtest"
+)
+
+func TestScheduler_Deliver(t *testing.T) {
+	t.Parallel()
+
+	cluster := newTestCluster(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	runScheduler(ctx, t, cluster)
+
+	deliverAt := time.Now().Add(200 * time.Millisecond)
+	produceScheduled(t, cluster, "later", deliverAt)
+	produceScheduled(t, cluster, "now", time.Now().Add(-time.Second))
+	require.NoError(t, cluster.NewProducer().Produce(&kafkalib.Message{Topic: "delays", Payload: []byte("invalid")}))
+
+	require.Eventually(t, func() bool {
+		return len(cluster.Messages("reminders")) == 1
+	}, time.Second, 5*time.Millisecond)
+
+	// the held message keeps the committed offset
+	_, ok := cluster.CommittedOffset("scheduler", "delays", 0)
+	require.False(t, ok)
+
+	require.Eventually(t, func() bool {
+		return len(cluster.Messages("reminders")) == 2
+	}, time.Second, 5*time.Millisecond)
+	require.False(t, time.Now().Before(deliverAt))
+
+	messages := cluster.Messages("reminders")
+	require.Equal(t, "now", string(messages[0].Payload))
+	require.Equal(t, "later", string(messages[1].Payload))
+	require.Equal(t, []kafkalib.Header{{Key: "trace-id", Value: []byte("1")}}, messages[1].Headers)
+
+	require.Eventually(t, func() bool {
+		offset, _ := cluster.CommittedOffset("scheduler", "delays", 0)
+		return offset == 3
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestScheduler_Restart(t *testing.T) {
+	t.Parallel()
+
+	cluster := newTestCluster(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	wait := runScheduler(ctx, t, cluster)
+
+	produceScheduled(t, cluster, "reminder", time.Now().Add(300*time.Millisecond))
+	time.Sleep(50 * time.Millisecond)
+
+	cancel()
+	wait()
+	require.Empty(t, cluster.Messages("reminders"))
+
+	// the restarted scheduler consumes the held message again
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	runScheduler(ctx, t, cluster)
+
+	require.Eventually(t, func() bool {
+		return len(cluster.Messages("reminders")) == 1
+	}, time.Second, 5*time.Millisecond)
+
+	time.Sleep(50 * time.Millisecond)
+	require.Len(t, cluster.Messages("reminders"), 1)
+}
+
+func TestScheduler_UnassignFrees(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cluster := newTestCluster(t)
+	config := Config{
+		ConsumerConfig: kafkalib.ConsumerConfig{GroupID: "scheduler", Topics: []string{"delays"}},
+		MaxPending:     1,
+	}
+	s := New(config, cluster.NewConsumer(config.ConsumerConfig), cluster.NewProducer(), slog.Default())
+	s.store.add(&scheduled{deliverAt: time.Now().Add(time.Hour), topic: "delays"})
+
+	chanFree := make(chan bool, 1)
+	go func() {
+		chanFree <- s.waitFree(ctx)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case <-chanFree:
+		require.Fail(t, "handler is not waiting")
+	default:
+	}
+
+	// the dropped messages of the revoked partition free the handler
+	require.NoError(t, s.unassign(ctx, "delays", []int32{0}))
+
+	select {
+	case free := <-chanFree:
+		require.True(t, free)
+	case <-time.After(time.Second):
+		require.Fail(t, "handler is still waiting")
+	}
+}
+
+func TestBucketStore(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	store := newBucketStore(time.Minute)
+
+	for i, delay := range []time.Duration{90 * time.Second, 10 * time.Second, -time.Hour, 30 * time.Second} {
+		store.add(&scheduled{deliverAt: now.Add(delay), topic: "delays", partition: int32(i % 2), offset: int64(i)})
+	}
+	require.Equal(t, 4, store.count)
+
+	due := store.due(now.Add(20 * time.Second))
+	require.Len(t, due, 2)
+	require.Equal(t, int64(2), due[0].offset)
+	require.Equal(t, int64(1), due[1].offset)
+
+	store.removePartition("delays", 1)
+	require.Equal(t, 1, store.count)
+
+	due = store.due(now.Add(time.Hour))
+	require.Len(t, due, 1)
+	require.Equal(t, int64(0), due[0].offset)
+	require.Empty(t, store.starts)
+}
+
+func newTestCluster(t *testing.T) *kafkalibtest.Cluster {
+	t.Helper()
+
+	cluster := kafkalibtest.NewCluster()
+	require.NoError(t, cluster.CreateTopic("delays", 1))
+	require.NoError(t, cluster.CreateTopic("reminders", 1))
+
+	return cluster
+}
+
+// runScheduler runs the scheduler in background and returns the function waiting for it to stop.
+func runScheduler(ctx context.Context, t *testing.T, cluster *kafkalibtest.Cluster) func() {
+	t.Helper()
+
+	config := Config{
+		ConsumerConfig: kafkalib.ConsumerConfig{
+			GroupID:       "scheduler",
+			Topics:        []string{"delays"},
+			InitialOffset: kafkalib.InitialOffsetOldest,
+		},
+		BucketSize: 10 * time.Millisecond,
+	}.WithDefaults()
+
+	s := New(config, cluster.NewConsumer(config.ConsumerConfig), cluster.NewProducer(), slog.Default())
+
+	chanErr := make(chan error, 1)
+	go func() {
+		chanErr <- s.Run(ctx)
+	}()
+
+	return func() {
+		require.NoError(t, <-chanErr)
+	}
+}
+
+func produceScheduled(t *testing.T, cluster *kafkalibtest.Cluster, payload string, at time.Time) {
+	t.Helper()
+
+	msg := &kafkalib.Message{
+		Topic:   "reminders",
+		Payload: []byte(payload),
+		Headers: []kafkalib.Header{{Key: "trace-id", Value: []byte("1")}},
+	}
+
+	require.NoError(t, cluster.NewProducer().Produce(Schedule(msg, "delays", at)))
+}
@@ -0,0 +1,102 @@
+package scheduler
+
+import (
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/yvyrovyi-cinemo/utils/kafkalib"
+)
+
+type (
+	// scheduled is a message held until its delivery time.
+	scheduled struct {
+		msg       *kafkalib.Message
+		deliverAt time.Time
+		topic     string
+		partition int32
+		offset    int64
+	}
+
+	// bucketStore groups scheduled messages into buckets by delivery time,
+	// so that only the due buckets are looked at on every tick.
+	bucketStore struct {
+		bucketSize time.Duration
+		buckets    map[int64][]*scheduled
+		// starts keeps the bucket start times sorted
+		starts []int64
+		count  int
+	}
+)
+
+func newBucketStore(bucketSize time.Duration) *bucketStore {
+	return &bucketStore{
+		bucketSize: bucketSize,
+		buckets:    make(map[int64][]*scheduled),
+	}
+}
+
+func (s *bucketStore) add(msg *scheduled) {
+	start := msg.deliverAt.Truncate(s.bucketSize).UnixNano()
+
+	if _, ok := s.buckets[start]; !ok {
+		idx, _ := slices.BinarySearch(s.starts, start)
+		s.starts = slices.Insert(s.starts, idx, start)
+	}
+
+	s.buckets[start] = append(s.buckets[start], msg)
+	s.count++
+}
+
+// due removes and returns the messages to deliver at the time, ordered by delivery time.
+func (s *bucketStore) due(now time.Time) []*scheduled {
+	var res []*scheduled
+
+	for len(s.starts) > 0 && s.starts[0] <= now.UnixNano() {
+		start := s.starts[0]
+
+		var rest []*scheduled
+		for _, msg := range s.buckets[start] {
+			if msg.deliverAt.After(now) {
+				rest = append(rest, msg)
+			} else {
+				res = append(res, msg)
+			}
+		}
+
+		if len(rest) > 0 {
+			s.buckets[start] = rest
+			break
+		}
+
+		delete(s.buckets, start)
+		s.starts = s.starts[1:]
+	}
+
+	s.count -= len(res)
+	sort.SliceStable(res, func(i, j int) bool { return res[i].deliverAt.Before(res[j].deliverAt) })
+
+	return res
+}
+
+// removePartition removes the messages consumed from the partition.
+func (s *bucketStore) removePartition(topic string, partition int32) {
+	starts := s.starts[:0]
+
+	for _, start := range s.starts {
+		bucket := slices.DeleteFunc(s.buckets[start], func(msg *scheduled) bool {
+			return msg.topic == topic && msg.partition == partition
+		})
+		s.count -= len(s.buckets[start]) - len(bucket)
+
+		if len(bucket) == 0 {
+			delete(s.buckets, start)
+			continue
+		}
+
+		s.buckets[start] = bucket
+		starts = append(starts, start)
+	}
+
+	s.starts = starts
+}
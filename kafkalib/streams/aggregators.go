@@ -0,0 +1,93 @@
+package streams
+
+import (
+	"math"
+
+	"github.com/yvyrovyi-cinemo/utils/kafkalib"
+)
+
+type (
+	// Aggregator folds the messages of a window into an aggregate.
+	// Aggregates are stored and emitted as JSON.
+	Aggregator[A any] interface {
+		// Init returns the aggregate of an empty window.
+		Init() A
+		// Add returns the aggregate with the message added.
+		// An error stops the processor, so skip bad messages by returning the aggregate unchanged.
+		Add(agg A, msg *kafkalib.Message) (A, error)
+		// Merge combines aggregates of two session windows merged by a message between them.
+		Merge(a, b A) A
+	}
+
+	// Count counts messages.
+	Count struct{}
+
+	// StatsValue is the aggregate of Stats.
+	StatsValue struct {
+		Count int64   `json:"count"`
+		Sum   float64 `json:"sum"`
+		Min   float64 `json:"min"`
+		Max   float64 `json:"max"`
+	}
+
+	// Stats aggregates count, sum, min and max of values extracted from messages,
+	// like circlebuf does for a rolling time frame.
+	Stats struct {
+		value func(*kafkalib.Message) (float64, error)
+	}
+)
+
+func (Count) Init() int64 {
+	return 0
+}
+
+func (Count) Add(agg int64, _ *kafkalib.Message) (int64, error) {
+	return agg + 1, nil
+}
+
+func (Count) Merge(a, b int64) int64 {
+	return a + b
+}
+
+// NewStats returns Stats of the values returned by the function.
+func NewStats(value func(*kafkalib.Message) (float64, error)) Stats {
+	return Stats{value: value}
+}
+
+func (Stats) Init() StatsValue {
+	return StatsValue{}
+}
+
+func (s Stats) Add(agg StatsValue, msg *kafkalib.Message) (StatsValue, error) {
+	value, err := s.value(msg)
+	if err != nil {
+		return agg, err
+	}
+
+	return s.Merge(agg, StatsValue{Count: 1, Sum: value, Min: value, Max: value}), nil
+}
+
+func (Stats) Merge(a, b StatsValue) StatsValue {
+	if a.Count == 0 {
+		return b
+	}
+	if b.Count == 0 {
+		return a
+	}
+
+	return StatsValue{
+		Count: a.Count + b.Count,
+		Sum:   a.Sum + b.Sum,
+		Min:   math.Min(a.Min, b.Min),
+		Max:   math.Max(a.Max, b.Max),
+	}
+}
+
+// Avg returns the mean value, 0 for an empty window.
+func (v StatsValue) Avg() float64 {
+	if v.Count == 0 {
+		return 0
+	}
+
+	return v.Sum / float64(v.Count)
+}
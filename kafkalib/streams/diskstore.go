@@ -0,0 +1,221 @@
+package streams
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	diskRecordHeaderSize = 8
+	// compaction starts when the file has at least this many bytes of overwritten records
+	// and they take more space than the live ones.
+	diskCompactMinGarbage = 1 << 20
+)
+
+type (
+	// DiskStore keeps values in an append-only file and only the key index in memory.
+	// The file is rewritten without overwritten records when they take more space than the live ones.
+	DiskStore struct {
+		path    string
+		file    *os.File
+		size    int64
+		garbage int64
+		index   map[string]diskValue
+		mux     sync.RWMutex
+	}
+
+	diskValue struct {
+		offset int64
+		size   int
+	}
+)
+
+// DiskStores returns a factory of stores kept in the dir, one file per partition.
+// The name distinguishes stores of different processors sharing the dir.
+func DiskStores(dir, name string) StoreFactory {
+	return func(partition int32) (Store, error) {
+		return OpenDiskStore(filepath.Join(dir, fmt.Sprintf("%s-%d.db", name, partition)))
+	}
+}
+
+// OpenDiskStore creates an empty store in the file, truncating the file if it exists.
+func OpenDiskStore(path string) (*DiskStore, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("opening store file: %w", err)
+	}
+
+	return &DiskStore{
+		path:  path,
+		file:  file,
+		index: make(map[string]diskValue),
+	}, nil
+}
+
+func (s *DiskStore) Get(key string) ([]byte, bool, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	value, ok := s.index[key]
+	if !ok {
+		return nil, false, nil
+	}
+
+	res, err := s.read(value)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return res, true, nil
+}
+
+func (s *DiskStore) Put(key string, value []byte) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	offset, err := s.write(key, value)
+	if err != nil {
+		return err
+	}
+
+	s.forget(key)
+	s.index[key] = diskValue{offset: offset, size: len(value)}
+	s.size = offset + int64(len(value))
+
+	return s.compactIfNeeded()
+}
+
+func (s *DiskStore) Delete(key string) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.forget(key)
+	delete(s.index, key)
+
+	return s.compactIfNeeded()
+}
+
+func (s *DiskStore) Range(fn func(key string, value []byte) bool) error {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	for key, value := range s.index {
+		res, err := s.read(value)
+		if err != nil {
+			return err
+		}
+
+		if !fn(key, res) {
+			break
+		}
+	}
+
+	return nil
+}
+
+// Close closes and removes the file, since the store is restored from the changelog anyway.
+func (s *DiskStore) Close() error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if err := s.file.Close(); err != nil {
+		return fmt.Errorf("closing store file: %w", err)
+	}
+
+	return os.Remove(s.path)
+}
+
+func (s *DiskStore) read(value diskValue) ([]byte, error) {
+	res := make([]byte, value.size)
+	if _, err := s.file.ReadAt(res, value.offset); err != nil {
+		return nil, fmt.Errorf("reading store file: %w", err)
+	}
+
+	return res, nil
+}
+
+// write appends the record to the file and returns the offset of the value.
+func (s *DiskStore) write(key string, value []byte) (int64, error) {
+	if _, err := s.file.WriteAt(encodeRecord(key, value), s.size); err != nil {
+		return 0, fmt.Errorf("writing store file: %w", err)
+	}
+
+	return s.size + int64(diskRecordHeaderSize+len(key)), nil
+}
+
+// encodeRecord encodes the key and value lengths followed by the key and value.
+// The key is kept to make the file self-describing, records are never read back on start.
+func encodeRecord(key string, value []byte) []byte {
+	record := make([]byte, diskRecordHeaderSize, diskRecordHeaderSize+len(key)+len(value))
+	binary.BigEndian.PutUint32(record, uint32(len(key)))
+	binary.BigEndian.PutUint32(record[4:], uint32(len(value)))
+	record = append(record, key...)
+
+	return append(record, value...)
+}
+
+// forget counts the current record of the key as garbage.
+func (s *DiskStore) forget(key string) {
+	if old, ok := s.index[key]; ok {
+		s.garbage += int64(diskRecordHeaderSize + len(key) + old.size)
+	}
+}
+
+func (s *DiskStore) compactIfNeeded() error {
+	if s.garbage < diskCompactMinGarbage || s.garbage*2 < s.size {
+		return nil
+	}
+
+	tmpPath := s.path + ".tmp"
+
+	tmp, err := os.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("creating compacted store file: %w", err)
+	}
+
+	index, size, err := s.copyLive(tmp)
+	if err == nil {
+		err = os.Rename(tmpPath, s.path)
+	}
+	if err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("compacting store file: %w", err)
+	}
+
+	_ = s.file.Close()
+
+	s.file = tmp
+	s.index = index
+	s.size = size
+	s.garbage = 0
+
+	return nil
+}
+
+func (s *DiskStore) copyLive(tmp *os.File) (map[string]diskValue, int64, error) {
+	index := make(map[string]diskValue, len(s.index))
+	writer := bufio.NewWriter(tmp)
+	var size int64
+
+	for key, value := range s.index {
+		res, err := s.read(value)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if _, err := writer.Write(encodeRecord(key, res)); err != nil {
+			return nil, 0, err
+		}
+
+		offset := size + int64(diskRecordHeaderSize+len(key))
+		index[key] = diskValue{offset: offset, size: len(res)}
+		size = offset + int64(len(res))
+	}
+
+	return index, size, writer.Flush()
+}
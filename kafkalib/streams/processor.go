@@ -0,0 +1,566 @@
+// Package streams implements windowed aggregations of Kafka messages by key on top of kafkalib.
+//
+// The Processor consumes the input topic, folds messages into per-key windows with an Aggregator
+// and produces the aggregates to the output topic as WindowedResult JSON.
+// Aggregation state lives in a Store per input partition and every change is written
+// to a compacted changelog topic, so the state is restored from it when a partition is assigned after a rebalance.
+package streams
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/yvyrovyi-cinemo/utils/kafkalib"
+)
+
+const (
+	// EmitOnClose produces a window aggregate once, when the window closes.
+	EmitOnClose EmitMode = iota
+	// EmitOnUpdate produces the window aggregate after every message added to it.
+	EmitOnUpdate
+)
+
+var ErrInvalidConfig = errors.New("invalid streams config")
+
+type (
+	EmitMode int
+
+	Config struct {
+		InputTopic  string
+		OutputTopic string
+		// ChangelogTopic must be a compacted topic with the same number of partitions as InputTopic.
+		// Partition N of the changelog keeps the state of the input partition N,
+		// so the producer must write to Message.Partition (kafkalib.ProducerConfig.ManualPartitioning).
+		ChangelogTopic string
+
+		Windows Windows
+		// Grace is how long a window accepts out-of-order messages after its close time.
+		// Time is the stream time: the newest message timestamp seen in the partition.
+		Grace time.Duration
+		Emit  EmitMode
+
+		// Stores creates partition stores, in-memory by default.
+		Stores StoreFactory
+	}
+
+	// Consumer is implemented by kafkalib.Consumer.
+	Consumer interface {
+		SetOnAssignPartitionsHandler(h kafkalib.PartitionsRebalanceHandler)
+		SetOnUnassignPartitionsHandler(h kafkalib.PartitionsRebalanceHandler)
+		Run(ctx context.Context, handler kafkalib.MessageHandler) error
+	}
+
+	// Producer is implemented by kafkalib.Producer.
+	Producer interface {
+		ProduceSync(ctx context.Context, msg *kafkalib.Message) (int32, int64, error)
+	}
+
+	// Reader is implemented by kafkalib.Reader.
+	Reader interface {
+		Read(ctx context.Context, topic string, opts kafkalib.ReadOptions, handler kafkalib.MessageHandler) error
+	}
+
+	// WindowedResult is the payload of the output messages, which are keyed by the input message key.
+	WindowedResult[A any] struct {
+		Key string `json:"key"`
+		Window
+		Value A `json:"value"`
+	}
+
+	Processor[A any] struct {
+		config     Config
+		aggregator Aggregator[A]
+		consumer   Consumer
+		producer   Producer
+		reader     Reader
+		logger     *slog.Logger
+		partitions map[int32]*partitionState
+		mux        sync.Mutex
+	}
+
+	partitionState struct {
+		partition  int32
+		store      Store
+		streamTime time.Time
+		// deadlines keeps the earliest close time of the key windows.
+		deadlines map[string]time.Time
+		// nextClose is not after the earliest deadline, windows are not checked for closing before it.
+		nextClose time.Time
+		mux       sync.Mutex
+	}
+
+	// keyState is the stored and changelog value of a key.
+	keyState[A any] struct {
+		Seen    time.Time        `json:"seen"`
+		Windows []windowState[A] `json:"windows"`
+	}
+
+	windowState[A any] struct {
+		Window
+		Value A `json:"value"`
+	}
+)
+
+func NewProcessor[A any](
+	config Config,
+	aggregator Aggregator[A],
+	consumer Consumer,
+	producer Producer,
+	reader Reader,
+	logger *slog.Logger,
+) (*Processor[A], error) {
+	if config.InputTopic == "" || config.OutputTopic == "" || config.ChangelogTopic == "" {
+		return nil, fmt.Errorf("%w: input, output and changelog topics are required", ErrInvalidConfig)
+	}
+
+	if !config.Windows.isSession() && (config.Windows.size <= 0 || config.Windows.advance <= 0) {
+		return nil, fmt.Errorf("%w: windows are not set", ErrInvalidConfig)
+	}
+
+	if config.Stores == nil {
+		config.Stores = MemoryStores()
+	}
+
+	p := &Processor[A]{
+		config:     config,
+		aggregator: aggregator,
+		consumer:   consumer,
+		producer:   producer,
+		reader:     reader,
+		logger:     logger.With(kafkalib.LogsLabelComponent, "kafkalib-streams"),
+		partitions: make(map[int32]*partitionState),
+	}
+
+	consumer.SetOnAssignPartitionsHandler(p.assign)
+	consumer.SetOnUnassignPartitionsHandler(p.unassign)
+
+	return p, nil
+}
+
+// Run processes messages until ctx is done or an error occurs.
+// Input offsets are committed after the state changes and results are produced, so delivery is at-least-once.
+func (p *Processor[A]) Run(ctx context.Context) error {
+	return p.consumer.Run(ctx, p.handle)
+}
+
+func (p *Processor[A]) handle(ctx context.Context, msg *kafkalib.Message) error {
+	if msg.Topic != p.config.InputTopic {
+		return nil
+	}
+
+	if msg.Key == nil {
+		p.logger.Debug("skipping message without key", "partition", msg.Partition, "offset", msg.Offset)
+		return nil
+	}
+
+	state := p.partition(msg.Partition)
+	if state == nil {
+		return fmt.Errorf("partition %v is not assigned", msg.Partition)
+	}
+
+	state.mux.Lock()
+	defer state.mux.Unlock()
+
+	if msg.Timestamp.After(state.streamTime) {
+		state.streamTime = msg.Timestamp
+	}
+
+	if err := p.aggregate(ctx, state, msg); err != nil {
+		return err
+	}
+
+	return p.closeWindows(ctx, state)
+}
+
+func (p *Processor[A]) partition(partition *int32) *partitionState {
+	if partition == nil {
+		return nil
+	}
+
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	return p.partitions[*partition]
+}
+
+func (p *Processor[A]) aggregate(ctx context.Context, state *partitionState, msg *kafkalib.Message) error {
+	key := string(msg.Key)
+
+	ks, err := p.load(state, key)
+	if err != nil {
+		return err
+	}
+
+	var updated []windowState[A]
+	if p.config.Windows.isSession() {
+		ks.Windows, updated, err = p.addToSession(state, ks.Windows, msg)
+	} else {
+		ks.Windows, updated, err = p.addToWindows(state, ks.Windows, msg)
+	}
+	if err != nil {
+		return fmt.Errorf("aggregating message: %w", err)
+	}
+
+	if len(updated) == 0 {
+		p.logger.Debug("skipping late message", "key", key, "partition", state.partition, "offset", msg.Offset)
+		return nil
+	}
+
+	if msg.Timestamp.After(ks.Seen) {
+		ks.Seen = msg.Timestamp
+	}
+
+	if err := p.save(ctx, state, key, ks); err != nil {
+		return err
+	}
+
+	if p.config.Emit != EmitOnUpdate {
+		return nil
+	}
+
+	return p.emit(ctx, key, updated)
+}
+
+func (p *Processor[A]) addToWindows(
+	state *partitionState,
+	windows []windowState[A],
+	msg *kafkalib.Message,
+) ([]windowState[A], []windowState[A], error) {
+	var updated []windowState[A]
+
+	for _, w := range p.config.Windows.windowsFor(msg.Timestamp) {
+		if p.closed(state, w) {
+			continue
+		}
+
+		idx := sort.Search(len(windows), func(i int) bool { return !windows[i].Start.Before(w.Start) })
+		if idx == len(windows) || !windows[idx].Start.Equal(w.Start) {
+			windows = slices.Insert(windows, idx, windowState[A]{Window: w, Value: p.aggregator.Init()})
+		}
+
+		value, err := p.aggregator.Add(windows[idx].Value, msg)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		windows[idx].Value = value
+		updated = append(updated, windows[idx])
+	}
+
+	return windows, updated, nil
+}
+
+// addToSession adds the message to a new session window and merges into it the sessions within the gap.
+func (p *Processor[A]) addToSession(
+	state *partitionState,
+	windows []windowState[A],
+	msg *kafkalib.Message,
+) ([]windowState[A], []windowState[A], error) {
+	session := windowState[A]{Window: Window{Start: msg.Timestamp, End: msg.Timestamp}}
+	if p.closed(state, session.Window) {
+		return windows, nil, nil
+	}
+
+	value, err := p.aggregator.Add(p.aggregator.Init(), msg)
+	if err != nil {
+		return nil, nil, err
+	}
+	session.Value = value
+
+	res := make([]windowState[A], 0, len(windows)+1)
+	for _, w := range windows {
+		if p.closed(state, w.Window) || !p.config.Windows.mergeable(w.Window, msg.Timestamp) {
+			res = append(res, w)
+			continue
+		}
+
+		session.Start = minTime(session.Start, w.Start)
+		session.End = maxTime(session.End, w.End)
+		session.Value = p.aggregator.Merge(w.Value, session.Value)
+	}
+
+	res = append(res, session)
+	sort.Slice(res, func(i, j int) bool { return res[i].Start.Before(res[j].Start) })
+
+	return res, []windowState[A]{session}, nil
+}
+
+func (p *Processor[A]) closed(state *partitionState, w Window) bool {
+	return !p.config.Windows.closeTime(w).Add(p.config.Grace).After(state.streamTime)
+}
+
+// closeWindows removes the windows closed by the stream time and emits them with EmitOnClose.
+func (p *Processor[A]) closeWindows(ctx context.Context, state *partitionState) error {
+	if state.nextClose.IsZero() || state.nextClose.Add(p.config.Grace).After(state.streamTime) {
+		return nil
+	}
+
+	var keys []string
+	for key, deadline := range state.deadlines {
+		if !deadline.Add(p.config.Grace).After(state.streamTime) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if err := p.closeKeyWindows(ctx, state, key); err != nil {
+			return err
+		}
+	}
+
+	state.nextClose = time.Time{}
+	for _, deadline := range state.deadlines {
+		if state.nextClose.IsZero() || deadline.Before(state.nextClose) {
+			state.nextClose = deadline
+		}
+	}
+
+	return nil
+}
+
+func (p *Processor[A]) closeKeyWindows(ctx context.Context, state *partitionState, key string) error {
+	ks, err := p.load(state, key)
+	if err != nil {
+		return err
+	}
+
+	var open, closed []windowState[A]
+	for _, w := range ks.Windows {
+		if p.closed(state, w.Window) {
+			closed = append(closed, w)
+		} else {
+			open = append(open, w)
+		}
+	}
+	ks.Windows = open
+
+	if err := p.save(ctx, state, key, ks); err != nil {
+		return err
+	}
+
+	if p.config.Emit != EmitOnClose {
+		return nil
+	}
+
+	return p.emit(ctx, key, closed)
+}
+
+func (p *Processor[A]) load(state *partitionState, key string) (keyState[A], error) {
+	var ks keyState[A]
+
+	data, ok, err := state.store.Get(key)
+	if err != nil {
+		return ks, fmt.Errorf("getting state of key %s: %w", key, err)
+	}
+
+	if ok {
+		if err := json.Unmarshal(data, &ks); err != nil {
+			return ks, fmt.Errorf("decoding state of key %s: %w", key, err)
+		}
+	}
+
+	return ks, nil
+}
+
+// save writes the key state to the changelog and the store, a key without windows is deleted.
+func (p *Processor[A]) save(ctx context.Context, state *partitionState, key string, ks keyState[A]) error {
+	var data []byte
+	if len(ks.Windows) > 0 {
+		var err error
+		if data, err = json.Marshal(ks); err != nil {
+			return fmt.Errorf("encoding state of key %s: %w", key, err)
+		}
+	}
+
+	_, _, err := p.producer.ProduceSync(ctx, &kafkalib.Message{
+		Topic:     p.config.ChangelogTopic,
+		Key:       []byte(key),
+		Payload:   data,
+		Partition: &state.partition,
+		Timestamp: ks.Seen,
+	})
+	if err != nil {
+		return fmt.Errorf("writing changelog: %w", err)
+	}
+
+	if data == nil {
+		err = state.store.Delete(key)
+	} else {
+		err = state.store.Put(key, data)
+	}
+	if err != nil {
+		return fmt.Errorf("storing state of key %s: %w", key, err)
+	}
+
+	p.track(state, key, ks)
+
+	return nil
+}
+
+func (p *Processor[A]) track(state *partitionState, key string, ks keyState[A]) {
+	if len(ks.Windows) == 0 {
+		delete(state.deadlines, key)
+		return
+	}
+
+	var deadline time.Time
+	for _, w := range ks.Windows {
+		if closeTime := p.config.Windows.closeTime(w.Window); deadline.IsZero() || closeTime.Before(deadline) {
+			deadline = closeTime
+		}
+	}
+
+	state.deadlines[key] = deadline
+	if state.nextClose.IsZero() || deadline.Before(state.nextClose) {
+		state.nextClose = deadline
+	}
+}
+
+func (p *Processor[A]) emit(ctx context.Context, key string, windows []windowState[A]) error {
+	for _, w := range windows {
+		data, err := json.Marshal(WindowedResult[A]{Key: key, Window: w.Window, Value: w.Value})
+		if err != nil {
+			return fmt.Errorf("encoding result: %w", err)
+		}
+
+		_, _, err = p.producer.ProduceSync(ctx, &kafkalib.Message{
+			Topic:     p.config.OutputTopic,
+			Key:       []byte(key),
+			Payload:   data,
+			Timestamp: w.End,
+		})
+		if err != nil {
+			return fmt.Errorf("producing result: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// assign restores the partition states from the changelog.
+// The consumer does not start consuming the partitions until the restore is done.
+func (p *Processor[A]) assign(ctx context.Context, topic string, partitions []int32) error {
+	if topic != p.config.InputTopic {
+		return nil
+	}
+
+	for _, partition := range partitions {
+		state, err := p.restore(ctx, partition)
+		if err != nil {
+			return fmt.Errorf("restoring partition %d: %w", partition, err)
+		}
+
+		p.mux.Lock()
+		p.partitions[partition] = state
+		p.mux.Unlock()
+	}
+
+	return nil
+}
+
+func (p *Processor[A]) restore(ctx context.Context, partition int32) (*partitionState, error) {
+	store, err := p.config.Stores(partition)
+	if err != nil {
+		return nil, fmt.Errorf("creating store: %w", err)
+	}
+
+	state := &partitionState{
+		partition: partition,
+		store:     store,
+		deadlines: make(map[string]time.Time),
+	}
+
+	opts := kafkalib.ReadOptions{
+		Start:      kafkalib.StartOldest,
+		Partitions: []int32{partition},
+		StopAtEnd:  true,
+	}
+
+	var restored int
+	err = p.reader.Read(ctx, p.config.ChangelogTopic, opts, func(_ context.Context, msg *kafkalib.Message) error {
+		restored++
+		return p.restoreKey(state, msg)
+	})
+	if err != nil {
+		return nil, errors.Join(err, store.Close())
+	}
+
+	p.logger.Info("partition state restored",
+		"partition", partition,
+		"changelog_messages", restored,
+		"keys", len(state.deadlines),
+	)
+
+	return state, nil
+}
+
+func (p *Processor[A]) restoreKey(state *partitionState, msg *kafkalib.Message) error {
+	key := string(msg.Key)
+
+	var ks keyState[A]
+	if msg.Payload != nil {
+		if err := json.Unmarshal(msg.Payload, &ks); err != nil {
+			return fmt.Errorf("decoding changelog of key %s: %w", key, err)
+		}
+	}
+
+	var err error
+	if len(ks.Windows) == 0 {
+		err = state.store.Delete(key)
+	} else {
+		err = state.store.Put(key, msg.Payload)
+	}
+	if err != nil {
+		return fmt.Errorf("storing state of key %s: %w", key, err)
+	}
+
+	if ks.Seen.After(state.streamTime) {
+		state.streamTime = ks.Seen
+	}
+	p.track(state, key, ks)
+
+	return nil
+}
+
+func (p *Processor[A]) unassign(_ context.Context, topic string, partitions []int32) error {
+	if topic != p.config.InputTopic {
+		return nil
+	}
+
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	var errs []error
+	for _, partition := range partitions {
+		if state, ok := p.partitions[partition]; ok {
+			state.mux.Lock()
+			errs = append(errs, state.store.Close())
+			state.mux.Unlock()
+			delete(p.partitions, partition)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,242 @@
+package streams
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/yvyrovyi-cinemo/utils/kafkalib"
+	"github.com/yvyrovyi-cinemo/utils/kafkalib/kafkalibtest"
+)
+
+var testStart = time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+func TestWindows(t *testing.T) {
+	t.Parallel()
+
+	ts := testStart.Add(90 * time.Second)
+
+	require.Equal(t,
+		[]Window{{Start: testStart.Add(time.Minute), End: testStart.Add(2 * time.Minute)}},
+		Tumbling(time.Minute).windowsFor(ts),
+	)
+
+	require.Equal(t,
+		[]Window{
+			{Start: testStart, End: testStart.Add(2 * time.Minute)},
+			{Start: testStart.Add(time.Minute), End: testStart.Add(3 * time.Minute)},
+		},
+		Hopping(2*time.Minute, time.Minute).windowsFor(ts),
+	)
+
+	session := Session(time.Minute)
+	require.True(t, session.mergeable(Window{Start: testStart, End: testStart}, testStart.Add(time.Minute)))
+	require.False(t, session.mergeable(Window{Start: testStart, End: testStart}, ts))
+	require.Equal(t, testStart.Add(time.Minute), session.closeTime(Window{Start: testStart, End: testStart}))
+}
+
+func TestProcessor_TumblingCount(t *testing.T) {
+	t.Parallel()
+
+	cluster := newTestCluster(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	runProcessor(ctx, t, cluster, Config{Windows: Tumbling(time.Minute), Grace: 10 * time.Second}, Count{})
+
+	produce(t, cluster, "a", testStart.Add(10*time.Second))
+	produce(t, cluster, "a", testStart.Add(50*time.Second))
+	produce(t, cluster, "b", testStart.Add(20*time.Second))
+	// within the grace period the window is still open
+	produce(t, cluster, "b", testStart.Add(65*time.Second))
+	produce(t, cluster, "a", testStart.Add(30*time.Second))
+	// closes the first window, the late message is dropped
+	produce(t, cluster, "b", testStart.Add(75*time.Second))
+	produce(t, cluster, "a", testStart.Add(40*time.Second))
+
+	results := waitResults[int64](t, cluster, 2)
+	require.Equal(t, []WindowedResult[int64]{
+		{Key: "a", Window: Window{Start: testStart, End: testStart.Add(time.Minute)}, Value: 3},
+		{Key: "b", Window: Window{Start: testStart, End: testStart.Add(time.Minute)}, Value: 1},
+	}, results)
+}
+
+func TestProcessor_Restore(t *testing.T) {
+	t.Parallel()
+
+	cluster := newTestCluster(t)
+	config := Config{
+		Windows: Hopping(2*time.Minute, time.Minute),
+		Emit:    EmitOnUpdate,
+		Stores:  DiskStores(t.TempDir(), "stats"),
+	}
+	stats := NewStats(func(msg *kafkalib.Message) (float64, error) {
+		return strconv.ParseFloat(string(msg.Payload), 64)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	wait := runProcessor(ctx, t, cluster, config, stats)
+
+	produceValue(t, cluster, "a", testStart.Add(70*time.Second), "4")
+	waitResults[StatsValue](t, cluster, 2)
+
+	cancel()
+	wait()
+
+	// the new processor continues from the state restored from the changelog
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	runProcessor(ctx, t, cluster, config, stats)
+
+	produceValue(t, cluster, "a", testStart.Add(100*time.Second), "2")
+
+	results := waitResults[StatsValue](t, cluster, 4)
+	require.Equal(t, WindowedResult[StatsValue]{
+		Key:    "a",
+		Window: Window{Start: testStart, End: testStart.Add(2 * time.Minute)},
+		Value:  StatsValue{Count: 2, Sum: 6, Min: 2, Max: 4},
+	}, results[2])
+	require.InDelta(t, 3, results[2].Value.Avg(), 0.001)
+	require.Equal(t, StatsValue{Count: 2, Sum: 6, Min: 2, Max: 4}, results[3].Value)
+}
+
+func TestProcessor_SessionMerge(t *testing.T) {
+	t.Parallel()
+
+	cluster := newTestCluster(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	runProcessor(ctx, t, cluster, Config{Windows: Session(time.Minute), Grace: time.Minute}, Count{})
+
+	produce(t, cluster, "a", testStart)
+	produce(t, cluster, "a", testStart.Add(100*time.Second))
+	// joins the two sessions
+	produce(t, cluster, "a", testStart.Add(50*time.Second))
+	produce(t, cluster, "a", testStart.Add(10*time.Minute))
+
+	results := waitResults[int64](t, cluster, 1)
+	require.Equal(t, []WindowedResult[int64]{
+		{Key: "a", Window: Window{Start: testStart, End: testStart.Add(100 * time.Second)}, Value: 3},
+	}, results)
+}
+
+func TestDiskStore(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "store.db")
+	store, err := OpenDiskStore(path)
+	require.NoError(t, err)
+
+	value := make([]byte, 64<<10)
+	for i := 0; i < 40; i++ {
+		value[0] = byte(i)
+		require.NoError(t, store.Put("big", value))
+	}
+	require.NoError(t, store.Put("small", []byte("v")))
+	require.NoError(t, store.Delete("deleted"))
+
+	got, ok, err := store.Get("big")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, byte(39), got[0])
+
+	// overwritten values were compacted
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Less(t, info.Size(), int64(2<<20))
+
+	keys := map[string]string{}
+	require.NoError(t, store.Range(func(key string, value []byte) bool {
+		keys[key] = string(value[:1])
+		return true
+	}))
+	require.Len(t, keys, 2)
+	require.Equal(t, "v", keys["small"])
+
+	require.NoError(t, store.Close())
+	require.NoFileExists(t, path)
+}
+
+func newTestCluster(t *testing.T) *kafkalibtest.Cluster {
+	t.Helper()
+
+	cluster := kafkalibtest.NewCluster()
+	require.NoError(t, cluster.CreateTopic("events", 1))
+	require.NoError(t, cluster.CreateTopic("events-changelog", 1))
+	require.NoError(t, cluster.CreateTopic("counts", 1))
+
+	return cluster
+}
+
+// runProcessor runs the processor in background and returns the function waiting for it to stop.
+func runProcessor[A any](
+	ctx context.Context,
+	t *testing.T,
+	cluster *kafkalibtest.Cluster,
+	config Config,
+	aggregator Aggregator[A],
+) func() {
+	t.Helper()
+
+	config.InputTopic = "events"
+	config.OutputTopic = "counts"
+	config.ChangelogTopic = "events-changelog"
+
+	consumer := cluster.NewConsumer(kafkalib.ConsumerConfig{
+		GroupID:       "streams",
+		Topics:        []string{"events"},
+		InitialOffset: kafkalib.InitialOffsetOldest,
+	})
+
+	processor, err := NewProcessor(config, aggregator, consumer, cluster.NewProducer(), cluster.NewReader(), slog.Default())
+	require.NoError(t, err)
+
+	chanErr := make(chan error, 1)
+	go func() {
+		chanErr <- processor.Run(ctx)
+	}()
+
+	return func() {
+		require.NoError(t, <-chanErr)
+	}
+}
+
+func produce(t *testing.T, cluster *kafkalibtest.Cluster, key string, ts time.Time) {
+	produceValue(t, cluster, key, ts, "")
+}
+
+func produceValue(t *testing.T, cluster *kafkalibtest.Cluster, key string, ts time.Time, value string) {
+	t.Helper()
+
+	require.NoError(t, cluster.NewProducer().Produce(&kafkalib.Message{
+		Topic:     "events",
+		Key:       []byte(key),
+		Payload:   []byte(value),
+		Timestamp: ts,
+	}))
+}
+
+func waitResults[A any](t *testing.T, cluster *kafkalibtest.Cluster, n int) []WindowedResult[A] {
+	t.Helper()
+
+	require.Eventually(t, func() bool {
+		return len(cluster.Messages("counts")) >= n
+	}, time.Second, 10*time.Millisecond)
+
+	var res []WindowedResult[A]
+	for _, msg := range cluster.Messages("counts") {
+		var result WindowedResult[A]
+		require.NoError(t, json.Unmarshal(msg.Payload, &result))
+		res = append(res, result)
+	}
+
+	return res
+}
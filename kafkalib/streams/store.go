@@ -0,0 +1,79 @@
+package streams
+
+import (
+	"sync"
+)
+
+type (
+	// Store keeps the aggregation state of one input partition.
+	// The processor restores it from the changelog topic on every partition assignment,
+	// so a store always starts empty.
+	Store interface {
+		Get(key string) ([]byte, bool, error)
+		Put(key string, value []byte) error
+		Delete(key string) error
+		// Range calls fn for every key until fn returns false.
+		Range(fn func(key string, value []byte) bool) error
+		Close() error
+	}
+
+	// StoreFactory creates an empty store for the input partition.
+	StoreFactory func(partition int32) (Store, error)
+
+	MemoryStore struct {
+		values map[string][]byte
+		mux    sync.RWMutex
+	}
+)
+
+// MemoryStores returns a factory of in-memory stores.
+func MemoryStores() StoreFactory {
+	return func(int32) (Store, error) {
+		return NewMemoryStore(), nil
+	}
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{values: make(map[string][]byte)}
+}
+
+func (s *MemoryStore) Get(key string) ([]byte, bool, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	value, ok := s.values[key]
+	return value, ok, nil
+}
+
+func (s *MemoryStore) Put(key string, value []byte) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.values[key] = append([]byte{}, value...)
+	return nil
+}
+
+func (s *MemoryStore) Delete(key string) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	delete(s.values, key)
+	return nil
+}
+
+func (s *MemoryStore) Range(fn func(key string, value []byte) bool) error {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	for key, value := range s.values {
+		if !fn(key, value) {
+			break
+		}
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
@@ -0,0 +1,76 @@
+package streams
+
+import (
+	"time"
+)
+
+type (
+	// Windows defines how messages of a key are grouped into windows.
+	// Create it with Tumbling, Hopping or Session.
+	Windows struct {
+		size    time.Duration
+		advance time.Duration
+		gap     time.Duration
+	}
+
+	// Window is a time range [Start, End) of a windowed aggregation.
+	// Session windows end at the timestamp of their last message.
+	Window struct {
+		Start time.Time `json:"start"`
+		End   time.Time `json:"end"`
+	}
+)
+
+// Tumbling returns fixed-size, non-overlapping windows.
+// Windows are aligned to the size, so 1 minute windows start at whole minutes.
+func Tumbling(size time.Duration) Windows {
+	return Windows{size: size, advance: size}
+}
+
+// Hopping returns fixed-size windows starting every advance, so a message may fall into several windows.
+func Hopping(size, advance time.Duration) Windows {
+	return Windows{size: size, advance: advance}
+}
+
+// Session returns windows of activity of a key, which are closed after the inactivity gap.
+func Session(gap time.Duration) Windows {
+	return Windows{gap: gap}
+}
+
+func (w Windows) isSession() bool {
+	return w.gap > 0
+}
+
+// windowsFor returns the time windows containing the timestamp, the oldest first.
+func (w Windows) windowsFor(ts time.Time) []Window {
+	if w.isSession() {
+		return []Window{{Start: ts, End: ts}}
+	}
+
+	var res []Window
+
+	start := ts.Truncate(w.advance)
+	for ; start.After(ts.Add(-w.size)); start = start.Add(-w.advance) {
+		res = append(res, Window{Start: start, End: start.Add(w.size)})
+	}
+
+	for i, j := 0, len(res)-1; i < j; i, j = i+1, j-1 {
+		res[i], res[j] = res[j], res[i]
+	}
+
+	return res
+}
+
+// closeTime returns the time after which no message can change the window.
+func (w Windows) closeTime(window Window) time.Time {
+	if w.isSession() {
+		return window.End.Add(w.gap)
+	}
+
+	return window.End
+}
+
+// mergeable reports whether a session window is close enough to the timestamp to be extended by it.
+func (w Windows) mergeable(window Window, ts time.Time) bool {
+	return !ts.Before(window.Start.Add(-w.gap)) && !ts.After(window.End.Add(w.gap))
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/yvyrovyi-cinemo/utils/kafkalib"
+)
+
+func (c *command) groups(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: groups list|describe <group>|lag <group>", errUsage)
+	}
+
+	admin, err := kafkalib.NewAdmin(c.config, c.logger)
+	if err != nil {
+		return fmt.Errorf("creating admin: %w", err)
+	}
+	defer func() { _ = admin.Close() }()
+
+	switch args[0] {
+	case "list":
+		groups, err := admin.ListGroups()
+		if err != nil {
+			return err
+		}
+		for _, g := range groups {
+			fmt.Fprintln(c.stdout, g)
+		}
+		return nil
+
+	case "describe":
+		if len(args) != 2 {
+			return fmt.Errorf("%w: groups describe <group>", errUsage)
+		}
+		return c.describeGroup(admin, args[1])
+
+	case "lag":
+		if len(args) != 2 {
+			return fmt.Errorf("%w: groups lag <group>", errUsage)
+		}
+		return c.groupLag(admin, args[1])
+
+	default:
+		return fmt.Errorf("%w: unknown groups command %q", errUsage, args[0])
+	}
+}
+
+func (c *command) describeGroup(admin *kafkalib.Admin, group string) error {
+	description, err := admin.DescribeGroup(group)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.stdout, "group: %s\nstate: %s\nprotocol: %s/%s\n\n",
+		description.GroupID, description.State, description.ProtocolType, description.Protocol)
+
+	w := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "MEMBER\tCLIENT ID\tHOST\tASSIGNMENT")
+	for _, m := range description.Members {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", m.MemberID, m.ClientID, m.ClientHost, formatAssignment(m.Assignment))
+	}
+
+	return w.Flush()
+}
+
+func (c *command) groupLag(admin *kafkalib.Admin, group string) error {
+	lags, err := admin.GroupLag(group)
+	if err != nil {
+		return err
+	}
+
+	var total int64
+
+	w := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "TOPIC\tPARTITION\tCOMMITTED\tNEWEST\tLAG")
+	for _, l := range lags {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\n", l.Topic, l.Partition, l.CommittedOffset, l.NewestOffset, l.Lag)
+		total += l.Lag
+	}
+	fmt.Fprintf(w, "TOTAL\t\t\t\t%d\n", total)
+
+	return w.Flush()
+}
+
+func (c *command) topics(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: topics list|describe <topic>", errUsage)
+	}
+
+	admin, err := kafkalib.NewAdmin(c.config, c.logger)
+	if err != nil {
+		return fmt.Errorf("creating admin: %w", err)
+	}
+	defer func() { _ = admin.Close() }()
+
+	switch args[0] {
+	case "list":
+		topics, err := admin.ListTopics()
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "TOPIC\tPARTITIONS\tREPLICATION")
+		for _, t := range topics {
+			fmt.Fprintf(w, "%s\t%d\t%d\n", t.Name, t.Partitions, t.ReplicationFactor)
+		}
+		return w.Flush()
+
+	case "describe":
+		if len(args) != 2 {
+			return fmt.Errorf("%w: topics describe <topic>", errUsage)
+		}
+		return c.describeTopic(admin, args[1])
+
+	default:
+		return fmt.Errorf("%w: unknown topics command %q", errUsage, args[0])
+	}
+}
+
+func (c *command) describeTopic(admin *kafkalib.Admin, topic string) error {
+	description, err := admin.DescribeTopic(topic)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "PARTITION\tLEADER\tREPLICAS\tISR\tOLDEST\tNEWEST")
+	for _, p := range description.Partitions {
+		fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%d\t%d\n",
+			p.ID, p.Leader, formatInts(p.Replicas), formatInts(p.ISR), p.OldestOffset, p.NewestOffset)
+	}
+
+	return w.Flush()
+}
+
+func formatAssignment(assignment map[string][]int32) string {
+	topics := make([]string, 0, len(assignment))
+	for topic := range assignment {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+
+	parts := make([]string, 0, len(topics))
+	for _, topic := range topics {
+		parts = append(parts, topic+":"+formatInts(assignment[topic]))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func formatInts(values []int32) string {
+	parts := make([]string, 0, len(values))
+	for _, v := range values {
+		parts = append(parts, fmt.Sprint(v))
+	}
+
+	return strings.Join(parts, ",")
+}
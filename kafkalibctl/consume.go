@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yvyrovyi-cinemo/utils/kafkalib"
+)
+
+// outputMessage is the JSON representation of a consumed message.
+// Value is embedded as JSON when the payload is valid JSON and as a string otherwise.
+type outputMessage struct {
+	Topic     string            `json:"topic"`
+	Partition int32             `json:"partition"`
+	Offset    int64             `json:"offset"`
+	Timestamp time.Time         `json:"timestamp"`
+	Key       *string           `json:"key"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Value     any               `json:"value"`
+}
+
+func (c *command) consume(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("consume", flag.ContinueOnError)
+	topic := flags.String("topic", "", "topic to consume")
+	from := flags.String("from", "latest", "start position: earliest, latest, RFC3339 time or negative duration, e.g. -1h")
+	partitionsFlag := flags.String("partitions", "", "comma separated partitions, all by default")
+	filterExpr := flags.String("filter", "", `filter expression, e.g. 'key == "a" && value.amount > 10'`)
+	limit := flags.Int("n", 0, "exit after printing n messages")
+	exitAtEnd := flags.Bool("exit", false, "exit when the end of all partitions is reached")
+	pretty := flags.Bool("pretty", false, "indent JSON output")
+
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+
+	if *topic == "" {
+		return fmt.Errorf("%w: -topic is required", errUsage)
+	}
+
+	start, err := parseStart(*from, time.Now())
+	if err != nil {
+		return err
+	}
+
+	partitions, err := parsePartitions(*partitionsFlag)
+	if err != nil {
+		return err
+	}
+
+	msgFilter, err := parseFilter(*filterExpr)
+	if err != nil {
+		return err
+	}
+
+	reader, err := kafkalib.NewReader(c.config, c.logger)
+	if err != nil {
+		return fmt.Errorf("creating reader: %w", err)
+	}
+	defer func() { _ = reader.Close() }()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	printed := 0
+	opts := kafkalib.ReadOptions{Start: start, Partitions: partitions, StopAtEnd: *exitAtEnd}
+
+	return reader.Read(ctx, *topic, opts, func(_ context.Context, msg *kafkalib.Message) error {
+		// the messages already fetched are still handled after cancel
+		if *limit > 0 && printed >= *limit {
+			return nil
+		}
+
+		if !msgFilter.match(msg) {
+			return nil
+		}
+
+		if err := writeMessage(c.stdout, msg, *pretty); err != nil {
+			return err
+		}
+
+		printed++
+		if *limit > 0 && printed >= *limit {
+			cancel()
+		}
+		return nil
+	})
+}
+
+func parseStart(from string, now time.Time) (kafkalib.StartPosition, error) {
+	switch from {
+	case "earliest", "oldest":
+		return kafkalib.StartOldest, nil
+	case "latest", "newest":
+		return kafkalib.StartNewest, nil
+	}
+
+	if tm, err := time.Parse(time.RFC3339, from); err == nil {
+		return kafkalib.StartAt(tm), nil
+	}
+
+	if d, err := time.ParseDuration(from); err == nil && d < 0 {
+		return kafkalib.StartAt(now.Add(d)), nil
+	}
+
+	return kafkalib.StartPosition{}, fmt.Errorf("%w: invalid -from %q", errUsage, from)
+}
+
+func parsePartitions(s string) ([]int32, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	var res []int32
+	for _, p := range strings.Split(s, ",") {
+		v, err := strconv.ParseInt(strings.TrimSpace(p), 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid partition %q", errUsage, p)
+		}
+		res = append(res, int32(v))
+	}
+
+	return res, nil
+}
+
+func writeMessage(w io.Writer, msg *kafkalib.Message, pretty bool) error {
+	out := outputMessage{
+		Topic:     msg.Topic,
+		Offset:    msg.Offset,
+		Timestamp: msg.Timestamp,
+		Value:     string(msg.Payload),
+	}
+
+	if msg.Partition != nil {
+		out.Partition = *msg.Partition
+	}
+
+	if msg.Key != nil {
+		key := string(msg.Key)
+		out.Key = &key
+	}
+
+	if msg.Payload == nil {
+		out.Value = nil
+	} else if json.Valid(msg.Payload) {
+		out.Value = json.RawMessage(msg.Payload)
+	}
+
+	if len(msg.Headers) > 0 {
+		out.Headers = make(map[string]string, len(msg.Headers))
+		for _, h := range msg.Headers {
+			out.Headers[h.Key] = string(h.Value)
+		}
+	}
+
+	encoder := json.NewEncoder(w)
+	if pretty {
+		encoder.SetIndent("", "  ")
+	}
+
+	if err := encoder.Encode(out); err != nil {
+		return fmt.Errorf("writing message: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/yvyrovyi-cinemo/utils/kafkalib"
+)
+
+func TestParseStart(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		from     string
+		expected kafkalib.StartPosition
+	}{
+		{from: "earliest", expected: kafkalib.StartOldest},
+		{from: "oldest", expected: kafkalib.StartOldest},
+		{from: "latest", expected: kafkalib.StartNewest},
+		{from: "newest", expected: kafkalib.StartNewest},
+		{from: "2024-04-30T10:00:00Z", expected: kafkalib.StartAt(time.Date(2024, 4, 30, 10, 0, 0, 0, time.UTC))},
+		{from: "-1h", expected: kafkalib.StartAt(now.Add(-time.Hour))},
+		{from: "-90m30s", expected: kafkalib.StartAt(now.Add(-90*time.Minute - 30*time.Second))},
+	}
+
+	for _, tt := range tests {
+		start, err := parseStart(tt.from, now)
+		require.NoError(t, err, tt.from)
+		require.Equal(t, tt.expected, start, tt.from)
+	}
+
+	for _, from := range []string{"", "yesterday", "1h", "0s", "2024-04-30"} {
+		_, err := parseStart(from, now)
+		require.ErrorIs(t, err, errUsage, from)
+	}
+}
+
+func TestParsePartitions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		s        string
+		expected []int32
+	}{
+		{s: "", expected: nil},
+		{s: "0", expected: []int32{0}},
+		{s: "0, 2,5", expected: []int32{0, 2, 5}},
+	}
+
+	for _, tt := range tests {
+		partitions, err := parsePartitions(tt.s)
+		require.NoError(t, err, tt.s)
+		require.Equal(t, tt.expected, partitions, tt.s)
+	}
+
+	for _, s := range []string{"a", "1,", "1;2"} {
+		_, err := parsePartitions(s)
+		require.ErrorIs(t, err, errUsage, s)
+	}
+}
+
+func TestWriteMessage(t *testing.T) {
+	t.Parallel()
+
+	partition := int32(0)
+	buf := &bytes.Buffer{}
+
+	require.NoError(t, writeMessage(buf, &kafkalib.Message{
+		Topic:     "t",
+		Key:       []byte("k"),
+		Payload:   []byte(`{"a":1}`),
+		Partition: &partition,
+		Offset:    7,
+		Timestamp: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+	}, false))
+
+	require.JSONEq(t,
+		`{"topic":"t","partition":0,"offset":7,"timestamp":"2024-05-01T00:00:00Z","key":"k","value":{"a":1}}`,
+		buf.String(),
+	)
+}
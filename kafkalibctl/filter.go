@@ -0,0 +1,298 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/yvyrovyi-cinemo/utils/kafkalib"
+)
+
+// filter is a parsed filter expression.
+//
+// The expression is a list of comparisons joined with && and ||, && binds tighter:
+//
+//	key == "user-1" && header.source != 'batch' || value.amount >= 100
+//
+// Fields are topic, key, partition, offset, value (the whole payload), header.<name>
+// and value.<path> where path is a dot separated path into a JSON payload (array items by index).
+// Operators are == != =~ !~ (regexp) contains > >= < <= (numeric).
+// Values are quoted strings or bare words; a missing field never matches.
+type filter struct {
+	// anyOf holds groups joined with ||, each group holds comparisons joined with &&.
+	anyOf [][]comparison
+}
+
+type comparison struct {
+	field string
+	op    string
+	value string
+	re    *regexp.Regexp
+	num   float64
+}
+
+var errFilter = errors.New("invalid filter")
+
+var operators = map[string]bool{
+	"==": true, "!=": true, "=~": true, "!~": true, "contains": true,
+	">": true, ">=": true, "<": true, "<=": true,
+}
+
+func parseFilter(expr string) (*filter, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &filter{}
+	var group []comparison
+
+	for len(tokens) > 0 {
+		if len(tokens) < 3 {
+			return nil, fmt.Errorf("%w: incomplete comparison %q", errFilter, strings.Join(tokens, " "))
+		}
+
+		cmp, err := newComparison(tokens[0], tokens[1], tokens[2])
+		if err != nil {
+			return nil, err
+		}
+		group = append(group, cmp)
+		tokens = tokens[3:]
+
+		if len(tokens) == 0 {
+			break
+		}
+
+		switch tokens[0] {
+		case "&&":
+		case "||":
+			res.anyOf = append(res.anyOf, group)
+			group = nil
+		default:
+			return nil, fmt.Errorf("%w: expected && or || instead of %q", errFilter, tokens[0])
+		}
+
+		tokens = tokens[1:]
+		if len(tokens) == 0 {
+			return nil, fmt.Errorf("%w: expression ends with an operator", errFilter)
+		}
+	}
+
+	if len(group) > 0 {
+		res.anyOf = append(res.anyOf, group)
+	}
+
+	return res, nil
+}
+
+func newComparison(field, op, value string) (comparison, error) {
+	if !operators[op] {
+		return comparison{}, fmt.Errorf("%w: unknown operator %q", errFilter, op)
+	}
+
+	if !isKnownField(field) {
+		return comparison{}, fmt.Errorf("%w: unknown field %q", errFilter, field)
+	}
+
+	cmp := comparison{field: field, op: op, value: value}
+
+	switch op {
+	case "=~", "!~":
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return comparison{}, fmt.Errorf("%w: %v", errFilter, err)
+		}
+		cmp.re = re
+
+	case ">", ">=", "<", "<=":
+		num, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return comparison{}, fmt.Errorf("%w: %q is not a number", errFilter, value)
+		}
+		cmp.num = num
+	}
+
+	return cmp, nil
+}
+
+func isKnownField(field string) bool {
+	switch field {
+	case "topic", "key", "partition", "offset", "value":
+		return true
+	}
+
+	return strings.HasPrefix(field, "header.") || strings.HasPrefix(field, "value.")
+}
+
+// match reports whether the message satisfies the filter. An empty filter matches everything.
+func (f *filter) match(msg *kafkalib.Message) bool {
+	if f == nil || len(f.anyOf) == 0 {
+		return true
+	}
+
+	for _, group := range f.anyOf {
+		if matchAll(group, msg) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matchAll(group []comparison, msg *kafkalib.Message) bool {
+	for _, cmp := range group {
+		if !cmp.match(msg) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (c comparison) match(msg *kafkalib.Message) bool {
+	actual, ok := fieldValue(c.field, msg)
+	if !ok {
+		return false
+	}
+
+	switch c.op {
+	case "==":
+		return actual == c.value
+	case "!=":
+		return actual != c.value
+	case "=~":
+		return c.re.MatchString(actual)
+	case "!~":
+		return !c.re.MatchString(actual)
+	case "contains":
+		return strings.Contains(actual, c.value)
+	}
+
+	num, err := strconv.ParseFloat(actual, 64)
+	if err != nil {
+		return false
+	}
+
+	switch c.op {
+	case ">":
+		return num > c.num
+	case ">=":
+		return num >= c.num
+	case "<":
+		return num < c.num
+	default:
+		return num <= c.num
+	}
+}
+
+func fieldValue(field string, msg *kafkalib.Message) (string, bool) {
+	switch field {
+	case "topic":
+		return msg.Topic, true
+	case "key":
+		return string(msg.Key), true
+	case "value":
+		return string(msg.Payload), true
+	case "offset":
+		return strconv.FormatInt(msg.Offset, 10), true
+	case "partition":
+		if msg.Partition == nil {
+			return "", false
+		}
+		return strconv.Itoa(int(*msg.Partition)), true
+	}
+
+	if name, ok := strings.CutPrefix(field, "header."); ok {
+		v, ok := msg.Header(name)
+		return string(v), ok
+	}
+
+	path, _ := strings.CutPrefix(field, "value.")
+	return jsonPathValue(msg.Payload, strings.Split(path, "."))
+}
+
+// jsonPathValue returns the value at the path as a string: strings unquoted, other values as JSON.
+func jsonPathValue(payload []byte, path []string) (string, bool) {
+	var node any
+	if err := json.Unmarshal(payload, &node); err != nil {
+		return "", false
+	}
+
+	for _, p := range path {
+		switch v := node.(type) {
+		case map[string]any:
+			next, ok := v[p]
+			if !ok {
+				return "", false
+			}
+			node = next
+		case []any:
+			idx, err := strconv.Atoi(p)
+			if err != nil || idx < 0 || idx >= len(v) {
+				return "", false
+			}
+			node = v[idx]
+		default:
+			return "", false
+		}
+	}
+
+	if s, ok := node.(string); ok {
+		return s, true
+	}
+
+	buf, err := json.Marshal(node)
+	if err != nil {
+		return "", false
+	}
+
+	return string(buf), true
+}
+
+// tokenize splits the expression into words, operators and quoted strings.
+func tokenize(expr string) ([]string, error) {
+	var tokens []string
+	runes := []rune(expr)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case r == '"' || r == '\'':
+			end := i + 1
+			for end < len(runes) && runes[end] != r {
+				end++
+			}
+			if end == len(runes) {
+				return nil, fmt.Errorf("%w: unterminated string", errFilter)
+			}
+			tokens = append(tokens, string(runes[i+1:end]))
+			i = end + 1
+
+		case strings.ContainsRune("=!<>&|~", r):
+			end := i
+			for end < len(runes) && strings.ContainsRune("=!<>&|~", runes[end]) {
+				end++
+			}
+			tokens = append(tokens, string(runes[i:end]))
+			i = end
+
+		default:
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) && !strings.ContainsRune("=!<>&|~\"'", runes[end]) {
+				end++
+			}
+			tokens = append(tokens, string(runes[i:end]))
+			i = end
+		}
+	}
+
+	return tokens, nil
+}
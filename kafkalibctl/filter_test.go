@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/yvyrovyi-cinemo/utils/kafkalib"
+)
+
+func TestFilter(t *testing.T) {
+	t.Parallel()
+
+	partition := int32(2)
+	msg := &kafkalib.Message{
+		Topic:     "orders",
+		Key:       []byte("user-1"),
+		Payload:   []byte(`{"amount": 150, "items": [{"sku": "a-1"}], "note": "first order"}`),
+		Headers:   []kafkalib.Header{{Key: "source", Value: []byte("web")}},
+		Partition: &partition,
+		Offset:    42,
+	}
+
+	tests := []struct {
+		expr  string
+		match bool
+	}{
+		{expr: "", match: true},
+		{expr: `key == "user-1"`, match: true},
+		{expr: `key != user-1`, match: false},
+		{expr: `header.source == 'web' && partition == 2`, match: true},
+		{expr: `header.missing == ""`, match: false},
+		{expr: `value.amount >= 100 && value.items.0.sku =~ "^a-"`, match: true},
+		{expr: `value.amount < 100 || offset == 42`, match: true},
+		{expr: `value.amount < 100 || offset == 41`, match: false},
+		{expr: `value.note contains "first"`, match: true},
+		{expr: `topic !~ "^pay"`, match: true},
+	}
+
+	for _, tt := range tests {
+		f, err := parseFilter(tt.expr)
+		require.NoError(t, err, tt.expr)
+		require.Equal(t, tt.match, f.match(msg), tt.expr)
+	}
+}
+
+func TestFilter_Invalid(t *testing.T) {
+	t.Parallel()
+
+	for _, expr := range []string{
+		`key ==`,
+		`key == a &&`,
+		`key = a`,
+		`size > 1`,
+		`value.amount > ten`,
+		`key =~ "("`,
+		`key == "a`,
+		`key == a key == b`,
+	} {
+		_, err := parseFilter(expr)
+		require.ErrorIs(t, err, errFilter, expr)
+	}
+}
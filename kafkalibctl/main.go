@@ -0,0 +1,116 @@
+// Command kafkalibctl produces, consumes and inspects Kafka topics and groups using kafkalib.Config.
+//
+// Usage:
+//
+//	kafkalibctl [-config file] [-brokers list] <command> [flags]
+//
+// Commands:
+//
+//	produce          produce messages from stdin or a file
+//	consume          consume messages of a topic
+//	groups list      list consumer groups
+//	groups describe  describe a consumer group
+//	groups lag       show a consumer group lag
+//	topics list      list topics
+//	topics describe  describe a topic
+//
+// The connection is configured by KAFKA_ prefixed environment variables (e.g. KAFKA_BROKERS),
+// by the "kafka" section of the config file or by the -kafka.* flags, e.g. -kafka.client_id.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/yvyrovyi-cinemo/utils/config"
+	"github.com/yvyrovyi-cinemo/utils/kafkalib"
+)
+
+type Config struct {
+	Kafka kafkalib.Config `envPrefix:"KAFKA_" json:"kafka" yaml:"kafka"`
+}
+
+var errUsage = errors.New("invalid usage")
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "ERR:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdin io.Reader, stdout io.Writer) error {
+	flags := flag.NewFlagSet("kafkalibctl", flag.ContinueOnError)
+	configPath := flags.String("config", "", "path to JSON or YAML config file")
+	debug := flags.Bool("debug", false, "log debug messages")
+
+	var cfg Config
+	configFlags := config.NewFlags(flags, &cfg)
+	// the brokers are set as a config flag, so they are validated with the config
+	flags.Func("brokers", "comma separated brokers, overrides the config", func(brokers string) error {
+		return flags.Set("kafka.brokers", brokers)
+	})
+
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+
+	err := config.Load(&cfg, config.WithFiles(strings.Split(*configPath, ",")...), config.WithFlags(configFlags))
+	if err != nil {
+		return fmt.Errorf("parsing config: %w", err)
+	}
+
+	if cfg.Kafka.ClientID == "" {
+		cfg.Kafka.ClientID = "kafkalibctl"
+	}
+
+	level := slog.LevelWarn
+	if *debug {
+		level = slog.LevelDebug
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	cmd := command{
+		config: cfg.Kafka,
+		logger: logger,
+		stdin:  stdin,
+		stdout: stdout,
+	}
+
+	rest := flags.Args()
+	if len(rest) == 0 {
+		flags.Usage()
+		return errUsage
+	}
+
+	switch rest[0] {
+	case "produce":
+		return cmd.produce(ctx, rest[1:])
+	case "consume":
+		return cmd.consume(ctx, rest[1:])
+	case "groups":
+		return cmd.groups(rest[1:])
+	case "topics":
+		return cmd.topics(rest[1:])
+	default:
+		return fmt.Errorf("%w: unknown command %q", errUsage, rest[0])
+	}
+}
+
+type command struct {
+	config kafkalib.Config
+	logger *slog.Logger
+	stdin  io.Reader
+	stdout io.Writer
+}
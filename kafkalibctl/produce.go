@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/yvyrovyi-cinemo/utils/kafkalib"
+)
+
+const (
+	formatLines = "lines"
+	formatJSONL = "jsonl"
+)
+
+type (
+	// jsonlMessage is a line of the JSONL input.
+	// Value is produced as is unless it is a JSON string, which is produced unquoted.
+	jsonlMessage struct {
+		Key       *string           `json:"key"`
+		Value     json.RawMessage   `json:"value"`
+		Headers   map[string]string `json:"headers"`
+		Partition *int32            `json:"partition"`
+	}
+
+	headerFlags []kafkalib.Header
+)
+
+func (h *headerFlags) String() string {
+	return fmt.Sprint(*h)
+}
+
+func (h *headerFlags) Set(v string) error {
+	key, value, ok := strings.Cut(v, "=")
+	if !ok {
+		return fmt.Errorf("header %q must be key=value", v)
+	}
+	*h = append(*h, kafkalib.Header{Key: key, Value: []byte(value)})
+	return nil
+}
+
+func (c *command) produce(ctx context.Context, args []string) error {
+	var headers headerFlags
+
+	flags := flag.NewFlagSet("produce", flag.ContinueOnError)
+	topic := flags.String("topic", "", "topic to produce to")
+	filePath := flags.String("file", "", "read messages from the file instead of stdin")
+	format := flags.String("format", formatLines, "input format: lines (a message per line) or jsonl")
+	keySeparator := flags.String("key-separator", "", "lines format: split each line into key and value by the separator")
+	flags.Var(&headers, "header", "lines format: key=value header added to every message, can be repeated")
+	verbose := flags.Bool("verbose", false, "print partition and offset of every produced message")
+
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+
+	if *topic == "" {
+		return fmt.Errorf("%w: -topic is required", errUsage)
+	}
+
+	input := c.stdin
+	if *filePath != "" {
+		f, err := os.Open(*filePath)
+		if err != nil {
+			return fmt.Errorf("opening input file: %w", err)
+		}
+		defer f.Close()
+		input = f
+	}
+
+	producer, err := kafkalib.NewProducer(kafkalib.ProducerConfig{Config: c.config, ManualPartitioning: true}, c.logger)
+	if err != nil {
+		return fmt.Errorf("creating producer: %w", err)
+	}
+	defer func() { _ = producer.Close() }()
+
+	return readMessages(input, *format, *keySeparator, headers, func(msg *kafkalib.Message) error {
+		msg.Topic = *topic
+
+		partition, offset, err := producer.ProduceSync(ctx, msg)
+		if err != nil {
+			return err
+		}
+
+		if *verbose {
+			fmt.Fprintf(c.stdout, "produced to %s/%d at offset %d\n", *topic, partition, offset)
+		}
+		return nil
+	})
+}
+
+// readMessages parses the input and calls fn for every message.
+func readMessages(
+	input io.Reader,
+	format string,
+	keySeparator string,
+	headers []kafkalib.Header,
+	fn func(*kafkalib.Message) error,
+) error {
+	scanner := bufio.NewScanner(input)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		var msg *kafkalib.Message
+		var err error
+
+		switch format {
+		case formatLines:
+			msg = parseLine(line, keySeparator, headers)
+		case formatJSONL:
+			msg, err = parseJSONLine(line)
+		default:
+			return fmt.Errorf("%w: unknown format %q", errUsage, format)
+		}
+
+		if err != nil {
+			return fmt.Errorf("line %d: %w", lineNum, err)
+		}
+
+		if err := fn(msg); err != nil {
+			return fmt.Errorf("line %d: %w", lineNum, err)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("reading input: %w", err)
+	}
+
+	return nil
+}
+
+func parseLine(line []byte, keySeparator string, headers []kafkalib.Header) *kafkalib.Message {
+	msg := &kafkalib.Message{
+		Payload: append([]byte{}, line...),
+		Headers: headers,
+	}
+
+	if keySeparator != "" {
+		if key, value, ok := bytes.Cut(line, []byte(keySeparator)); ok {
+			msg.Key = append([]byte{}, key...)
+			msg.Payload = append([]byte{}, value...)
+		}
+	}
+
+	return msg
+}
+
+func parseJSONLine(line []byte) (*kafkalib.Message, error) {
+	var in jsonlMessage
+	if err := json.Unmarshal(line, &in); err != nil {
+		return nil, fmt.Errorf("parsing JSON: %w", err)
+	}
+
+	msg := &kafkalib.Message{
+		Partition: in.Partition,
+	}
+
+	// a missing or null value produces a tombstone
+	var str string
+	switch {
+	case len(in.Value) == 0 || string(in.Value) == "null":
+	case json.Unmarshal(in.Value, &str) == nil:
+		msg.Payload = []byte(str)
+	default:
+		msg.Payload = []byte(in.Value)
+	}
+
+	if in.Key != nil {
+		msg.Key = []byte(*in.Key)
+	}
+
+	for k, v := range in.Headers {
+		msg.Headers = append(msg.Headers, kafkalib.Header{Key: k, Value: []byte(v)})
+	}
+	sort.Slice(msg.Headers, func(i, j int) bool { return msg.Headers[i].Key < msg.Headers[j].Key })
+
+	return msg, nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/yvyrovyi-cinemo/utils/kafkalib"
+)
+
+func TestReadMessages(t *testing.T) {
+	t.Parallel()
+
+	partition := int32(1)
+	headers := []kafkalib.Header{{Key: "h", Value: []byte("v")}}
+
+	tests := []struct {
+		name         string
+		input        string
+		format       string
+		keySeparator string
+		headers      []kafkalib.Header
+		expected     []*kafkalib.Message
+	}{
+		{
+			name:     "lines",
+			input:    "a\n\nb c\n",
+			format:   formatLines,
+			expected: []*kafkalib.Message{{Payload: []byte("a")}, {Payload: []byte("b c")}},
+		},
+		{
+			name:         "lines with key separator",
+			input:        "a:1\nb:2:3\nno-key\n",
+			format:       formatLines,
+			keySeparator: ":",
+			expected: []*kafkalib.Message{
+				{Key: []byte("a"), Payload: []byte("1")},
+				{Key: []byte("b"), Payload: []byte("2:3")},
+				{Payload: []byte("no-key")},
+			},
+		},
+		{
+			name:     "lines with headers",
+			input:    "a\n",
+			format:   formatLines,
+			headers:  headers,
+			expected: []*kafkalib.Message{{Payload: []byte("a"), Headers: headers}},
+		},
+		{
+			name:   "jsonl",
+			input:  `{"key": "k1", "value": {"a": 1}, "headers": {"h": "v"}, "partition": 1}` + "\n",
+			format: formatJSONL,
+			expected: []*kafkalib.Message{
+				{Key: []byte("k1"), Payload: []byte(`{"a": 1}`), Headers: headers, Partition: &partition},
+			},
+		},
+		{
+			name:     "jsonl string value",
+			input:    `{"key": "k2", "value": "plain"}` + "\n",
+			format:   formatJSONL,
+			expected: []*kafkalib.Message{{Key: []byte("k2"), Payload: []byte("plain")}},
+		},
+		{
+			name:     "jsonl tombstones",
+			input:    `{"key": "k3", "value": null}` + "\n" + `{"key": "k4"}` + "\n",
+			format:   formatJSONL,
+			expected: []*kafkalib.Message{{Key: []byte("k3")}, {Key: []byte("k4")}},
+		},
+		{
+			name:         "jsonl ignores the lines options",
+			input:        `{"value": "a:1"}` + "\n",
+			format:       formatJSONL,
+			keySeparator: ":",
+			headers:      headers,
+			expected:     []*kafkalib.Message{{Payload: []byte("a:1")}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var res []*kafkalib.Message
+			err := readMessages(strings.NewReader(tt.input), tt.format, tt.keySeparator, tt.headers,
+				func(msg *kafkalib.Message) error {
+					res = append(res, msg)
+					return nil
+				})
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, res)
+		})
+	}
+}
+
+func TestReadMessages_Invalid(t *testing.T) {
+	t.Parallel()
+
+	fn := func(*kafkalib.Message) error { return nil }
+
+	err := readMessages(strings.NewReader("a\n"), "csv", "", nil, fn)
+	require.ErrorIs(t, err, errUsage)
+
+	err = readMessages(strings.NewReader(`{"value": 1}`+"\n{\n"), formatJSONL, "", nil, fn)
+	require.ErrorContains(t, err, "line 2: parsing JSON")
+}
+
+func TestHeaderFlags(t *testing.T) {
+	t.Parallel()
+
+	var headers headerFlags
+	require.NoError(t, headers.Set("source=web"))
+	require.NoError(t, headers.Set("query=a=b"))
+	require.Equal(t, headerFlags{
+		{Key: "source", Value: []byte("web")},
+		{Key: "query", Value: []byte("a=b")},
+	}, headers)
+
+	require.Error(t, headers.Set("source"))
+}
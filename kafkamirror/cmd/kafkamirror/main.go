@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/yvyrovyi-cinemo/utils/config"
+	"github.com/yvyrovyi-cinemo/utils/infraserver"
+	"github.com/yvyrovyi-cinemo/utils/kafkalib"
+	"github.com/yvyrovyi-cinemo/utils/kafkamirror"
+)
+
+type Config struct {
+	Mirror      kafkamirror.Config `envPrefix:"MIRROR_" json:"mirror" yaml:"mirror"`
+	InfraServer infraserver.Config `envPrefix:"INFRA_SERVER_" json:"infra_server" yaml:"infra_server"`
+}
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Println("ERR:", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	var cfg Config
+
+	configPath := flag.String("config", "", "comma-separated paths to JSON or YAML config files, later ones override earlier ones")
+	flags := config.NewFlags(flag.CommandLine, &cfg)
+	flag.Parse()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+
+	var sources config.Sources
+	err := config.Load(&cfg,
+		config.WithFiles(strings.Split(*configPath, ",")...), config.WithFlags(flags), config.WithSources(&sources))
+	if err != nil {
+		return fmt.Errorf("parsing config: %w", err)
+	}
+	cfg.Mirror = cfg.Mirror.WithDefaults()
+
+	topics, err := sourceTopics(cfg.Mirror, logger)
+	if err != nil {
+		return err
+	}
+	cfg.Mirror.Source.Topics = topics
+
+	// the producer must honour source partitions only if they are preserved
+	cfg.Mirror.Target.ManualPartitioning = cfg.Mirror.PreservePartitions
+
+	consumer, err := kafkalib.NewConsumer(cfg.Mirror.Source, logger)
+	if err != nil {
+		return fmt.Errorf("creating source consumer: %w", err)
+	}
+
+	producer, err := kafkalib.NewProducer(cfg.Mirror.Target, logger)
+	if err != nil {
+		return fmt.Errorf("creating target producer: %w", err)
+	}
+	defer func() {
+		if err := producer.Close(); err != nil {
+			logger.Error("closing target producer", "error", err)
+		}
+	}()
+
+	mirror, err := kafkamirror.New(cfg.Mirror, consumer, producer, logger)
+	if err != nil {
+		return fmt.Errorf("creating mirror: %w", err)
+	}
+
+	server := infraserver.New(cfg.InfraServer)
+	server.Handle(infraserver.DefaultConfigEndpoint, config.Handler(&cfg, sources))
+
+	return runAll(ctx, cancel, mirror, server)
+}
+
+// sourceTopics lists the source cluster topics matching the include/exclude rules.
+func sourceTopics(cfg kafkamirror.Config, logger *slog.Logger) ([]string, error) {
+	rules, err := kafkamirror.NewTopicRules(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("topic rules: %w", err)
+	}
+
+	admin, err := kafkalib.NewAdmin(cfg.Source.Config, logger)
+	if err != nil {
+		return nil, fmt.Errorf("creating source admin: %w", err)
+	}
+	defer func() { _ = admin.Close() }()
+
+	topicInfos, err := admin.ListTopics()
+	if err != nil {
+		return nil, fmt.Errorf("listing source topics: %w", err)
+	}
+
+	names := make([]string, 0, len(topicInfos))
+	for _, t := range topicInfos {
+		names = append(names, t.Name)
+	}
+
+	topics := rules.Select(names)
+	if len(topics) == 0 {
+		return nil, errors.New("no source topics match the include/exclude rules")
+	}
+
+	return topics, nil
+}
+
+func runAll(ctx context.Context, cancel func(), mirror *kafkamirror.Mirror, server *infraserver.Server) error {
+	chanServerErr := make(chan error, 1)
+	go func() {
+		chanServerErr <- server.Run(ctx, nil, nil)
+		cancel()
+	}()
+
+	err := mirror.Run(ctx)
+	cancel()
+
+	return errors.Join(err, <-chanServerErr)
+}
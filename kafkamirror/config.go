@@ -0,0 +1,56 @@
+package kafkamirror
+
+import "github.com/yvyrovyi-cinemo/utils/kafkalib"
+
+const DefaultOffsetSyncInterval = 100
+
+type (
+	Config struct {
+		Source kafkalib.ConsumerConfig `envPrefix:"SOURCE_" json:"source" yaml:"source"`
+		Target kafkalib.ProducerConfig `envPrefix:"TARGET_" json:"target" yaml:"target"`
+
+		// Include and Exclude are regular expressions matched against the whole source topic name.
+		// A topic is mirrored when it matches any Include (all topics when Include is empty)
+		// and does not match any Exclude.
+		Include []string `env:"INCLUDE" envSeparator:"," json:"include" yaml:"include"`
+		Exclude []string `env:"EXCLUDE" envSeparator:"," json:"exclude" yaml:"exclude"`
+
+		// Rename rules are applied in order, the first matching rule wins.
+		// Topics not matching any rule keep their name.
+		Rename []RenameRule `json:"rename" yaml:"rename"`
+
+		// PreservePartitions writes every message to the same partition number it has in the source.
+		// The target topics must have at least as many partitions as the source ones.
+		PreservePartitions bool `env:"PRESERVE_PARTITIONS" envDefault:"true" json:"preserve_partitions" yaml:"preserve_partitions" default:"true"`
+
+		// OffsetSyncTopic is a target topic receiving source-to-target offset checkpoints.
+		// Checkpoints are kept in memory only when it is empty.
+		OffsetSyncTopic string `env:"OFFSET_SYNC_TOPIC" json:"offset_sync_topic" yaml:"offset_sync_topic"`
+
+		// OffsetSyncInterval is the number of mirrored messages per partition between two checkpoints.
+		OffsetSyncInterval int `env:"OFFSET_SYNC_INTERVAL" envDefault:"100" json:"offset_sync_interval" yaml:"offset_sync_interval" default:"100"`
+	}
+
+	// RenameRule renames topics matching Pattern to Replacement.
+	// Replacement may reference Pattern groups, e.g. `eu.$1` for the pattern `(.*)`.
+	RenameRule struct {
+		Pattern     string `json:"pattern" yaml:"pattern"`
+		Replacement string `json:"replacement" yaml:"replacement"`
+	}
+)
+
+func (c Config) WithDefaults() Config {
+	if c.OffsetSyncInterval <= 0 {
+		c.OffsetSyncInterval = DefaultOffsetSyncInterval
+	}
+
+	c.Source = c.Source.WithDefaults()
+
+	return c
+}
+
+// Validate implements config.Validator, the Kafka clients are checked by the tags of their configs.
+func (c Config) Validate() error {
+	_, err := NewTopicRules(c)
+	return err
+}
@@ -0,0 +1,99 @@
+package kafkamirror
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/yvyrovyi-cinemo/utils/metrics"
+)
+
+type mirrorMetrics struct {
+	messagesCounterVec *prometheus.CounterVec
+	bytesCounterVec    *prometheus.CounterVec
+	errorsCounterVec   *prometheus.CounterVec
+	latencyGaugeVec    *prometheus.GaugeVec
+	sourceLagGaugeVec  *prometheus.GaugeVec
+}
+
+func initMetrics() (*mirrorMetrics, error) {
+	labels := []string{"source_topic", "target_topic"}
+
+	messagesCounterVec, err := metrics.Register(prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kafkamirror_messages_total",
+			Help: "a number of mirrored messages",
+		},
+		labels,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to register kafkamirror metrics: %w", err)
+	}
+
+	bytesCounterVec, err := metrics.Register(prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kafkamirror_bytes_total",
+			Help: "a number of mirrored key and payload bytes",
+		},
+		labels,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to register kafkamirror metrics: %w", err)
+	}
+
+	errorsCounterVec, err := metrics.Register(prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kafkamirror_errors_total",
+			Help: "a number of messages failed to be mirrored",
+		},
+		labels,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to register kafkamirror metrics: %w", err)
+	}
+
+	latencyGaugeVec, err := metrics.Register(prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "kafkamirror_replication_latency_seconds",
+			Help: "a time between the source message timestamp and its acknowledgement by the target",
+		},
+		labels,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to register kafkamirror metrics: %w", err)
+	}
+
+	sourceLagGaugeVec, err := metrics.Register(prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "kafkamirror_source_lag_messages",
+			Help: "a number of messages of the source partition not mirrored yet",
+		},
+		[]string{"source_topic", "partition"},
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to register kafkamirror metrics: %w", err)
+	}
+
+	return &mirrorMetrics{
+		messagesCounterVec: messagesCounterVec,
+		bytesCounterVec:    bytesCounterVec,
+		errorsCounterVec:   errorsCounterVec,
+		latencyGaugeVec:    latencyGaugeVec,
+		sourceLagGaugeVec:  sourceLagGaugeVec,
+	}, nil
+}
+
+func (m *mirrorMetrics) mirrored(sourceTopic, targetTopic string, bytes int, latencySec float64) {
+	m.messagesCounterVec.WithLabelValues(sourceTopic, targetTopic).Inc()
+	m.bytesCounterVec.WithLabelValues(sourceTopic, targetTopic).Add(float64(bytes))
+	m.latencyGaugeVec.WithLabelValues(sourceTopic, targetTopic).Set(latencySec)
+}
+
+func (m *mirrorMetrics) failed(sourceTopic, targetTopic string) {
+	m.errorsCounterVec.WithLabelValues(sourceTopic, targetTopic).Inc()
+}
+
+func (m *mirrorMetrics) setSourceLag(sourceTopic string, partition int32, lag int64) {
+	m.sourceLagGaugeVec.WithLabelValues(sourceTopic, strconv.Itoa(int(partition))).Set(float64(lag))
+}
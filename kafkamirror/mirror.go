@@ -0,0 +1,173 @@
+// Package kafkamirror copies topics between Kafka clusters preserving keys, headers and partitions.
+package kafkamirror
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/yvyrovyi-cinemo/utils/kafkalib"
+)
+
+const LogsLabelComponent = "component"
+
+type (
+	// Source is satisfied by kafkalib.Consumer.
+	Source interface {
+		Run(ctx context.Context, handler kafkalib.MessageHandler) error
+	}
+
+	// Target is satisfied by kafkalib.Producer.
+	Target interface {
+		ProduceSync(ctx context.Context, msg *kafkalib.Message) (int32, int64, error)
+	}
+
+	Mirror struct {
+		Config
+		source  Source
+		target  Target
+		rules   *TopicRules
+		offsets *OffsetTranslator
+		logger  *slog.Logger
+		metrics *mirrorMetrics
+
+		// mirrored counts messages per source partition for the offset sync cadence.
+		// The handler is called concurrently for different partitions.
+		mirrored    map[sourcePartition]int
+		mirroredMux sync.Mutex
+	}
+)
+
+func New(config Config, source Source, target Target, logger *slog.Logger) (*Mirror, error) {
+	config = config.WithDefaults()
+
+	rules, err := NewTopicRules(config)
+	if err != nil {
+		return nil, fmt.Errorf("topic rules: %w", err)
+	}
+
+	logger = logger.With(LogsLabelComponent, "kafkamirror")
+
+	metrics, err := initMetrics()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Mirror{
+		Config:   config,
+		source:   source,
+		target:   target,
+		rules:    rules,
+		offsets:  NewOffsetTranslator(),
+		logger:   logger,
+		metrics:  metrics,
+		mirrored: make(map[sourcePartition]int),
+	}, nil
+}
+
+// Offsets returns the translator fed by the running mirror.
+func (m *Mirror) Offsets() *OffsetTranslator {
+	return m.offsets
+}
+
+// Run mirrors messages until ctx is done or mirroring fails.
+func (m *Mirror) Run(ctx context.Context) error {
+	m.logger.Info("kafka mirror is up and running",
+		"source_topics", m.Source.Topics,
+		"preserve_partitions", m.PreservePartitions,
+	)
+
+	return m.source.Run(ctx, m.handle)
+}
+
+func (m *Mirror) handle(ctx context.Context, msg *kafkalib.Message) error {
+	if !m.rules.Match(msg.Topic) {
+		return nil
+	}
+
+	targetTopic := m.rules.TargetTopic(msg.Topic)
+
+	out := &kafkalib.Message{
+		Topic:     targetTopic,
+		Key:       msg.Key,
+		Payload:   msg.Payload,
+		Headers:   msg.Headers,
+		Timestamp: msg.Timestamp,
+	}
+	if m.PreservePartitions {
+		out.Partition = msg.Partition
+	}
+
+	partition, offset, err := m.target.ProduceSync(ctx, out)
+	if err != nil {
+		m.metrics.failed(msg.Topic, targetTopic)
+		return fmt.Errorf("mirroring %s to %s: %w", msg.Topic, targetTopic, err)
+	}
+
+	m.metrics.mirrored(msg.Topic, targetTopic, len(msg.Key)+len(msg.Payload), latency(msg.Timestamp))
+
+	if msg.Partition == nil {
+		return nil
+	}
+
+	if msg.HighWaterMark > 0 {
+		m.metrics.setSourceLag(msg.Topic, *msg.Partition, msg.HighWaterMark-msg.Offset-1)
+	}
+
+	return m.checkpoint(ctx, OffsetSync{
+		SourceTopic:     msg.Topic,
+		SourcePartition: *msg.Partition,
+		SourceOffset:    msg.Offset,
+		TargetTopic:     targetTopic,
+		TargetPartition: partition,
+		TargetOffset:    offset,
+	})
+}
+
+// checkpoint records every OffsetSyncInterval-th mirrored message of a partition
+// and publishes it to OffsetSyncTopic if configured.
+func (m *Mirror) checkpoint(ctx context.Context, offsetSync OffsetSync) error {
+	key := sourcePartition{topic: offsetSync.SourceTopic, partition: offsetSync.SourcePartition}
+
+	m.mirroredMux.Lock()
+	cnt := m.mirrored[key]
+	m.mirrored[key] = cnt + 1
+	m.mirroredMux.Unlock()
+
+	if cnt%m.OffsetSyncInterval != 0 {
+		return nil
+	}
+
+	m.offsets.Record(offsetSync)
+
+	if m.OffsetSyncTopic == "" {
+		return nil
+	}
+
+	payload, err := json.Marshal(offsetSync)
+	if err != nil {
+		return fmt.Errorf("marshaling offset sync: %w", err)
+	}
+
+	_, _, err = m.target.ProduceSync(ctx, &kafkalib.Message{
+		Topic:   m.OffsetSyncTopic,
+		Key:     []byte(fmt.Sprintf("%s/%d", offsetSync.SourceTopic, offsetSync.SourcePartition)),
+		Payload: payload,
+	})
+	if err != nil {
+		return fmt.Errorf("producing offset sync: %w", err)
+	}
+
+	return nil
+}
+
+func latency(tm time.Time) float64 {
+	if tm.IsZero() {
+		return 0
+	}
+
+	return time.Since(tm).Seconds()
+}
@@ -0,0 +1,153 @@
+package kafkamirror
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yvyrovyi-cinemo/utils/config"
+	"github.com/yvyrovyi-cinemo/utils/kafkalib"
+	"github.com/yvyrovyi-cinemo/utils/kafkalib/kafkalibtest"
+)
+
+func TestTopicRules(t *testing.T) {
+	t.Parallel()
+
+	rules, err := NewTopicRules(Config{
+		Include: []string{`orders\..*`, "payments"},
+		Exclude: []string{`.*\.internal`},
+		Rename: []RenameRule{
+			{Pattern: `orders\.(.*)`, Replacement: "eu.orders.$1"},
+		},
+	})
+	require.NoError(t, err)
+
+	require.Equal(t,
+		[]string{"orders.created", "payments"},
+		rules.Select([]string{"orders.created", "orders.internal", "payments", "payments.v2", "users"}),
+	)
+
+	require.Equal(t, "eu.orders.created", rules.TargetTopic("orders.created"))
+	require.Equal(t, "payments", rules.TargetTopic("payments"))
+
+	_, err = NewTopicRules(Config{Include: []string{"("}})
+	require.Error(t, err)
+}
+
+func TestOffsetTranslator(t *testing.T) {
+	t.Parallel()
+
+	translator := NewOffsetTranslator()
+	translator.Record(OffsetSync{SourceTopic: "a", SourcePartition: 1, SourceOffset: 10, TargetTopic: "b", TargetPartition: 1, TargetOffset: 3})
+	translator.Record(OffsetSync{SourceTopic: "a", SourcePartition: 1, SourceOffset: 20, TargetTopic: "b", TargetPartition: 1, TargetOffset: 13})
+
+	_, ok := translator.Translate("a", 1, 10)
+	require.False(t, ok)
+
+	res, ok := translator.Translate("a", 1, 11)
+	require.True(t, ok)
+	require.Equal(t, int64(4), res.TargetOffset)
+
+	res, ok = translator.Translate("a", 1, 25)
+	require.True(t, ok)
+	require.Equal(t, "b", res.TargetTopic)
+	require.Equal(t, int64(14), res.TargetOffset)
+
+	_, ok = translator.Translate("a", 0, 25)
+	require.False(t, ok)
+}
+
+func TestMirror_Run(t *testing.T) {
+	t.Parallel()
+
+	source := kafkalibtest.NewCluster()
+	target := kafkalibtest.NewCluster()
+
+	require.NoError(t, source.CreateTopic("orders", 3))
+	require.NoError(t, target.CreateTopic("eu.orders", 3))
+	require.NoError(t, target.CreateTopic("offset-syncs", 1))
+
+	sourceProducer := source.NewProducer()
+	for i := int32(0); i < 6; i++ {
+		partition := i % 3
+		require.NoError(t, sourceProducer.Produce(&kafkalib.Message{
+			Topic:     "orders",
+			Key:       []byte{byte('a' + i)},
+			Payload:   []byte{byte(i)},
+			Headers:   []kafkalib.Header{{Key: "trace", Value: []byte{byte(i)}}},
+			Partition: &partition,
+		}))
+	}
+
+	cfg := Config{
+		Source: kafkalib.ConsumerConfig{
+			GroupID:       "mirror",
+			Topics:        []string{"orders"},
+			InitialOffset: kafkalib.InitialOffsetOldest,
+		},
+		Rename:             []RenameRule{{Pattern: "(.*)", Replacement: "eu.$1"}},
+		PreservePartitions: true,
+		OffsetSyncTopic:    "offset-syncs",
+		OffsetSyncInterval: 1,
+	}
+
+	mirror, err := New(cfg, source.NewConsumer(cfg.Source), target.NewProducer(), slog.Default())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	chanErr := make(chan error, 1)
+	go func() { chanErr <- mirror.Run(ctx) }()
+
+	require.Eventually(t, func() bool {
+		return len(target.Messages("eu.orders")) == 6
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-chanErr)
+
+	sourceMessages := source.Messages("orders")
+	for i, msg := range target.Messages("eu.orders") {
+		require.Equal(t, sourceMessages[i].Key, msg.Key)
+		require.Equal(t, sourceMessages[i].Payload, msg.Payload)
+		require.Equal(t, sourceMessages[i].Headers, msg.Headers)
+		require.Equal(t, *sourceMessages[i].Partition, *msg.Partition)
+	}
+
+	require.Len(t, target.Messages("offset-syncs"), 6)
+
+	for partition := int32(0); partition < 3; partition++ {
+		lag := mirror.metrics.sourceLagGaugeVec.WithLabelValues("orders", strconv.Itoa(int(partition)))
+		require.Zero(t, testutil.ToFloat64(lag))
+	}
+
+	res, ok := mirror.Offsets().Translate("orders", 2, 2)
+	require.True(t, ok)
+	require.Equal(t, "eu.orders", res.TargetTopic)
+	require.Equal(t, int32(2), res.TargetPartition)
+	require.Equal(t, int64(2), res.TargetOffset)
+}
+
+func TestConfig_Validate(t *testing.T) {
+	t.Parallel()
+
+	cfg := Config{Include: []string{"("}}
+	cfg.Source.Brokers = "localhost:9092"
+	cfg.Source.InitialOffset = "latest"
+
+	err := config.Validate(struct {
+		Mirror Config `yaml:"mirror"`
+	}{cfg})
+	require.ErrorContains(t, err, "invalid config: mirror.source.group_id: required; mirror.source.initial_offset: "+
+		`"latest" must be one of newest, oldest; mirror.target.brokers: required; mirror: include: `)
+
+	cfg.Include = nil
+	cfg.Source.InitialOffset = kafkalib.InitialOffsetOldest
+	cfg.Source.GroupID = "mirror"
+	cfg.Target.Brokers = "localhost:9093"
+	require.NoError(t, config.Validate(cfg))
+}
@@ -0,0 +1,79 @@
+package kafkamirror
+
+import (
+	"sort"
+	"sync"
+)
+
+// checkpointsPerPartition limits the translation history kept in memory for a source partition.
+const checkpointsPerPartition = 1024
+
+type (
+	// OffsetSync is a checkpoint pairing a mirrored source message with its position in the target.
+	OffsetSync struct {
+		SourceTopic     string `json:"source_topic"`
+		SourcePartition int32  `json:"source_partition"`
+		SourceOffset    int64  `json:"source_offset"`
+		TargetTopic     string `json:"target_topic"`
+		TargetPartition int32  `json:"target_partition"`
+		TargetOffset    int64  `json:"target_offset"`
+	}
+
+	// OffsetTranslator translates source consumer group offsets to target offsets
+	// using the recorded checkpoints.
+	OffsetTranslator struct {
+		checkpoints map[sourcePartition][]OffsetSync
+		mux         sync.RWMutex
+	}
+
+	sourcePartition struct {
+		topic     string
+		partition int32
+	}
+)
+
+func NewOffsetTranslator() *OffsetTranslator {
+	return &OffsetTranslator{
+		checkpoints: make(map[sourcePartition][]OffsetSync),
+	}
+}
+
+// Record adds a checkpoint. Checkpoints of a partition must be recorded in offset order.
+func (t *OffsetTranslator) Record(offsetSync OffsetSync) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	key := sourcePartition{topic: offsetSync.SourceTopic, partition: offsetSync.SourcePartition}
+
+	checkpoints := append(t.checkpoints[key], offsetSync)
+	if len(checkpoints) > checkpointsPerPartition {
+		checkpoints = checkpoints[len(checkpoints)-checkpointsPerPartition:]
+	}
+	t.checkpoints[key] = checkpoints
+}
+
+// Translate converts the committed source offset (the next offset to consume) to the target position
+// to continue consuming from. The result never skips messages but may repeat the ones mirrored
+// after the closest checkpoint. It reports false when no checkpoint precedes the offset.
+//
+// The translation is exact per partition only when partitions are preserved.
+func (t *OffsetTranslator) Translate(topic string, partition int32, committedOffset int64) (OffsetSync, bool) {
+	t.mux.RLock()
+	defer t.mux.RUnlock()
+
+	checkpoints := t.checkpoints[sourcePartition{topic: topic, partition: partition}]
+
+	// index of the first checkpoint which was not consumed yet
+	idx := sort.Search(len(checkpoints), func(i int) bool {
+		return checkpoints[i].SourceOffset >= committedOffset
+	})
+	if idx == 0 {
+		return OffsetSync{}, false
+	}
+
+	res := checkpoints[idx-1]
+	res.SourceOffset = committedOffset
+	res.TargetOffset++
+
+	return res, true
+}
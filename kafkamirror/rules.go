@@ -0,0 +1,114 @@
+package kafkamirror
+
+import (
+	"fmt"
+	"regexp"
+)
+
+type (
+	// TopicRules selects source topics and maps them to target topic names.
+	TopicRules struct {
+		include []*regexp.Regexp
+		exclude []*regexp.Regexp
+		rename  []renameRule
+	}
+
+	renameRule struct {
+		pattern     *regexp.Regexp
+		replacement string
+	}
+)
+
+func NewTopicRules(config Config) (*TopicRules, error) {
+	include, err := compileAll(config.Include)
+	if err != nil {
+		return nil, fmt.Errorf("include: %w", err)
+	}
+
+	exclude, err := compileAll(config.Exclude)
+	if err != nil {
+		return nil, fmt.Errorf("exclude: %w", err)
+	}
+
+	rename := make([]renameRule, 0, len(config.Rename))
+	for _, r := range config.Rename {
+		re, err := compile(r.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("rename: %w", err)
+		}
+		rename = append(rename, renameRule{pattern: re, replacement: r.Replacement})
+	}
+
+	return &TopicRules{
+		include: include,
+		exclude: exclude,
+		rename:  rename,
+	}, nil
+}
+
+// Match reports whether the source topic has to be mirrored.
+func (r *TopicRules) Match(topic string) bool {
+	for _, re := range r.exclude {
+		if re.MatchString(topic) {
+			return false
+		}
+	}
+
+	if len(r.include) == 0 {
+		return true
+	}
+
+	for _, re := range r.include {
+		if re.MatchString(topic) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Select returns the topics to be mirrored keeping their order.
+func (r *TopicRules) Select(topics []string) []string {
+	var res []string
+	for _, topic := range topics {
+		if r.Match(topic) {
+			res = append(res, topic)
+		}
+	}
+
+	return res
+}
+
+// TargetTopic returns the target name of the source topic.
+func (r *TopicRules) TargetTopic(topic string) string {
+	for _, rule := range r.rename {
+		if match := rule.pattern.FindStringSubmatchIndex(topic); match != nil {
+			return string(rule.pattern.ExpandString(nil, rule.replacement, topic, match))
+		}
+	}
+
+	return topic
+}
+
+func compileAll(patterns []string) ([]*regexp.Regexp, error) {
+	res := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		re, err := compile(p)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, re)
+	}
+
+	return res, nil
+}
+
+// compile anchors the pattern so that it has to match the whole topic name.
+func compile(pattern string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile("^(?:" + pattern + ")$")
+	if err != nil {
+		return nil, fmt.Errorf("compiling %q: %w", pattern, err)
+	}
+
+	return re, nil
+}
@@ -0,0 +1,25 @@
+// Package metrics holds the Prometheus helpers shared by the utils modules.
+package metrics
+
+import (
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Register registers the collector in the default registry or returns the already registered one
+// of the same type, so that several clients, consumers or producers can live in one process.
+func Register[T prometheus.Collector](collector T) (T, error) {
+	if err := prometheus.Register(collector); err != nil {
+		var errRegistered prometheus.AlreadyRegisteredError
+		if errors.As(err, &errRegistered) {
+			if existing, ok := errRegistered.ExistingCollector.(T); ok {
+				return existing, nil
+			}
+		}
+
+		return collector, err
+	}
+
+	return collector, nil
+}
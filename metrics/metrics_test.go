@@ -0,0 +1,24 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegister(t *testing.T) {
+	t.Parallel()
+
+	opts := prometheus.CounterOpts{Name: "metrics_test_total", Help: "a number of tests"}
+
+	first, err := Register(prometheus.NewCounterVec(opts, []string{"label"}))
+	require.NoError(t, err)
+
+	second, err := Register(prometheus.NewCounterVec(opts, []string{"label"}))
+	require.NoError(t, err)
+	require.Same(t, first, second)
+
+	_, err = Register(prometheus.NewCounterVec(opts, []string{"other"}))
+	require.Error(t, err)
+}
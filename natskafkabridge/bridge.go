@@ -0,0 +1,318 @@
+// Package natskafkabridge forwards messages between NATS and Kafka with at-least-once delivery.
+package natskafkabridge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/yvyrovyi-cinemo/utils/kafkalib"
+	"github.com/yvyrovyi-cinemo/utils/natslib"
+)
+
+const (
+	LogsLabelComponent = "component"
+
+	// fetchTimeout limits a fetch waiting for new messages, so that the fetch requests are renewed.
+	fetchTimeout = 5 * time.Second
+)
+
+var ErrNoRoutes = errors.New("no routes configured")
+
+type (
+	// KafkaConsumer is satisfied by kafkalib.Consumer.
+	KafkaConsumer interface {
+		Run(ctx context.Context, handler kafkalib.MessageHandler) error
+	}
+
+	// KafkaProducer is satisfied by kafkalib.Producer.
+	KafkaProducer interface {
+		ProduceSync(ctx context.Context, msg *kafkalib.Message) (int32, int64, error)
+	}
+
+	Bridge struct {
+		Config
+		nats     *natslib.Client
+		consumer KafkaConsumer
+		producer KafkaProducer
+		toKafka  *Router
+		toNATS   *Router
+		logger   *slog.Logger
+		metrics  *bridgeMetrics
+	}
+)
+
+// New returns the bridge of the configured directions. The consumer is required by KafkaToNATS only
+// and the producer by NATSToKafka only, they may be nil otherwise.
+func New(
+	config Config,
+	client *natslib.Client,
+	consumer KafkaConsumer,
+	producer KafkaProducer,
+	logger *slog.Logger,
+) (*Bridge, error) {
+	config = config.WithDefaults()
+
+	if len(config.NATSToKafka.Routes) == 0 && len(config.KafkaToNATS.Routes) == 0 {
+		return nil, ErrNoRoutes
+	}
+	if len(config.NATSToKafka.Routes) > 0 && producer == nil {
+		return nil, errors.New("nats to kafka requires the kafka producer")
+	}
+	if len(config.KafkaToNATS.Routes) > 0 && consumer == nil {
+		return nil, errors.New("kafka to nats requires the kafka consumer")
+	}
+
+	toKafka, err := NewRouter(config.NATSToKafka.Routes)
+	if err != nil {
+		return nil, fmt.Errorf("nats to kafka routes: %w", err)
+	}
+
+	toNATS, err := NewRouter(config.KafkaToNATS.Routes)
+	if err != nil {
+		return nil, fmt.Errorf("kafka to nats routes: %w", err)
+	}
+
+	metrics, err := initMetrics()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Bridge{
+		Config:   config,
+		nats:     client,
+		consumer: consumer,
+		producer: producer,
+		toKafka:  toKafka,
+		toNATS:   toNATS,
+		logger:   logger.With(LogsLabelComponent, "natskafkabridge"),
+		metrics:  metrics,
+	}, nil
+}
+
+// Run forwards the messages of the configured directions until ctx is done or any direction fails.
+func (b *Bridge) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var runs []func(context.Context) error
+	if len(b.NATSToKafka.Routes) > 0 {
+		runs = append(runs, b.runNATSToKafka)
+	}
+	if len(b.KafkaToNATS.Routes) > 0 {
+		runs = append(runs, b.runKafkaToNATS)
+	}
+
+	b.logger.Info("nats kafka bridge is up and running",
+		"nats_to_kafka_routes", len(b.NATSToKafka.Routes),
+		"kafka_to_nats_routes", len(b.KafkaToNATS.Routes),
+	)
+
+	chanErr := make(chan error, len(runs))
+	for _, run := range runs {
+		go func() {
+			err := run(ctx)
+			cancel()
+			chanErr <- err
+		}()
+	}
+
+	errs := make([]error, 0, len(runs))
+	for range runs {
+		errs = append(errs, <-chanErr)
+	}
+
+	return errors.Join(errs...)
+}
+
+// runNATSToKafka fetches the messages of the JetStream consumer and acknowledges them
+// only after they are produced to Kafka.
+func (b *Bridge) runNATSToKafka(ctx context.Context) error {
+	consumerConfig := b.NATSToKafka.Consumer
+	// pull consumer
+	consumerConfig.DeliverSubject, consumerConfig.DeliverGroup = "", ""
+
+	if err := b.nats.EnsureConsumer(ctx, consumerConfig); err != nil {
+		return fmt.Errorf("ensuring jetstream consumer: %w", err)
+	}
+
+	consumer, err := b.nats.PullConsumer(ctx, consumerConfig.Stream, consumerConfig.Durable)
+	if err != nil {
+		return fmt.Errorf("binding jetstream consumer: %w", err)
+	}
+
+	for {
+		messages, err := b.fetch(ctx, consumer)
+		if err == nil {
+			err = b.forwardBatch(ctx, messages)
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+func (b *Bridge) fetch(ctx context.Context, consumer *natslib.PullConsumer) ([]*natslib.JSMessage, error) {
+	fetchCtx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	defer cancel()
+
+	messages, err := consumer.Fetch(fetchCtx, b.NATSToKafka.Batch)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+			// no new messages
+			return nil, nil
+		}
+		return nil, fmt.Errorf("fetching messages: %w", err)
+	}
+
+	return messages, nil
+}
+
+// forwardBatch forwards the messages in order. When a message fails, it is redelivered
+// after NATSToKafka.RetryDelay together with the rest of the batch, so that the order is kept.
+func (b *Bridge) forwardBatch(ctx context.Context, messages []*natslib.JSMessage) error {
+	for i, jsMsg := range messages {
+		if err := b.forwardToKafka(ctx, jsMsg); err != nil {
+			b.logger.Error("failed to forward message to kafka",
+				"subject", jsMsg.Subject,
+				"deliveries", jsMsg.NumDelivered,
+				"error", err,
+			)
+
+			for _, rest := range messages[i:] {
+				if err := rest.NakWithDelay(b.NATSToKafka.RetryDelay); err != nil {
+					return fmt.Errorf("rejecting message: %w", err)
+				}
+			}
+			return nil
+		}
+
+		if err := jsMsg.Ack(ctx); err != nil {
+			return fmt.Errorf("acknowledging message: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (b *Bridge) forwardToKafka(ctx context.Context, jsMsg *natslib.JSMessage) error {
+	target, ok := b.toKafka.Target(jsMsg.Subject)
+	if !ok {
+		b.metrics.unrouted(directionNATSToKafka)
+		return nil
+	}
+
+	msg := &kafkalib.Message{
+		Topic:     target.Name,
+		Key:       target.Key,
+		Payload:   jsMsg.Data,
+		Headers:   kafkaHeaders(jsMsg.Header, b.NATSToKafka.Headers),
+		Timestamp: jsMsg.Timestamp,
+	}
+
+	if _, _, err := b.producer.ProduceSync(ctx, msg); err != nil {
+		b.metrics.failed(directionNATSToKafka, target.Route)
+		return fmt.Errorf("producing to %s: %w", target.Name, err)
+	}
+
+	b.metrics.forwarded(directionNATSToKafka, target.Route, len(msg.Key)+len(msg.Payload), latency(jsMsg.Timestamp))
+
+	return nil
+}
+
+// runKafkaToNATS publishes the Kafka messages, the consumer commits the offsets of the published ones only.
+func (b *Bridge) runKafkaToNATS(ctx context.Context) error {
+	if err := b.consumer.Run(ctx, b.forwardToNATS); err != nil {
+		return fmt.Errorf("consuming kafka: %w", err)
+	}
+
+	return nil
+}
+
+func (b *Bridge) forwardToNATS(ctx context.Context, msg *kafkalib.Message) error {
+	target, ok := b.toNATS.Target(msg.Topic)
+	if !ok {
+		b.metrics.unrouted(directionKafkaToNATS)
+		return nil
+	}
+
+	natsMsg := &natslib.Message{
+		Subject: target.Name,
+		Data:    msg.Payload,
+		Header:  b.natsHeaders(msg),
+	}
+
+	var err error
+	if b.KafkaToNATS.JetStream {
+		_, err = b.nats.PublishJSMsg(ctx, natsMsg)
+	} else {
+		err = b.nats.PublishMsg(ctx, natsMsg)
+	}
+	if err != nil {
+		b.metrics.failed(directionKafkaToNATS, target.Route)
+		return fmt.Errorf("publishing to %s: %w", target.Name, err)
+	}
+
+	b.metrics.forwarded(directionKafkaToNATS, target.Route, len(msg.Key)+len(msg.Payload), latency(msg.Timestamp))
+
+	return nil
+}
+
+func (b *Bridge) natsHeaders(msg *kafkalib.Message) nats.Header {
+	res := nats.Header{}
+
+	for _, h := range msg.Headers {
+		if key, ok := mapHeader(b.KafkaToNATS.Headers, h.Key); ok {
+			res.Add(key, string(h.Value))
+		}
+	}
+
+	if b.KafkaToNATS.KeyHeader != "" && msg.Key != nil {
+		res.Set(b.KafkaToNATS.KeyHeader, string(msg.Key))
+	}
+
+	if len(res) == 0 {
+		return nil
+	}
+
+	return res
+}
+
+// kafkaHeaders sorts the headers by name, the repeated values are kept in order.
+func kafkaHeaders(header nats.Header, mapping map[string]string) []kafkalib.Header {
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var res []kafkalib.Header
+	for _, key := range keys {
+		mapped, ok := mapHeader(mapping, key)
+		if !ok {
+			continue
+		}
+
+		for _, value := range header[key] {
+			res = append(res, kafkalib.Header{Key: mapped, Value: []byte(value)})
+		}
+	}
+
+	return res
+}
+
+func latency(tm time.Time) float64 {
+	if tm.IsZero() {
+		return 0
+	}
+
+	return time.Since(tm).Seconds()
+}
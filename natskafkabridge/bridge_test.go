@@ -0,0 +1,212 @@
+package natskafkabridge
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yvyrovyi-cinemo/utils/config"
+	"github.com/yvyrovyi-cinemo/utils/kafkalib"
+	"github.com/yvyrovyi-cinemo/utils/kafkalib/kafkalibtest"
+	"github.com/yvyrovyi-cinemo/utils/natslib"
+	"github.com/yvyrovyi-cinemo/utils/natslib/natslibtest"
+)
+
+// failingProducer fails the first produce attempts.
+type failingProducer struct {
+	KafkaProducer
+	failures atomic.Int32
+}
+
+func (p *failingProducer) ProduceSync(ctx context.Context, msg *kafkalib.Message) (int32, int64, error) {
+	if p.failures.Add(-1) >= 0 {
+		return 0, 0, errors.New("kafka is down")
+	}
+
+	return p.KafkaProducer.ProduceSync(ctx, msg)
+}
+
+func TestRouter(t *testing.T) {
+	t.Parallel()
+
+	router, err := NewRouter([]Route{
+		{From: "events.*.device.*", To: "analytics.$1", Key: "$2"},
+		{From: "events.>", To: "analytics.other"},
+	})
+	require.NoError(t, err)
+
+	target, ok := router.Target("events.eu.device.42")
+	require.True(t, ok)
+	require.Equal(t, Target{Route: "events.*.device.*", Name: "analytics.eu", Key: []byte("42")}, target)
+
+	target, ok = router.Target("events.eu.gateway.1")
+	require.True(t, ok)
+	require.Equal(t, Target{Route: "events.>", Name: "analytics.other"}, target)
+
+	require.False(t, router.Match("orders.created"))
+
+	_, err = NewRouter([]Route{{From: "events.*", To: "analytics.$2"}})
+	require.Error(t, err)
+
+	_, err = NewRouter([]Route{{From: "events.*", To: "analytics", Key: "$0"}})
+	require.Error(t, err)
+
+	_, err = NewRouter([]Route{{From: "events.*"}})
+	require.Error(t, err)
+}
+
+func TestBridge_NATSToKafka(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	srv := natslibtest.NewServer(t, natslibtest.WithJetStream())
+	client := srv.Connect()
+	require.NoError(t, client.EnsureStream(ctx, natslib.StreamConfig{Name: "EVENTS", Subjects: []string{"events.>"}}))
+
+	cluster := kafkalibtest.NewCluster()
+	require.NoError(t, cluster.CreateTopic("analytics.eu", 1))
+
+	for i, subject := range []string{"events.eu.device.1", "events.us.device.2", "events.eu.device.3"} {
+		_, err := client.PublishJSMsg(ctx, &natslib.Message{
+			Subject: subject,
+			Data:    []byte{byte(i)},
+			Header:  nats.Header{"Trace-Id": {"trace"}, "Internal": {"x"}},
+		})
+		require.NoError(t, err)
+	}
+
+	producer := &failingProducer{KafkaProducer: cluster.NewProducer()}
+	producer.failures.Store(1)
+
+	cfg := Config{NATSToKafka: NATSToKafkaConfig{
+		Consumer:   natslib.ConsumerConfig{Stream: "EVENTS", Durable: "bridge", AckWait: time.Minute},
+		RetryDelay: 10 * time.Millisecond,
+		Routes:     []Route{{From: "events.eu.device.*", To: "analytics.eu", Key: "$1"}},
+		Headers:    map[string]string{"Trace-Id": "trace_id", "Internal": ""},
+	}}
+
+	bridge, err := New(cfg, srv.Connect(), nil, producer, slog.Default())
+	require.NoError(t, err)
+
+	chanErr := make(chan error, 1)
+	go func() { chanErr <- bridge.Run(ctx) }()
+
+	require.Eventually(t, func() bool {
+		return len(cluster.Messages("analytics.eu")) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-chanErr)
+
+	// the failed message is redelivered in order, the unrouted one is dropped
+	messages := cluster.Messages("analytics.eu")
+	require.Equal(t, []byte("1"), messages[0].Key)
+	require.Equal(t, []byte{0}, messages[0].Payload)
+	require.Equal(t, []kafkalib.Header{{Key: "trace_id", Value: []byte("trace")}}, messages[0].Headers)
+	require.Equal(t, []byte("3"), messages[1].Key)
+
+	// all the messages are acknowledged
+	consumer, err := client.PullConsumer(context.Background(), "EVENTS", "bridge")
+	require.NoError(t, err)
+
+	fetchCtx, fetchCancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer fetchCancel()
+
+	_, err = consumer.Fetch(fetchCtx, 10)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestBridge_KafkaToNATS(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	srv := natslibtest.NewServer(t)
+	capture := srv.Capture("fanout.>")
+
+	cluster := kafkalibtest.NewCluster()
+	require.NoError(t, cluster.CreateTopic("orders.eu", 1))
+	require.NoError(t, cluster.CreateTopic("payments", 1))
+
+	kafkaProducer := cluster.NewProducer()
+	require.NoError(t, kafkaProducer.Produce(&kafkalib.Message{
+		Topic:   "orders.eu",
+		Key:     []byte("order-1"),
+		Payload: []byte("created"),
+		Headers: []kafkalib.Header{{Key: "trace_id", Value: []byte("trace")}},
+	}))
+	require.NoError(t, kafkaProducer.Produce(&kafkalib.Message{Topic: "payments", Payload: []byte("paid")}))
+
+	cfg := Config{KafkaToNATS: KafkaToNATSConfig{
+		Routes:    []Route{{From: "orders.*", To: "fanout.orders.$1"}},
+		Headers:   map[string]string{"trace_id": "Trace-Id"},
+		KeyHeader: "Kafka-Key",
+	}}
+
+	consumer := cluster.NewConsumer(kafkalib.ConsumerConfig{
+		GroupID:       "bridge",
+		Topics:        []string{"orders.eu", "payments"},
+		InitialOffset: kafkalib.InitialOffsetOldest,
+	})
+
+	bridge, err := New(cfg, srv.Connect(), consumer, nil, slog.Default())
+	require.NoError(t, err)
+
+	chanErr := make(chan error, 1)
+	go func() { chanErr <- bridge.Run(ctx) }()
+
+	capture.Wait(1)
+
+	require.Eventually(t, func() bool {
+		offset, ok := cluster.CommittedOffset("bridge", "payments", 0)
+		return ok && offset == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-chanErr)
+
+	messages := capture.Messages()
+	require.Len(t, messages, 1)
+	require.Equal(t, "fanout.orders.eu", messages[0].Subject)
+	require.Equal(t, []byte("created"), messages[0].Data)
+	require.Equal(t, "trace", messages[0].Header.Get("Trace-Id"))
+	require.Equal(t, "order-1", messages[0].Header.Get("Kafka-Key"))
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	_, err := New(Config{}, nil, nil, nil, slog.Default())
+	require.ErrorIs(t, err, ErrNoRoutes)
+
+	_, err = New(Config{NATSToKafka: NATSToKafkaConfig{
+		Routes: []Route{{From: "events.>", To: "events"}},
+	}}, nil, nil, nil, slog.Default())
+	require.Error(t, err)
+}
+
+func TestConfig_Validate(t *testing.T) {
+	t.Parallel()
+
+	cfg := Config{KafkaToNATS: KafkaToNATSConfig{
+		Routes: []Route{{From: "orders.*", To: "fanout.$2"}},
+	}}
+	cfg.Producer.Brokers = "localhost:9092"
+
+	err := config.Validate(&cfg)
+	require.ErrorIs(t, err, config.ErrRequired)
+	require.ErrorContains(t, err, "kafka_consumer.brokers: required; kafka_consumer.group_id: required; kafka_to_nats.routes: ")
+
+	cfg.Consumer.GroupID, cfg.Consumer.Brokers = "bridge", "localhost:9092"
+	cfg.KafkaToNATS.Routes[0].To = "fanout.$1"
+	require.NoError(t, config.Validate(&cfg))
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/yvyrovyi-cinemo/utils/config"
+	"github.com/yvyrovyi-cinemo/utils/infraserver"
+	"github.com/yvyrovyi-cinemo/utils/kafkalib"
+	"github.com/yvyrovyi-cinemo/utils/natskafkabridge"
+	"github.com/yvyrovyi-cinemo/utils/natslib"
+)
+
+type Config struct {
+	Bridge      natskafkabridge.Config `envPrefix:"BRIDGE_" json:"bridge" yaml:"bridge"`
+	InfraServer infraserver.Config     `envPrefix:"INFRA_SERVER_" json:"infra_server" yaml:"infra_server"`
+}
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Println("ERR:", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	var cfg Config
+
+	configPath := flag.String("config", "", "comma-separated paths to JSON or YAML config files, later ones override earlier ones")
+	flags := config.NewFlags(flag.CommandLine, &cfg)
+	flag.Parse()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+
+	var sources config.Sources
+	err := config.Load(&cfg,
+		config.WithFiles(strings.Split(*configPath, ",")...), config.WithFlags(flags), config.WithSources(&sources))
+	if err != nil {
+		return fmt.Errorf("parsing config: %w", err)
+	}
+	cfg.Bridge = cfg.Bridge.WithDefaults()
+
+	client, err := natslib.Connect(ctx, cfg.Bridge.NATS, logger)
+	if err != nil {
+		return fmt.Errorf("connecting to nats: %w", err)
+	}
+	defer func() {
+		if err := client.Shutdown(context.Background()); err != nil {
+			logger.Error("closing nats client", "error", err)
+		}
+	}()
+
+	consumer, err := newConsumer(cfg.Bridge, logger)
+	if err != nil {
+		return err
+	}
+
+	producer, closeProducer, err := newProducer(cfg.Bridge, logger)
+	if err != nil {
+		return err
+	}
+	defer closeProducer()
+
+	bridge, err := natskafkabridge.New(cfg.Bridge, client, consumer, producer, logger)
+	if err != nil {
+		return fmt.Errorf("creating bridge: %w", err)
+	}
+
+	server := infraserver.New(cfg.InfraServer)
+	server.Handle(infraserver.DefaultConfigEndpoint, config.Handler(&cfg, sources))
+
+	return runAll(ctx, cancel, bridge, client, server)
+}
+
+// newConsumer returns nil when Kafka is not forwarded to NATS.
+// The consumed topics are the existing ones matching the routes unless they are configured.
+func newConsumer(cfg natskafkabridge.Config, logger *slog.Logger) (natskafkabridge.KafkaConsumer, error) {
+	if len(cfg.KafkaToNATS.Routes) == 0 {
+		return nil, nil
+	}
+
+	if len(cfg.Consumer.Topics) == 0 {
+		topics, err := sourceTopics(cfg, logger)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Consumer.Topics = topics
+	}
+
+	consumer, err := kafkalib.NewConsumer(cfg.Consumer, logger)
+	if err != nil {
+		return nil, fmt.Errorf("creating kafka consumer: %w", err)
+	}
+
+	return consumer, nil
+}
+
+// newProducer returns nil when NATS is not forwarded to Kafka.
+func newProducer(cfg natskafkabridge.Config, logger *slog.Logger) (natskafkabridge.KafkaProducer, func(), error) {
+	if len(cfg.NATSToKafka.Routes) == 0 {
+		return nil, func() {}, nil
+	}
+
+	producer, err := kafkalib.NewProducer(cfg.Producer, logger)
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating kafka producer: %w", err)
+	}
+
+	return producer, func() {
+		if err := producer.Close(); err != nil {
+			logger.Error("closing kafka producer", "error", err)
+		}
+	}, nil
+}
+
+// sourceTopics lists the Kafka topics matching the KafkaToNATS routes.
+func sourceTopics(cfg natskafkabridge.Config, logger *slog.Logger) ([]string, error) {
+	router, err := natskafkabridge.NewRouter(cfg.KafkaToNATS.Routes)
+	if err != nil {
+		return nil, fmt.Errorf("kafka to nats routes: %w", err)
+	}
+
+	admin, err := kafkalib.NewAdmin(cfg.Consumer.Config, logger)
+	if err != nil {
+		return nil, fmt.Errorf("creating kafka admin: %w", err)
+	}
+	defer func() { _ = admin.Close() }()
+
+	topicInfos, err := admin.ListTopics()
+	if err != nil {
+		return nil, fmt.Errorf("listing kafka topics: %w", err)
+	}
+
+	var topics []string
+	for _, t := range topicInfos {
+		if router.Match(t.Name) {
+			topics = append(topics, t.Name)
+		}
+	}
+
+	if len(topics) == 0 {
+		return nil, errors.New("no kafka topics match the kafka to nats routes")
+	}
+
+	return topics, nil
+}
+
+func runAll(
+	ctx context.Context,
+	cancel func(),
+	bridge *natskafkabridge.Bridge,
+	client *natslib.Client,
+	server *infraserver.Server,
+) error {
+	chanServerErr := make(chan error, 1)
+	go func() {
+		chanServerErr <- server.Run(ctx, []func(context.Context) error{client.Ready}, nil)
+		cancel()
+	}()
+
+	err := bridge.Run(ctx)
+	cancel()
+
+	return errors.Join(err, <-chanServerErr)
+}
@@ -0,0 +1,127 @@
+package natskafkabridge
+
+import (
+	"errors"
+	"time"
+
+	"github.com/yvyrovyi-cinemo/utils/config"
+	"github.com/yvyrovyi-cinemo/utils/kafkalib"
+	"github.com/yvyrovyi-cinemo/utils/natslib"
+)
+
+const (
+	DefaultBatch      = 100
+	DefaultRetryDelay = time.Second
+)
+
+type (
+	Config struct {
+		NATS     natslib.Config          `envPrefix:"NATS_" json:"nats" yaml:"nats"`
+		// Consumer and Producer are validated only when their directions are enabled, see Validate.
+		Consumer kafkalib.ConsumerConfig `envPrefix:"KAFKA_CONSUMER_" json:"kafka_consumer" yaml:"kafka_consumer" validate:"-"`
+		Producer kafkalib.ProducerConfig `envPrefix:"KAFKA_PRODUCER_" json:"kafka_producer" yaml:"kafka_producer" validate:"-"`
+
+		// NATSToKafka forwards the messages of a JetStream stream to Kafka, it is disabled without routes.
+		NATSToKafka NATSToKafkaConfig `envPrefix:"NATS_TO_KAFKA_" json:"nats_to_kafka" yaml:"nats_to_kafka"`
+		// KafkaToNATS publishes the messages of Kafka topics to NATS, it is disabled without routes.
+		KafkaToNATS KafkaToNATSConfig `envPrefix:"KAFKA_TO_NATS_" json:"kafka_to_nats" yaml:"kafka_to_nats"`
+	}
+
+	NATSToKafkaConfig struct {
+		// Consumer is the durable pull consumer of the stream, it is created or updated on start.
+		Consumer natslib.ConsumerConfig `envPrefix:"CONSUMER_" json:"consumer" yaml:"consumer"`
+		// Batch is the number of messages fetched at once.
+		Batch int `env:"BATCH" envDefault:"100" json:"batch" yaml:"batch" default:"100"`
+		// RetryDelay is the delay of the redelivery of the messages failed to be produced to Kafka.
+		RetryDelay time.Duration `env:"RETRY_DELAY" envDefault:"1s" json:"retry_delay" yaml:"retry_delay" default:"1s"`
+
+		// Routes map the subjects to the Kafka topics, the first matching route wins.
+		// The messages not matching any route are acknowledged and dropped.
+		Routes []Route `json:"routes" yaml:"routes"`
+		// Headers renames the NATS headers to the Kafka ones, the headers mapped to "" are dropped.
+		// The headers not listed keep their name.
+		Headers map[string]string `json:"headers" yaml:"headers"`
+	}
+
+	KafkaToNATSConfig struct {
+		// Routes map the Kafka topics to the subjects, the first matching route wins.
+		// The messages not matching any route are skipped.
+		Routes []Route `json:"routes" yaml:"routes"`
+		// Headers renames the Kafka headers to the NATS ones, the headers mapped to "" are dropped.
+		// The headers not listed keep their name.
+		Headers map[string]string `json:"headers" yaml:"headers"`
+
+		// KeyHeader is the NATS header the Kafka message key is passed in, the key is dropped when it is empty.
+		KeyHeader string `env:"KEY_HEADER" envDefault:"Kafka-Key" json:"key_header" yaml:"key_header" default:"Kafka-Key"`
+
+		// JetStream publishes the messages to a stream and waits for its acknowledgement,
+		// so the Kafka offset is committed only for the persisted messages.
+		// The core NATS publish does not wait for the subscribers, so the message may be lost on a disconnect.
+		JetStream bool `env:"JETSTREAM" json:"jetstream" yaml:"jetstream"`
+	}
+
+	// Route maps the NATS subjects or the Kafka topics matching From to the To ones.
+	// The Kafka topics are split into the tokens by dots as well as the subjects.
+	Route struct {
+		// From is the pattern with the * and > wildcards, e.g. "events.*.>".
+		From string `json:"from" yaml:"from"`
+		// To is the target name template, $N is replaced with the value of the N-th From wildcard
+		// counting from 1, e.g. "analytics.$1" for the pattern "events.*".
+		To string `json:"to" yaml:"to"`
+		// Key is the Kafka message key template with the From wildcards, e.g. "$1".
+		// The messages are produced without a key when it is empty, it is ignored by KafkaToNATS.
+		Key string `json:"key" yaml:"key"`
+	}
+)
+
+func (c Config) WithDefaults() Config {
+	if c.NATSToKafka.Batch <= 0 {
+		c.NATSToKafka.Batch = DefaultBatch
+	}
+	if c.NATSToKafka.RetryDelay <= 0 {
+		c.NATSToKafka.RetryDelay = DefaultRetryDelay
+	}
+
+	c.Consumer = c.Consumer.WithDefaults()
+
+	return c
+}
+
+// Validate implements config.Validator, the Kafka clients of the disabled directions are not checked.
+func (c Config) Validate() error {
+	if len(c.NATSToKafka.Routes) == 0 && len(c.KafkaToNATS.Routes) == 0 {
+		return ErrNoRoutes
+	}
+
+	var errs []error
+	required := func(path, value string) {
+		if value == "" {
+			errs = append(errs, config.NewFieldError(path, config.ErrRequired))
+		}
+	}
+	validate := func(path string, cfg any) {
+		if err := config.Validate(cfg); err != nil {
+			errs = append(errs, config.NewFieldError(path, err))
+		}
+	}
+
+	if len(c.NATSToKafka.Routes) > 0 {
+		required("nats_to_kafka.consumer.stream", c.NATSToKafka.Consumer.Stream)
+		required("nats_to_kafka.consumer.durable", c.NATSToKafka.Consumer.Durable)
+		validate("kafka_producer", c.Producer)
+
+		if _, err := NewRouter(c.NATSToKafka.Routes); err != nil {
+			errs = append(errs, config.NewFieldError("nats_to_kafka.routes", err))
+		}
+	}
+
+	if len(c.KafkaToNATS.Routes) > 0 {
+		validate("kafka_consumer", c.Consumer)
+
+		if _, err := NewRouter(c.KafkaToNATS.Routes); err != nil {
+			errs = append(errs, config.NewFieldError("kafka_to_nats.routes", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
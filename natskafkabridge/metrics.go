@@ -0,0 +1,105 @@
+package natskafkabridge
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/yvyrovyi-cinemo/utils/metrics"
+)
+
+const (
+	directionNATSToKafka = "nats_to_kafka"
+	directionKafkaToNATS = "kafka_to_nats"
+)
+
+type bridgeMetrics struct {
+	messagesCounterVec *prometheus.CounterVec
+	bytesCounterVec    *prometheus.CounterVec
+	errorsCounterVec   *prometheus.CounterVec
+	unroutedCounterVec *prometheus.CounterVec
+	latencyGaugeVec    *prometheus.GaugeVec
+}
+
+// initMetrics labels the metrics with the route patterns rather than the subjects,
+// so that the subject tokens like ids do not blow up the number of series.
+func initMetrics() (*bridgeMetrics, error) {
+	labels := []string{"direction", "route"}
+
+	messagesCounterVec, err := metrics.Register(prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "natskafkabridge_messages_total",
+			Help: "a number of forwarded messages",
+		},
+		labels,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to register natskafkabridge metrics: %w", err)
+	}
+
+	bytesCounterVec, err := metrics.Register(prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "natskafkabridge_bytes_total",
+			Help: "a number of forwarded key and payload bytes",
+		},
+		labels,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to register natskafkabridge metrics: %w", err)
+	}
+
+	errorsCounterVec, err := metrics.Register(prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "natskafkabridge_errors_total",
+			Help: "a number of messages failed to be forwarded, they are redelivered",
+		},
+		labels,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to register natskafkabridge metrics: %w", err)
+	}
+
+	unroutedCounterVec, err := metrics.Register(prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "natskafkabridge_unrouted_total",
+			Help: "a number of messages dropped as not matching any route",
+		},
+		[]string{"direction"},
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to register natskafkabridge metrics: %w", err)
+	}
+
+	latencyGaugeVec, err := metrics.Register(prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "natskafkabridge_latency_seconds",
+			Help: "a time between the source message timestamp and its acknowledgement by the target",
+		},
+		labels,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to register natskafkabridge metrics: %w", err)
+	}
+
+	return &bridgeMetrics{
+		messagesCounterVec: messagesCounterVec,
+		bytesCounterVec:    bytesCounterVec,
+		errorsCounterVec:   errorsCounterVec,
+		unroutedCounterVec: unroutedCounterVec,
+		latencyGaugeVec:    latencyGaugeVec,
+	}, nil
+}
+
+func (m *bridgeMetrics) forwarded(direction, route string, bytes int, latencySec float64) {
+	m.messagesCounterVec.WithLabelValues(direction, route).Inc()
+	m.bytesCounterVec.WithLabelValues(direction, route).Add(float64(bytes))
+	m.latencyGaugeVec.WithLabelValues(direction, route).Set(latencySec)
+}
+
+func (m *bridgeMetrics) failed(direction, route string) {
+	m.errorsCounterVec.WithLabelValues(direction, route).Inc()
+}
+
+func (m *bridgeMetrics) unrouted(direction string) {
+	m.unroutedCounterVec.WithLabelValues(direction).Inc()
+}
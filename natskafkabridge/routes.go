@@ -0,0 +1,117 @@
+package natskafkabridge
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/yvyrovyi-cinemo/utils/natslib"
+)
+
+type (
+	// Router maps the subjects or topics to the targets of the first matching route.
+	Router struct {
+		routes []Route
+	}
+
+	// Target is the destination of a routed message.
+	Target struct {
+		// Route is the From pattern of the matched route.
+		Route string
+		Name  string
+		Key   []byte
+	}
+)
+
+var templateRef = regexp.MustCompile(`\$(\d+)`)
+
+func NewRouter(routes []Route) (*Router, error) {
+	for _, route := range routes {
+		if route.From == "" || route.To == "" {
+			return nil, errors.New("route from and to are required")
+		}
+
+		wildcards := countWildcards(route.From)
+		if err := checkTemplate(route.To, wildcards); err != nil {
+			return nil, fmt.Errorf("route %q to: %w", route.From, err)
+		}
+		if err := checkTemplate(route.Key, wildcards); err != nil {
+			return nil, fmt.Errorf("route %q key: %w", route.From, err)
+		}
+	}
+
+	return &Router{routes: routes}, nil
+}
+
+// Match reports whether any route matches the name.
+func (r *Router) Match(name string) bool {
+	_, ok := r.Target(name)
+	return ok
+}
+
+// Target returns the target of the first route matching the name, false if there is none.
+func (r *Router) Target(name string) (Target, bool) {
+	for _, route := range r.routes {
+		wildcards, ok := natslib.SubjectWildcards(route.From, name)
+		if !ok {
+			continue
+		}
+
+		res := Target{
+			Route: route.From,
+			Name:  expand(route.To, wildcards),
+		}
+		if route.Key != "" {
+			res.Key = []byte(expand(route.Key, wildcards))
+		}
+
+		return res, true
+	}
+
+	return Target{}, false
+}
+
+// mapHeader returns the target name of the header, false if the header is dropped.
+func mapHeader(mapping map[string]string, key string) (string, bool) {
+	if mapped, ok := mapping[key]; ok {
+		return mapped, mapped != ""
+	}
+
+	return key, true
+}
+
+func countWildcards(pattern string) int {
+	res := 0
+	for _, token := range natslib.SubjectTokens(pattern) {
+		if token == "*" || token == ">" {
+			res++
+		}
+	}
+
+	return res
+}
+
+func checkTemplate(template string, wildcards int) error {
+	for _, match := range templateRef.FindAllStringSubmatch(template, -1) {
+		n, err := strconv.Atoi(match[1])
+		if err != nil || n < 1 || n > wildcards {
+			return fmt.Errorf("%s refers to a missing wildcard, the pattern has %d", match[0], wildcards)
+		}
+	}
+
+	return nil
+}
+
+// expand replaces $N with the N-th wildcard value, the template is checked by NewRouter.
+func expand(template string, wildcards []string) string {
+	if !strings.Contains(template, "$") {
+		return template
+	}
+
+	return templateRef.ReplaceAllStringFunc(template, func(ref string) string {
+		n, _ := strconv.Atoi(ref[1:])
+		return wildcards[n-1]
+	})
+}
@@ -0,0 +1,77 @@
+package natslib
+
+import (
+	"crypto/tls"
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+)
+
+// AuthConfig holds the credentials and TLS settings of the connection.
+// The fields may be combined, e.g. TLS client certificate with the creds file.
+type AuthConfig struct {
+	User     string `env:"USER" json:"user" yaml:"user"`
+	Password string `env:"PASSWORD" json:"password" yaml:"password" secret:"true"`
+	Token    string `env:"TOKEN" json:"token" yaml:"token" secret:"true"`
+
+	// NKeySeedFilePath is the path to the file with the user NKey seed, "SUA..." string.
+	NKeySeedFilePath string `env:"NKEY_SEED_FILE_PATH" json:"nkey_seed_file_path" yaml:"nkey_seed_file_path"`
+	// CredsFilePath is the path to the .creds file with the user JWT and NKey seed.
+	CredsFilePath string `env:"CREDS_FILE_PATH" json:"creds_file_path" yaml:"creds_file_path"`
+
+	// TLS is enabled when any of the TLS fields is set or the URL scheme is tls://.
+	// The files are read on every connect, so rotated certificates are picked up on reconnect.
+	TLSCaFilePath         string `env:"TLS_CA_FILE_PATH" json:"tls_ca_file_path" yaml:"tls_ca_file_path"`
+	TLSClientCertFilePath string `env:"TLS_CLIENT_CERT_FILE_PATH" json:"tls_client_cert_file_path" yaml:"tls_client_cert_file_path"`
+	TLSClientKeyFilePath  string `env:"TLS_CLIENT_KEY_FILE_PATH" json:"tls_client_key_file_path" yaml:"tls_client_key_file_path"`
+	// TLSServerName overrides the host name the server certificate is verified against.
+	TLSServerName string `env:"TLS_SERVER_NAME" json:"tls_server_name" yaml:"tls_server_name"`
+}
+
+func authOptions(config AuthConfig) ([]nats.Option, error) {
+	var res []nats.Option
+
+	if config.User != "" {
+		res = append(res, nats.UserInfo(config.User, config.Password))
+	}
+
+	if config.Token != "" {
+		res = append(res, nats.Token(config.Token))
+	}
+
+	if config.NKeySeedFilePath != "" {
+		opt, err := nats.NkeyOptionFromSeed(config.NKeySeedFilePath)
+		if err != nil {
+			return nil, fmt.Errorf("reading nkey seed file: %w", err)
+		}
+		res = append(res, opt)
+	}
+
+	if config.CredsFilePath != "" {
+		res = append(res, nats.UserCredentials(config.CredsFilePath))
+	}
+
+	return append(res, tlsOptions(config)...), nil
+}
+
+func tlsOptions(config AuthConfig) []nats.Option {
+	if config.TLSCaFilePath == "" && config.TLSClientCertFilePath == "" && config.TLSServerName == "" {
+		return nil
+	}
+
+	// Secure goes first, RootCAs and ClientCert add to its config.
+	res := []nats.Option{nats.Secure(&tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: config.TLSServerName,
+	})}
+
+	if config.TLSCaFilePath != "" {
+		res = append(res, nats.RootCAs(config.TLSCaFilePath))
+	}
+
+	if config.TLSClientCertFilePath != "" {
+		res = append(res, nats.ClientCert(config.TLSClientCertFilePath, config.TLSClientKeyFilePath))
+	}
+
+	return res
+}
@@ -0,0 +1,265 @@
+package natslib
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"log/slog"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nats-io/jwt/v2"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nkeys"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnect_TLS(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeTestCertificates(t, dir)
+
+	tlsConfig, err := server.GenTLSConfig(&server.TLSConfigOpts{
+		CertFile: filepath.Join(dir, "server-cert.pem"),
+		KeyFile:  filepath.Join(dir, "server-key.pem"),
+		CaFile:   filepath.Join(dir, "ca.pem"),
+		Verify:   true,
+	})
+	require.NoError(t, err)
+
+	url := runTestServerWithOptions(t, &server.Options{
+		TLS:       true,
+		TLSVerify: true,
+		TLSConfig: tlsConfig,
+		Username:  "app",
+		Password:  "secret",
+	})
+
+	auth := AuthConfig{
+		User:                  "app",
+		Password:              "secret",
+		TLSCaFilePath:         filepath.Join(dir, "ca.pem"),
+		TLSClientCertFilePath: filepath.Join(dir, "client-cert.pem"),
+		TLSClientKeyFilePath:  filepath.Join(dir, "client-key.pem"),
+		TLSServerName:         "nats.test",
+	}
+	testConnect(t, url, auth)
+
+	noClientCert := auth
+	noClientCert.TLSClientCertFilePath, noClientCert.TLSClientKeyFilePath = "", ""
+	_, err = connectWithAuth(url, noClientCert)
+	require.Error(t, err)
+
+	// the server certificate is issued for nats.test only
+	wrongServerName := auth
+	wrongServerName.TLSServerName = ""
+	_, err = connectWithAuth(url, wrongServerName)
+	require.Error(t, err)
+}
+
+func TestConnect_Auth(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	t.Run("token", func(t *testing.T) {
+		t.Parallel()
+
+		url := runTestServerWithOptions(t, &server.Options{Authorization: "s3cr3t"})
+
+		testConnect(t, url, AuthConfig{Token: "s3cr3t"})
+
+		_, err := connectWithAuth(url, AuthConfig{Token: "wrong"})
+		require.Error(t, err)
+	})
+
+	t.Run("nkey", func(t *testing.T) {
+		t.Parallel()
+
+		user, err := nkeys.CreateUser()
+		require.NoError(t, err)
+		publicKey, err := user.PublicKey()
+		require.NoError(t, err)
+		seed, err := user.Seed()
+		require.NoError(t, err)
+
+		seedFilePath := filepath.Join(dir, "user.nk")
+		require.NoError(t, os.WriteFile(seedFilePath, seed, 0o600))
+
+		url := runTestServerWithOptions(t, &server.Options{Nkeys: []*server.NkeyUser{{Nkey: publicKey}}})
+
+		testConnect(t, url, AuthConfig{NKeySeedFilePath: seedFilePath})
+
+		_, err = connectWithAuth(url, AuthConfig{})
+		require.Error(t, err)
+	})
+
+	t.Run("creds", func(t *testing.T) {
+		t.Parallel()
+
+		credsFilePath := filepath.Join(dir, "user.creds")
+		opts := operatorModeOptions(t, credsFilePath)
+
+		url := runTestServerWithOptions(t, opts)
+
+		testConnect(t, url, AuthConfig{CredsFilePath: credsFilePath})
+
+		_, err := connectWithAuth(url, AuthConfig{})
+		require.Error(t, err)
+	})
+}
+
+func TestConnect_Servers(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	first := runTestServer(t)
+	second := runTestServer(t)
+
+	client, err := Connect(ctx, Config{
+		URL:          "nats://127.0.0.1:1," + first + "," + second,
+		NoRandomize:  true,
+		DrainTimeout: time.Second,
+	}, slog.Default())
+	require.NoError(t, err)
+	defer client.Close()
+
+	require.Equal(t, first, client.conn.ConnectedUrl())
+}
+
+func testConnect(t *testing.T, url string, auth AuthConfig) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client, err := connectWithAuth(url, auth)
+	require.NoError(t, err)
+	defer client.Close()
+
+	sub, err := client.Subscribe(ctx, "secured")
+	require.NoError(t, err)
+	require.NoError(t, client.Publish(ctx, "secured", []byte("hello")))
+
+	payload, err := sub.Receive(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(payload))
+}
+
+func connectWithAuth(url string, auth AuthConfig) (*Client, error) {
+	return Connect(context.Background(), Config{
+		URL:                              url,
+		DrainTimeout:                     time.Second,
+		SubCheckPeriod:                   time.Second,
+		SubscriptionPendingMessagesLimit: 1000,
+		AuthConfig:                       auth,
+	}, slog.Default())
+}
+
+// operatorModeOptions returns the options of a server trusting a test operator
+// and writes the creds file of a user of the operator account.
+func operatorModeOptions(t *testing.T, credsFilePath string) *server.Options {
+	t.Helper()
+
+	operator, err := nkeys.CreateOperator()
+	require.NoError(t, err)
+	operatorPublicKey, err := operator.PublicKey()
+	require.NoError(t, err)
+
+	operatorJWT, err := jwt.NewOperatorClaims(operatorPublicKey).Encode(operator)
+	require.NoError(t, err)
+	operatorClaims, err := jwt.DecodeOperatorClaims(operatorJWT)
+	require.NoError(t, err)
+
+	account, err := nkeys.CreateAccount()
+	require.NoError(t, err)
+	accountPublicKey, err := account.PublicKey()
+	require.NoError(t, err)
+
+	accountJWT, err := jwt.NewAccountClaims(accountPublicKey).Encode(operator)
+	require.NoError(t, err)
+
+	user, err := nkeys.CreateUser()
+	require.NoError(t, err)
+	userPublicKey, err := user.PublicKey()
+	require.NoError(t, err)
+	userSeed, err := user.Seed()
+	require.NoError(t, err)
+
+	userJWT, err := jwt.NewUserClaims(userPublicKey).Encode(account)
+	require.NoError(t, err)
+
+	creds, err := jwt.FormatUserConfig(userJWT, userSeed)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(credsFilePath, creds, 0o600))
+
+	resolver := &server.MemAccResolver{}
+	require.NoError(t, resolver.Store(accountPublicKey, accountJWT))
+
+	return &server.Options{
+		TrustedOperators: []*jwt.OperatorClaims{operatorClaims},
+		AccountResolver:  resolver,
+	}
+}
+
+// writeTestCertificates writes the CA, the server certificate for nats.test
+// and the client certificate signed by the CA to the dir.
+func writeTestCertificates(t *testing.T, dir string) {
+	t.Helper()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	writePEM(t, filepath.Join(dir, "ca.pem"), "CERTIFICATE", caDER)
+
+	caCert, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	for i, name := range []string{"server", "client"} {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(int64(i + 2)),
+			Subject:      pkix.Name{CommonName: name},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+			DNSNames:     []string{"nats.test"},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+		require.NoError(t, err)
+		writePEM(t, filepath.Join(dir, name+"-cert.pem"), "CERTIFICATE", der)
+
+		keyDER, err := x509.MarshalECPrivateKey(key)
+		require.NoError(t, err)
+		writePEM(t, filepath.Join(dir, name+"-key.pem"), "EC PRIVATE KEY", keyDER)
+	}
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600))
+}
@@ -4,8 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"io"
 	"log/slog"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/nats-io/nats.go"
@@ -14,19 +15,60 @@ import (
 type (
 	Client struct {
 		conn           *nats.Conn
+		js             nats.JetStreamContext
 		logger         *slog.Logger
 		subCheckPeriod time.Duration
+		drainTimeout   time.Duration
+
+		pendingMessagesLimit int
+		// slowConsumerHandlers keeps HandleOptions.OnSlowConsumer by *nats.Subscription
+		slowConsumerHandlers sync.Map
+		// subscriptions keeps the active *nats.Subscription for the metrics and Drain
+		subscriptions sync.Map
+		// watchers are the goroutines removing the subscriptions
+		watchers sync.WaitGroup
+		// inFlight is the number of Handle messages taken from the subscriptions and not handled yet,
+		// handling is the number of them passed to the handlers
+		inFlight atomic.Int64
+		handling atomic.Int64
+
+		// done is closed by Close, connClosed when the connection is closed for any reason
+		done           chan struct{}
+		closeOnce      sync.Once
+		connClosed     chan struct{}
+		connClosedOnce sync.Once
+		draining       atomic.Bool
+
+		name               string
+		metrics            *clientsCollector
+		stateChangeHandler atomic.Pointer[StateChangeHandler]
+
+		publishPolicy  DisconnectedPublishPolicy
+		publishMetrics *publishMetrics
+		spill          *spillQueue
+		replaying      atomic.Bool
 	}
 
 	Config struct {
-		URL            string        `env:"URL" json:"url" yaml:"url"`
-		DrainTimeout   time.Duration `env:"DRAIN_TIMEOUT" envDefault:"10s" json:"drain_timeout" yaml:"drain_timeout" default:"10s"`
+		// Name is the connection name shown by the server monitoring and the client metrics label.
+		Name string `env:"NAME" json:"name" yaml:"name"`
+		// URL is a comma separated list of the server URLs, e.g. "nats://nats-1:4222,nats://nats-2:4222".
+		URL string `env:"URL" json:"url" yaml:"url"`
+		// NoRandomize makes the client connect to the servers in the URL order instead of the random one.
+		NoRandomize bool `env:"NO_RANDOMIZE" json:"no_randomize" yaml:"no_randomize"`
+
+		DrainTimeout time.Duration `env:"DRAIN_TIMEOUT" envDefault:"10s" json:"drain_timeout" yaml:"drain_timeout" default:"10s"`
+		// SubCheckPeriod is how often a closed subscription with SubscribeOptions.Resubscribe is tried to be re-created.
 		SubCheckPeriod time.Duration `env:"SUB_CHECK_PERIOD" envDefault:"10s" json:"sub_check_period" yaml:"sub_check_period" default:"10s"`
 		MaxReconnect   int           `env:"MAX_RECONNECT" envDefault:"60" json:"max_reconnect" yaml:"max_reconnect" default:"60"`
 		ReconnectWait  time.Duration `env:"RECONNECT_WAIT" envDefault:"1s" json:"reconnect_wait" yaml:"reconnect_wait" default:"1s"`
 
 		// SubscriptionPendingMessagesLimit is the maximum number of pending messages for a subscription.
 		SubscriptionPendingMessagesLimit int `env:"SUBSCRIPTION_PENDING_MESSAGES_LIMIT" envDefault:"1000" json:"subscription_pending_messages_limit" yaml:"subscription_pending_messages_limit" default:"1000"`
+
+		AuthConfig `yaml:",inline"`
+
+		DisconnectedPublish DisconnectedPublishConfig `envPrefix:"DISCONNECTED_PUBLISH_" json:"disconnected_publish" yaml:"disconnected_publish"`
 	}
 )
 
@@ -39,7 +81,72 @@ var (
 )
 
 func Connect(ctx context.Context, config Config, logger *slog.Logger) (*Client, error) {
+	metrics, err := initMetrics()
+	if err != nil {
+		return nil, fmt.Errorf("failed to init metrics: %w", err)
+	}
+
+	publishMetrics, err := initPublishMetrics()
+	if err != nil {
+		return nil, err
+	}
+
+	client := &Client{
+		name:                 config.Name,
+		metrics:              metrics,
+		publishMetrics:       publishMetrics,
+		logger:               logger,
+		subCheckPeriod:       config.SubCheckPeriod,
+		drainTimeout:         config.DrainTimeout,
+		pendingMessagesLimit: config.SubscriptionPendingMessagesLimit,
+		done:                 make(chan struct{}),
+		connClosed:           make(chan struct{}),
+	}
+
+	natsOptions, err := client.natsOptions(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	nc, err := nats.Connect(config.URL, natsOptions...)
+	if err != nil {
+		client.closeSpill()
+		return nil, fmt.Errorf("failed to connect to nats: %w", err)
+	}
+
+	js, err := nc.JetStream()
+	if err != nil {
+		nc.Close()
+		client.closeSpill()
+		return nil, fmt.Errorf("failed to init jetstream: %w", err)
+	}
+
+	client.conn = nc
+	client.js = js
+
+	metrics.add(client)
+
+	// the messages spilled by the previous process
+	if client.spilling() {
+		go client.replaySpilled()
+	}
+
+	return client, nil
+}
+
+func (c *Client) natsOptions(ctx context.Context, config Config) ([]nats.Option, error) {
+	authOptions, err := authOptions(config.AuthConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	publishOptions, err := c.initPublishPolicy(config.DisconnectedPublish)
+	if err != nil {
+		return nil, err
+	}
+
 	natsOptions := []nats.Option{
+		nats.Name(config.Name),
 		nats.SetCustomDialer(newDialer(ctx)),
 		nats.DrainTimeout(config.DrainTimeout),
 
@@ -51,66 +158,66 @@ func Connect(ctx context.Context, config Config, logger *slog.Logger) (*Client,
 		// set the maximum number of pending messages for a subscription.
 		nats.SyncQueueLen(config.SubscriptionPendingMessagesLimit),
 
-		nats.ErrorHandler(func(_ *nats.Conn, _ *nats.Subscription, err error) {
-			logger.Error("nats package error", "error", err)
-		}),
-
-		// Reports the error in case when the connection is disconnected.
-		// In that case subscription.IsValid() returns true until max reconnect
-		// and reconnect wait will be exceeded, and we have to report the error
-		// immediately in that handler.
-		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
-			if errors.Is(err, io.EOF) {
-				// a client losing the connection is not an unexpected error
+		nats.ErrorHandler(func(_ *nats.Conn, sub *nats.Subscription, err error) {
+			if errors.Is(err, nats.ErrSlowConsumer) && sub != nil {
+				c.reportSlowConsumer(sub)
 				return
 			}
-			logger.Error("nats package disconnect error", "error", err)
+			c.logger.Error("nats package error", "error", err)
 		}),
 	}
 
-	nc, err := nats.Connect(config.URL, natsOptions...)
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to nats: %w", err)
+	if config.NoRandomize {
+		natsOptions = append(natsOptions, nats.DontRandomize())
 	}
 
-	return &Client{
-		conn:           nc,
-		logger:         logger,
-		subCheckPeriod: config.SubCheckPeriod,
-	}, nil
+	natsOptions = append(natsOptions, authOptions...)
+	natsOptions = append(natsOptions, publishOptions...)
+	natsOptions = append(natsOptions, c.stateOptions()...)
+
+	return natsOptions, nil
 }
 
-// Close closes the NATS connection.
+// Close closes the NATS connection immediately, the pending publishes and the messages
+// not received yet are lost. Use Drain or Shutdown to stop gracefully.
 func (c *Client) Close() {
-	c.conn.Close()
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.conn.Close()
+		c.watchers.Wait()
+		c.metrics.remove(c)
+		c.closeSpill()
+	})
 }
 
-// Publish publishes a message to the NATS server.
-func (c *Client) Publish(ctx context.Context, topic string, payload []byte) error {
-	// Check if the connection still has connection to the NATS server.
-	// In that case connection is not connected to the NATS server but is not closed
-	// and tries to reconnect until reaching the max reconnect attempts.
-	// If not connected, then all messages will be saved in the buffer(default size 8 MB) and will be
-	// sent to the NATS server when the connection will be established.
-	// We don't want to return an error in that case, but we have to log it.
-	if !c.conn.IsConnected() {
-		return errors.New("nats is not connected")
+func (c *Client) closeSpill() {
+	if c.spill == nil {
+		return
 	}
 
-	if err := c.conn.Publish(topic, payload); err != nil {
-		if errors.Is(err, nats.ErrConnectionClosed) {
-			return ErrClosed
-		}
-
-		return fmt.Errorf("%w: %v", ErrPublishFailed, err)
+	if err := c.spill.close(); err != nil {
+		c.logger.Error("failed to close spill file", "error", err)
 	}
+}
 
-	return nil
+// Publish publishes a message to the NATS server.
+func (c *Client) Publish(ctx context.Context, topic string, payload []byte) error {
+	return c.PublishMsg(ctx, &Message{Subject: topic, Data: payload})
 }
 
 // Subscribe subscribes to the NATS server.
 func (c *Client) Subscribe(ctx context.Context, topic string) (*Subscription, error) {
-	natsSubscription, err := c.conn.SubscribeSync(topic)
+	return c.SubscribeWithOptions(ctx, topic, SubscribeOptions{})
+}
+
+// QueueSubscribe subscribes to the NATS server as a member of the queue group.
+// Every message is delivered to only one member of the group.
+func (c *Client) QueueSubscribe(ctx context.Context, topic, queue string) (*Subscription, error) {
+	return c.SubscribeWithOptions(ctx, topic, SubscribeOptions{Queue: queue})
+}
+
+func (c *Client) subscribeSync(topic, queue string) (*nats.Subscription, error) {
+	natsSubscription, err := c.conn.QueueSubscribeSync(topic, queue)
 	if err != nil {
 		if errors.Is(err, nats.ErrConnectionClosed) {
 			return nil, ErrClosed
@@ -118,35 +225,73 @@ func (c *Client) Subscribe(ctx context.Context, topic string) (*Subscription, er
 		return nil, fmt.Errorf("failed to subscribe: %w", err)
 	}
 
-	sub := Subscription{natsSubscription: natsSubscription}
+	return natsSubscription, nil
+}
+
+// watchSubscription removes the subscription when ctx is done or the client is closed.
+// Close waits for the watchers, so no goroutine outlives the client.
+func (c *Client) watchSubscription(ctx context.Context, subscription *nats.Subscription, onDone ...func()) {
+	c.subscriptions.Store(subscription, struct{}{})
+	chanClosed := subscriptionClosed(subscription)
 
-	go c.waitSubscriptionCancel(ctx, natsSubscription)
+	c.watchers.Add(1)
+	go func() {
+		defer c.watchers.Done()
 
-	return &sub, nil
+		_ = c.waitSubscriptionEnd(ctx, subscription, chanClosed)
+		c.subscriptions.Delete(subscription)
+
+		for _, fn := range onDone {
+			fn()
+		}
+	}()
 }
 
-func (c *Client) waitSubscriptionCancel(
+// waitSubscriptionEnd waits until the subscription is closed and returns the reason:
+// ctx error, ErrSubscriptionClosed or ErrClosed when the client is closed.
+// The subscription is unsubscribed when ctx is done.
+func (c *Client) waitSubscriptionEnd(
 	ctx context.Context,
 	subscription *nats.Subscription,
-) {
-	ticker := time.NewTicker(c.subCheckPeriod)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			err := subscription.Unsubscribe()
-			if err != nil &&
-				!errors.Is(err, nats.ErrConnectionClosed) &&
-				!errors.Is(err, nats.ErrConnectionDraining) {
-
-				c.logger.Error("failed to unsubscribe", "error", err)
-			}
-			return
-		case <-ticker.C:
-			if !subscription.IsValid() {
-				return
-			}
+	chanClosed <-chan struct{},
+) error {
+	select {
+	case <-ctx.Done():
+		err := subscription.Unsubscribe()
+		if err != nil &&
+			!errors.Is(err, nats.ErrConnectionClosed) &&
+			!errors.Is(err, nats.ErrConnectionDraining) &&
+			!errors.Is(err, nats.ErrBadSubscription) {
+
+			c.logger.Error("failed to unsubscribe", "error", err)
 		}
+		return ctx.Err()
+	case <-chanClosed:
+		if c.draining.Load() {
+			return ErrClosed
+		}
+		return ErrSubscriptionClosed
+	case <-c.done:
+		return ErrClosed
+	case <-c.connClosed:
+		return ErrClosed
 	}
 }
+
+// subscriptionClosed returns the channel closed when the subscription is unsubscribed or drained,
+// e.g. by the server. It is not closed when the connection is closed.
+func subscriptionClosed(subscription *nats.Subscription) <-chan struct{} {
+	chanClosed := make(chan struct{})
+
+	var once sync.Once
+	closeOnce := func() {
+		once.Do(func() { close(chanClosed) })
+	}
+
+	subscription.SetClosedHandler(func(string) { closeOnce() })
+	if !subscription.IsValid() {
+		closeOnce()
+	}
+
+	return chanClosed
+}
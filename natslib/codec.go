@@ -0,0 +1,119 @@
+package natslib
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+)
+
+type (
+	// Codec converts the values of TypedSubject and TypedEndpoint to the message payloads.
+	// Unmarshal gets a pointer to the value.
+	Codec interface {
+		Marshal(v any) ([]byte, error)
+		Unmarshal(data []byte, v any) error
+	}
+
+	// JSONCodec is the default codec.
+	JSONCodec struct{}
+
+	// ProtobufCodec encodes the protobuf messages, the value type must be a message pointer, e.g. *pb.Order.
+	ProtobufCodec struct{}
+
+	MsgpackCodec struct{}
+
+	// DecodeError is returned when a received payload can not be decoded.
+	// It matches ErrDecode, the subscription keeps receiving after it.
+	DecodeError struct {
+		// Message is the message which failed to decode.
+		Message Message
+		Err     error
+	}
+)
+
+var (
+	ErrEncode = errors.New("encoding failed")
+	ErrDecode = errors.New("decoding failed")
+
+	ErrNotProtoMessage = errors.New("value is not a protobuf message")
+)
+
+func (JSONCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSONCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+func (ProtobufCodec) Marshal(v any) ([]byte, error) {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T", ErrNotProtoMessage, v)
+	}
+
+	return proto.Marshal(msg)
+}
+
+func (ProtobufCodec) Unmarshal(data []byte, v any) error {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		// a pointer to the message pointer, e.g. **pb.Order, which is allocated if it is nil
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Pointer {
+			return fmt.Errorf("%w: %T", ErrNotProtoMessage, v)
+		}
+
+		if rv.Elem().IsNil() {
+			rv.Elem().Set(reflect.New(rv.Elem().Type().Elem()))
+		}
+
+		if msg, ok = rv.Elem().Interface().(proto.Message); !ok {
+			return fmt.Errorf("%w: %T", ErrNotProtoMessage, v)
+		}
+	}
+
+	return proto.Unmarshal(data, msg)
+}
+
+func (MsgpackCodec) Marshal(v any) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+func (MsgpackCodec) Unmarshal(data []byte, v any) error {
+	return msgpack.Unmarshal(data, v)
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("%s: message of %s: %s", ErrDecode, e.Message.Subject, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+func (e *DecodeError) Is(target error) bool {
+	return target == ErrDecode //nolint:errorlint,goerr113 // the sentinel itself
+}
+
+func encode(codec Codec, v any) ([]byte, error) {
+	data, err := codec.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrEncode, err)
+	}
+
+	return data, nil
+}
+
+func decode[T any](codec Codec, msg *Message) (T, error) {
+	var res T
+	if err := codec.Unmarshal(msg.Data, &res); err != nil {
+		return res, &DecodeError{Message: *msg, Err: err}
+	}
+
+	return res, nil
+}
@@ -0,0 +1,145 @@
+package natslib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// DrainReport counts the messages lost by the client.
+type DrainReport struct {
+	// Dropped is the number of messages the subscriptions dropped over their pending limits during the client lifetime.
+	Dropped int
+	// Unhandled is the number of received messages which were neither read by Receive nor passed to a Handle handler.
+	Unhandled int
+	// InFlight is the number of Handle handlers interrupted by the close.
+	InFlight int
+	// UnflushedBytes is the size of the publishes not sent to the server, e.g. buffered while disconnected.
+	UnflushedBytes int
+}
+
+// ErrDrainIncomplete is returned when the client is closed before the drain finished.
+var ErrDrainIncomplete = errors.New("drain incomplete")
+
+const drainPollInterval = 10 * time.Millisecond
+
+// Lost reports whether any message was lost.
+func (r DrainReport) Lost() bool {
+	return r.Dropped > 0 || r.Unhandled > 0 || r.InFlight > 0 || r.UnflushedBytes > 0
+}
+
+// Drain stops the subscriptions receiving new messages and waits until the received ones are handled:
+// the Subscribe messages are read by Receive and the Handle handlers return. Then it flushes the pending
+// publishes and closes the client.
+// Drain gives up after Config.DrainTimeout or when ctx is done, the client is closed anyway
+// and ErrDrainIncomplete is returned with the report of the lost messages.
+func (c *Client) Drain(ctx context.Context) (DrainReport, error) {
+	if c.conn.IsClosed() {
+		return DrainReport{}, ErrClosed
+	}
+	defer c.Close()
+
+	// the subscriptions closed by the drain are not re-created
+	c.draining.Store(true)
+
+	if c.drainTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.drainTimeout)
+		defer cancel()
+	}
+
+	var report DrainReport
+	subscriptions := c.drainSubscriptions(&report)
+
+	err := c.waitDrained(ctx, subscriptions)
+	if err == nil {
+		err = c.conn.FlushWithContext(ctx)
+	}
+	if err != nil {
+		report.Unhandled = pendingMessages(subscriptions)
+		report.InFlight = int(c.handling.Load())
+		report.UnflushedBytes, _ = c.conn.Buffered()
+
+		return report, fmt.Errorf("%w: %w", ErrDrainIncomplete, err)
+	}
+
+	return report, nil
+}
+
+// Shutdown drains the client and logs the lost messages.
+func (c *Client) Shutdown(ctx context.Context) error {
+	report, err := c.Drain(ctx)
+	if report.Lost() {
+		c.logger.Warn("nats client closed with lost messages",
+			"dropped", report.Dropped,
+			"unhandled", report.Unhandled,
+			"in_flight", report.InFlight,
+			"unflushed_bytes", report.UnflushedBytes,
+		)
+	}
+
+	return err
+}
+
+func (c *Client) drainSubscriptions(report *DrainReport) []*nats.Subscription {
+	var res []*nats.Subscription
+
+	c.subscriptions.Range(func(key, _ any) bool {
+		sub := key.(*nats.Subscription)
+
+		if dropped, err := sub.Dropped(); err == nil {
+			report.Dropped += dropped
+		}
+
+		// the subscriptions removed meanwhile are skipped
+		if err := sub.Drain(); err == nil {
+			res = append(res, sub)
+		}
+
+		return true
+	})
+
+	return res
+}
+
+// waitDrained waits until the subscriptions are drained and the handlers returned.
+func (c *Client) waitDrained(ctx context.Context, subscriptions []*nats.Subscription) error {
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if c.inFlight.Load() == 0 && !anyValid(subscriptions) {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func anyValid(subscriptions []*nats.Subscription) bool {
+	for _, sub := range subscriptions {
+		if sub.IsValid() {
+			return true
+		}
+	}
+
+	return false
+}
+
+func pendingMessages(subscriptions []*nats.Subscription) int {
+	res := 0
+	for _, sub := range subscriptions {
+		if pending, _, err := sub.Pending(); err == nil {
+			res += pending
+		}
+	}
+
+	return res
+}
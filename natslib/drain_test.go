@@ -0,0 +1,120 @@
+package natslib
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Drain(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	url := runTestServer(t)
+	client := connectTestClient(t, url)
+	publisher := connectTestClient(t, url)
+
+	var handled atomic.Int32
+	require.NoError(t, client.Handle(ctx, "jobs", func(context.Context, string, []byte) error {
+		time.Sleep(50 * time.Millisecond)
+		handled.Add(1)
+		return nil
+	}, HandleOptions{}))
+
+	sub, err := client.Subscribe(ctx, "events")
+	require.NoError(t, err)
+	require.NoError(t, client.Ready(ctx))
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, publisher.Publish(ctx, "jobs", []byte("job")))
+		require.NoError(t, publisher.Publish(ctx, "events", []byte("event")))
+	}
+	require.NoError(t, publisher.Ready(ctx))
+
+	// the received events are still read while draining
+	chanReceived := make(chan int)
+	go func() {
+		received := 0
+		for {
+			if _, err := sub.Receive(ctx); err != nil {
+				chanReceived <- received
+				return
+			}
+			received++
+		}
+	}()
+
+	report, err := client.Drain(ctx)
+	require.NoError(t, err)
+	require.Equal(t, DrainReport{}, report)
+	require.Equal(t, int32(5), handled.Load())
+	require.Equal(t, 5, <-chanReceived)
+
+	require.ErrorIs(t, client.Publish(ctx, "jobs", []byte("late")), ErrClosed)
+	_, err = client.Drain(ctx)
+	require.ErrorIs(t, err, ErrClosed)
+	requireNoSubscriptions(t, client)
+}
+
+func TestClient_Drain_Incomplete(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	url := runTestServer(t)
+	client := connectTestClient(t, url)
+	publisher := connectTestClient(t, url)
+
+	chanRelease := make(chan struct{})
+	defer close(chanRelease)
+
+	require.NoError(t, client.Handle(ctx, "jobs", func(context.Context, string, []byte) error {
+		<-chanRelease
+		return nil
+	}, HandleOptions{}))
+
+	// nobody reads the events
+	_, err := client.Subscribe(ctx, "events")
+	require.NoError(t, err)
+	require.NoError(t, client.Ready(ctx))
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, publisher.Publish(ctx, "jobs", []byte("job")))
+		require.NoError(t, publisher.Publish(ctx, "events", []byte("event")))
+	}
+	require.NoError(t, publisher.Ready(ctx))
+
+	require.Eventually(t, func() bool {
+		return client.inFlight.Load() == 2
+	}, time.Second, time.Millisecond)
+
+	drainCtx, drainCancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer drainCancel()
+
+	report, err := client.Drain(drainCtx)
+	require.ErrorIs(t, err, ErrDrainIncomplete)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.True(t, report.Lost())
+
+	// one job is handled, one waits for the worker and one is queued, all the events are queued
+	require.Equal(t, 1, report.InFlight)
+	require.Equal(t, 5, report.Unhandled)
+
+	require.ErrorIs(t, client.Publish(ctx, "jobs", []byte("late")), ErrClosed)
+	requireNoSubscriptions(t, client)
+}
+
+func requireNoSubscriptions(t *testing.T, client *Client) {
+	t.Helper()
+
+	client.subscriptions.Range(func(key, _ any) bool {
+		require.Fail(t, "subscription is not removed", key)
+		return false
+	})
+}
@@ -0,0 +1,103 @@
+package natslib
+
+import (
+	"context"
+	"sync"
+)
+
+type (
+	// Election elects one leader among the candidates of the same name.
+	// The leader keeps the lease of the name renewed, it is elected again only after
+	// the lease is resigned or expired.
+	Election struct {
+		lease *lease
+
+		mux        sync.Mutex
+		chanLeader chan bool
+	}
+
+	// Lock is a distributed lock of the name, it is held until Unlock or until its lease is lost.
+	Lock struct {
+		lease *lease
+	}
+)
+
+// NewElection returns the election of the name with the candidate ID, a random ID is used when it is empty.
+// A candidate restarted with the same ID takes its lease over without waiting for the expiry.
+func (c *Client) NewElection(ctx context.Context, config LeaseConfig, name, candidate string) (*Election, error) {
+	lease, err := c.newLease(ctx, config, name, candidate)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Election{
+		lease:      lease,
+		chanLeader: make(chan bool, 1),
+	}
+	lease.onChange = e.notify
+
+	return e, nil
+}
+
+// Campaign waits until the candidate is elected and returns the fencing token.
+// The token grows with every election, so the resources can reject the writes of a former leader.
+// After the leadership is lost, Campaign must be called again to take part in the next election.
+func (e *Election) Campaign(ctx context.Context) (uint64, error) {
+	token, _, err := e.lease.acquire(ctx)
+	return token, err
+}
+
+// Resign gives the leadership up, so another candidate is elected without waiting for the lease expiry.
+func (e *Election) Resign(ctx context.Context) error {
+	return e.lease.release(ctx)
+}
+
+// IsLeader reports the leadership changes: true when elected, false when resigned or lost.
+// Only the latest change is kept for a slow reader.
+func (e *Election) IsLeader() <-chan bool {
+	return e.chanLeader
+}
+
+// Token returns the fencing token of the current leadership, 0 when the candidate is not the leader.
+func (e *Election) Token() uint64 {
+	return e.lease.heldToken()
+}
+
+func (e *Election) notify(leader bool) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+
+	// replace the change not read yet
+	select {
+	case <-e.chanLeader:
+	default:
+	}
+
+	e.chanLeader <- leader
+}
+
+// NewLock returns the lock of the name with the owner ID, a random ID is used when it is empty.
+func (c *Client) NewLock(ctx context.Context, config LeaseConfig, name, owner string) (*Lock, error) {
+	lease, err := c.newLease(ctx, config, name, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Lock{lease: lease}, nil
+}
+
+// Lock waits until the lock is acquired and returns the fencing token and the channel
+// closed when the lock is lost or unlocked.
+func (l *Lock) Lock(ctx context.Context) (uint64, <-chan struct{}, error) {
+	return l.lease.acquire(ctx)
+}
+
+// TryLock acquires the lock if it is free, ok is false when it is held by others.
+func (l *Lock) TryLock(ctx context.Context) (token uint64, chanLost <-chan struct{}, ok bool, err error) {
+	return l.lease.tryAcquire(ctx)
+}
+
+// Unlock releases the lock, ErrLeaseNotHeld is returned when it is not held or already lost.
+func (l *Lock) Unlock(ctx context.Context) error {
+	return l.lease.release(ctx)
+}
@@ -0,0 +1,160 @@
+package natslib
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/stretchr/testify/require"
+)
+
+func TestElection(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	url := runTestServer(t)
+	config := LeaseConfig{TTL: time.Second, Storage: "memory"}
+
+	a, err := connectTestClient(t, url).NewElection(ctx, config, "scheduler", "a")
+	require.NoError(t, err)
+	b, err := connectTestClient(t, url).NewElection(ctx, config, "scheduler", "b")
+	require.NoError(t, err)
+
+	tokenA, err := a.Campaign(ctx)
+	require.NoError(t, err)
+	requireLeader(t, a, true)
+	require.Equal(t, tokenA, a.Token())
+
+	type campaignResult struct {
+		token uint64
+		err   error
+	}
+
+	// the result is asserted by the test goroutine, the channel is buffered to not block after the test
+	chanResB := make(chan campaignResult, 1)
+	go func() {
+		token, err := b.Campaign(ctx)
+		chanResB <- campaignResult{token: token, err: err}
+	}()
+
+	select {
+	case res := <-chanResB:
+		require.NoError(t, res.err)
+		require.Fail(t, "two leaders elected")
+	case <-time.After(2 * time.Second):
+	}
+
+	require.NoError(t, a.Resign(ctx))
+	requireLeader(t, a, false)
+	require.Zero(t, a.Token())
+	require.ErrorIs(t, a.Resign(ctx), ErrLeaseNotHeld)
+
+	select {
+	case res := <-chanResB:
+		require.NoError(t, res.err)
+		require.Greater(t, res.token, tokenA)
+		requireLeader(t, b, true)
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "no leader elected after resign")
+	}
+}
+
+func TestElection_ServerRestart(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	storeDir := t.TempDir()
+	srv := startTestServer(t, &server.Options{Port: server.RANDOM_PORT, JetStream: true, StoreDir: storeDir})
+	port := srv.Addr().(*net.TCPAddr).Port
+
+	config := LeaseConfig{TTL: time.Second}
+
+	a, err := connectRestartableClient(t, srv.ClientURL(), DisconnectedPublishConfig{}).
+		NewElection(ctx, config, "relay", "a")
+	require.NoError(t, err)
+	b, err := connectRestartableClient(t, srv.ClientURL(), DisconnectedPublishConfig{}).
+		NewElection(ctx, config, "relay", "b")
+	require.NoError(t, err)
+
+	tokenA, err := a.Campaign(ctx)
+	require.NoError(t, err)
+	requireLeader(t, a, true)
+
+	// the leader steps down when it can't renew the lease within the TTL
+	srv.Shutdown()
+	requireLeader(t, a, false)
+
+	startTestServer(t, &server.Options{Port: port, JetStream: true, StoreDir: storeDir})
+
+	tokenB, err := b.Campaign(ctx)
+	require.NoError(t, err)
+	require.Greater(t, tokenB, tokenA)
+	requireLeader(t, b, true)
+
+	campaignCtx, campaignCancel := context.WithTimeout(ctx, 2*time.Second)
+	defer campaignCancel()
+	_, err = a.Campaign(campaignCtx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestLock(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := connectTestClient(t, runTestServer(t))
+	config := LeaseConfig{TTL: time.Second, Storage: "memory"}
+
+	first, err := client.NewLock(ctx, config, "job", "")
+	require.NoError(t, err)
+	second, err := client.NewLock(ctx, config, "job", "")
+	require.NoError(t, err)
+
+	firstToken, firstLost, ok, err := first.TryLock(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	_, _, ok, err = second.TryLock(ctx)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	require.NoError(t, first.Unlock(ctx))
+	requireClosed(t, firstLost)
+
+	secondToken, secondLost, err := second.Lock(ctx)
+	require.NoError(t, err)
+	require.Greater(t, secondToken, firstToken)
+
+	// the lock changed by others is lost
+	require.NoError(t, second.lease.kv.Delete(ctx, "job"))
+	requireClosed(t, secondLost)
+	require.ErrorIs(t, second.Unlock(ctx), ErrLeaseNotHeld)
+}
+
+func requireLeader(t *testing.T, e *Election, leader bool) {
+	t.Helper()
+
+	select {
+	case got := <-e.IsLeader():
+		require.Equal(t, leader, got)
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "no leadership change")
+	}
+}
+
+func requireClosed(t *testing.T, ch <-chan struct{}) {
+	t.Helper()
+
+	select {
+	case <-ch:
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "channel is not closed")
+	}
+}
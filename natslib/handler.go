@@ -0,0 +1,150 @@
+package natslib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/nats-io/nats.go"
+)
+
+type (
+	// Handler handles a message of a Handle subscription.
+	// Errors are logged, core NATS has no redelivery.
+	Handler func(ctx context.Context, subject string, payload []byte) error
+
+	HandleOptions struct {
+		// Queue makes the subscription a member of the queue group, so every message is handled by one member only.
+		Queue string
+		// Workers is the number of messages handled concurrently, 1 by default.
+		Workers int
+
+		// PendingMessagesLimit and PendingBytesLimit limit the messages received but not handled yet.
+		// Messages over the limits are dropped and reported as a slow consumer.
+		// Config.SubscriptionPendingMessagesLimit and 64 MB are used by default.
+		PendingMessagesLimit int
+		PendingBytesLimit    int
+
+		// OnSlowConsumer is called when messages are dropped because of the pending limits.
+		// The dropped count is the total number of messages dropped by the subscription so far.
+		OnSlowConsumer func(subject string, dropped int)
+	}
+)
+
+const defaultPendingBytesLimit = 64 * 1024 * 1024
+
+// ErrHandlerPanic is logged when a handler panics.
+var ErrHandlerPanic = errors.New("handler panic")
+
+// Handle calls the handler for every message of the subject in a pool of Workers goroutines.
+// The subscription is removed when ctx is done, the handlers in progress get the cancelled ctx.
+func (c *Client) Handle(ctx context.Context, subject string, handler Handler, opts HandleOptions) error {
+	opts = c.handleDefaults(opts)
+
+	chanMessages := make(chan *nats.Msg)
+
+	natsSubscription, err := c.conn.QueueSubscribe(subject, opts.Queue, func(msg *nats.Msg) {
+		// counted before the callback returns, as the subscription is drained after that
+		c.inFlight.Add(1)
+
+		select {
+		case chanMessages <- msg:
+		case <-ctx.Done():
+			c.inFlight.Add(-1)
+		case <-c.done:
+			c.inFlight.Add(-1)
+		}
+	})
+	if err != nil {
+		if errors.Is(err, nats.ErrConnectionClosed) {
+			return ErrClosed
+		}
+		return fmt.Errorf("failed to subscribe: %w", err)
+	}
+
+	if err := natsSubscription.SetPendingLimits(opts.PendingMessagesLimit, opts.PendingBytesLimit); err != nil {
+		_ = natsSubscription.Unsubscribe()
+		return fmt.Errorf("failed to set pending limits: %w", err)
+	}
+
+	if opts.OnSlowConsumer != nil {
+		c.slowConsumerHandlers.Store(natsSubscription, opts.OnSlowConsumer)
+	}
+
+	for i := 0; i < opts.Workers; i++ {
+		go func() {
+			for {
+				select {
+				case msg := <-chanMessages:
+					c.handleMessage(ctx, handler, msg)
+				case <-ctx.Done():
+					return
+				case <-c.done:
+					return
+				}
+			}
+		}()
+	}
+
+	c.watchSubscription(ctx, natsSubscription, func() {
+		c.slowConsumerHandlers.Delete(natsSubscription)
+	})
+
+	return nil
+}
+
+func (c *Client) handleDefaults(opts HandleOptions) HandleOptions {
+	if opts.Workers <= 0 {
+		opts.Workers = 1
+	}
+
+	if opts.PendingMessagesLimit == 0 {
+		opts.PendingMessagesLimit = c.pendingMessagesLimit
+	}
+
+	if opts.PendingBytesLimit == 0 {
+		opts.PendingBytesLimit = defaultPendingBytesLimit
+	}
+
+	return opts
+}
+
+func (c *Client) handleMessage(ctx context.Context, handler Handler, msg *nats.Msg) {
+	defer func() {
+		if r := recover(); r != nil {
+			c.logger.Error("failed to handle message",
+				"subject", msg.Subject,
+				"error", fmt.Errorf("%w: %v", ErrHandlerPanic, r),
+				"stack", string(debug.Stack()),
+			)
+		}
+	}()
+
+	c.handling.Add(1)
+	defer func() {
+		c.handling.Add(-1)
+		c.inFlight.Add(-1)
+	}()
+
+	if err := handler(ctx, msg.Subject, msg.Data); err != nil {
+		c.logger.Error("failed to handle message", "subject", msg.Subject, "error", err)
+	}
+}
+
+// reportSlowConsumer is called by the nats error handler when the subscription drops messages.
+func (c *Client) reportSlowConsumer(sub *nats.Subscription) {
+	dropped, _ := sub.Dropped()
+	pending, _, _ := sub.Pending()
+
+	c.logger.Warn("slow consumer, messages dropped",
+		"subject", sub.Subject,
+		"queue", sub.Queue,
+		"pending", pending,
+		"dropped", dropped,
+	)
+
+	if h, ok := c.slowConsumerHandlers.Load(sub); ok {
+		h.(func(string, int))(sub.Subject, dropped)
+	}
+}
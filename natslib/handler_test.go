@@ -0,0 +1,132 @@
+package natslib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_QueueSubscribe(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := connectTestClient(t, runTestServer(t))
+
+	var subs []*Subscription
+	for i := 0; i < 2; i++ {
+		sub, err := client.QueueSubscribe(ctx, "jobs", "workers")
+		require.NoError(t, err)
+		subs = append(subs, sub)
+	}
+	require.NoError(t, client.conn.Flush())
+
+	const total = 20
+	for i := 0; i < total; i++ {
+		require.NoError(t, client.Publish(ctx, "jobs", []byte(fmt.Sprint(i))))
+	}
+
+	var received atomic.Int32
+	wg := &sync.WaitGroup{}
+	for _, sub := range subs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				receiveCtx, receiveCancel := context.WithTimeout(ctx, 200*time.Millisecond)
+				_, err := sub.Receive(receiveCtx)
+				receiveCancel()
+				if err != nil {
+					return
+				}
+				received.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.Equal(t, int32(total), received.Load())
+}
+
+func TestClient_Handle(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := connectTestClient(t, runTestServer(t))
+
+	var handled, inFlight, maxInFlight atomic.Int32
+	err := client.Handle(ctx, "jobs.*", func(_ context.Context, subject string, _ []byte) error {
+		if subject == "jobs.panic" {
+			panic("boom")
+		}
+
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			if m := maxInFlight.Load(); n <= m || maxInFlight.CompareAndSwap(m, n) {
+				break
+			}
+		}
+
+		time.Sleep(20 * time.Millisecond)
+		handled.Add(1)
+		return errors.New("logged")
+	}, HandleOptions{Workers: 3})
+	require.NoError(t, err)
+	require.NoError(t, client.conn.Flush())
+
+	require.NoError(t, client.Publish(ctx, "jobs.panic", nil))
+	for i := 0; i < 9; i++ {
+		require.NoError(t, client.Publish(ctx, "jobs.run", nil))
+	}
+
+	require.Eventually(t, func() bool { return handled.Load() == 9 }, 2*time.Second, 10*time.Millisecond)
+	require.Equal(t, int32(3), maxInFlight.Load())
+}
+
+func TestClient_Handle_SlowConsumer(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := connectTestClient(t, runTestServer(t))
+
+	chanRelease := make(chan struct{})
+	chanDropped := make(chan int, 100)
+
+	err := client.Handle(ctx, "jobs", func(context.Context, string, []byte) error {
+		<-chanRelease
+		return nil
+	}, HandleOptions{
+		PendingMessagesLimit: 1,
+		OnSlowConsumer: func(subject string, dropped int) {
+			require.Equal(t, "jobs", subject)
+			chanDropped <- dropped
+		},
+	})
+	require.NoError(t, err)
+	require.NoError(t, client.conn.Flush())
+
+	for i := 0; i < 10; i++ {
+		require.NoError(t, client.Publish(ctx, "jobs", nil))
+	}
+
+	select {
+	case dropped := <-chanDropped:
+		require.Positive(t, dropped)
+	case <-time.After(2 * time.Second):
+		require.Fail(t, "slow consumer is not reported")
+	}
+
+	close(chanRelease)
+}
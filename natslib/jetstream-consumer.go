@@ -0,0 +1,253 @@
+package natslib
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+type (
+	// JSMessage is a message received from a JetStream consumer.
+	// It must be acknowledged with Ack, Nak or Term, otherwise it is redelivered after the consumer AckWait.
+	JSMessage struct {
+		// Message.Reply is the subject the acknowledgements are sent to.
+		Message
+
+		// Sequence is the message sequence in the stream.
+		Sequence uint64
+		// NumDelivered is the number of delivery attempts, starting from 1.
+		NumDelivered uint64
+		Timestamp    time.Time
+
+		msg *nats.Msg
+	}
+
+	// PullConsumer fetches messages of a durable pull consumer.
+	PullConsumer struct {
+		natsSubscription *nats.Subscription
+	}
+
+	// PushConsumer receives messages of a durable push consumer.
+	PushConsumer struct {
+		natsSubscription *nats.Subscription
+	}
+
+	// DeadLetter is a message which reached the consumer MaxDeliver.
+	DeadLetter struct {
+		Stream       string
+		Consumer     string
+		Sequence     uint64
+		NumDelivered uint64
+
+		// Subject, Data and Header are empty when the message was already removed from the stream.
+		Subject string
+		Data    []byte
+		Header  nats.Header
+	}
+
+	// DeadLetterHandler handles a dead letter, errors are logged.
+	DeadLetterHandler func(ctx context.Context, msg *DeadLetter) error
+
+	maxDeliveriesAdvisory struct {
+		Stream     string `json:"stream"`
+		Consumer   string `json:"consumer"`
+		StreamSeq  uint64 `json:"stream_seq"`
+		Deliveries uint64 `json:"deliveries"`
+	}
+)
+
+const maxDeliveriesAdvisorySubject = "$JS.EVENT.ADVISORY.CONSUMER.MAX_DELIVERIES"
+
+// PullConsumer binds to the existing durable pull consumer of the stream.
+// The subscription is removed when ctx is done, the consumer itself is kept.
+func (c *Client) PullConsumer(ctx context.Context, stream, durable string) (*PullConsumer, error) {
+	info, err := c.js.ConsumerInfo(stream, durable, nats.Context(ctx))
+	if err != nil {
+		return nil, c.jsSubscribeError(stream, durable, err)
+	}
+
+	// the subject must match the consumer filter
+	natsSubscription, err := c.js.PullSubscribe(info.Config.FilterSubject, durable, nats.Bind(stream, durable))
+	if err != nil {
+		return nil, c.jsSubscribeError(stream, durable, err)
+	}
+
+	c.watchSubscription(ctx, natsSubscription)
+
+	return &PullConsumer{natsSubscription: natsSubscription}, nil
+}
+
+// Fetch waits for at least one message and returns up to batch messages.
+func (p *PullConsumer) Fetch(ctx context.Context, batch int) ([]*JSMessage, error) {
+	messages, err := p.natsSubscription.Fetch(batch, nats.Context(ctx))
+	if err != nil {
+		return nil, receiveError(err)
+	}
+
+	res := make([]*JSMessage, 0, len(messages))
+	for _, msg := range messages {
+		res = append(res, newJSMessage(msg))
+	}
+
+	return res, nil
+}
+
+// PushConsumer binds to the existing durable push consumer of the stream.
+// With the consumer DeliverGroup, the subscribers share the messages.
+// The subscription is removed when ctx is done, the consumer itself is kept.
+func (c *Client) PushConsumer(ctx context.Context, stream, durable string) (*PushConsumer, error) {
+	info, err := c.js.ConsumerInfo(stream, durable, nats.Context(ctx))
+	if err != nil {
+		return nil, c.jsSubscribeError(stream, durable, err)
+	}
+
+	opts := []nats.SubOpt{nats.Bind(stream, durable), nats.ManualAck()}
+
+	var natsSubscription *nats.Subscription
+	if group := info.Config.DeliverGroup; group != "" {
+		natsSubscription, err = c.js.QueueSubscribeSync("", group, opts...)
+	} else {
+		natsSubscription, err = c.js.SubscribeSync("", opts...)
+	}
+	if err != nil {
+		return nil, c.jsSubscribeError(stream, durable, err)
+	}
+
+	c.watchSubscription(ctx, natsSubscription)
+
+	return &PushConsumer{natsSubscription: natsSubscription}, nil
+}
+
+// Receive waits for the next message.
+func (p *PushConsumer) Receive(ctx context.Context) (*JSMessage, error) {
+	msg, err := p.natsSubscription.NextMsgWithContext(ctx)
+	if err != nil {
+		return nil, receiveError(err)
+	}
+
+	return newJSMessage(msg), nil
+}
+
+// Ack acknowledges the message and waits for the server to confirm it, so the message is not redelivered.
+func (m *JSMessage) Ack(ctx context.Context) error {
+	return ackError(m.msg.AckSync(nats.Context(ctx)))
+}
+
+// Nak asks for immediate redelivery of the message.
+func (m *JSMessage) Nak() error {
+	return ackError(m.msg.Nak())
+}
+
+// NakWithDelay asks for redelivery of the message after the delay.
+func (m *JSMessage) NakWithDelay(delay time.Duration) error {
+	return ackError(m.msg.NakWithDelay(delay))
+}
+
+// Term stops redelivery of the message, e.g. when it can never be processed.
+func (m *JSMessage) Term() error {
+	return ackError(m.msg.Term())
+}
+
+// InProgress resets the redelivery timer of the message, telling the server that processing takes longer.
+func (m *JSMessage) InProgress() error {
+	return ackError(m.msg.InProgress())
+}
+
+// HandleDeadLetters calls the handler for every message of the consumer which reached its MaxDeliver.
+// It relies on the server max deliveries advisories, so dead letters are not reported while the client is disconnected.
+// The handler is removed when ctx is done.
+func (c *Client) HandleDeadLetters(ctx context.Context, stream, consumer string, handler DeadLetterHandler) error {
+	subject := fmt.Sprintf("%s.%s.%s", maxDeliveriesAdvisorySubject, stream, consumer)
+
+	natsSubscription, err := c.conn.Subscribe(subject, func(msg *nats.Msg) {
+		deadLetter, err := c.deadLetter(ctx, msg.Data)
+		if err == nil {
+			err = handler(ctx, deadLetter)
+		}
+		if err != nil {
+			c.logger.Error("failed to handle dead letter", "stream", stream, "consumer", consumer, "error", err)
+		}
+	})
+	if err != nil {
+		if errors.Is(err, nats.ErrConnectionClosed) {
+			return ErrClosed
+		}
+		return fmt.Errorf("failed to subscribe to advisories: %w", err)
+	}
+
+	c.watchSubscription(ctx, natsSubscription)
+
+	return nil
+}
+
+func (c *Client) deadLetter(ctx context.Context, data []byte) (*DeadLetter, error) {
+	var advisory maxDeliveriesAdvisory
+	if err := json.Unmarshal(data, &advisory); err != nil {
+		return nil, fmt.Errorf("decoding advisory: %w", err)
+	}
+
+	res := &DeadLetter{
+		Stream:       advisory.Stream,
+		Consumer:     advisory.Consumer,
+		Sequence:     advisory.StreamSeq,
+		NumDelivered: advisory.Deliveries,
+	}
+
+	msg, err := c.js.GetMsg(advisory.Stream, advisory.StreamSeq, nats.Context(ctx))
+	switch {
+	case errors.Is(err, nats.ErrMsgNotFound):
+		return res, nil
+	case err != nil:
+		return nil, fmt.Errorf("getting message %d of stream %s: %w", advisory.StreamSeq, advisory.Stream, err)
+	}
+
+	res.Subject = msg.Subject
+	res.Data = msg.Data
+	res.Header = msg.Header
+
+	return res, nil
+}
+
+func (c *Client) jsSubscribeError(stream, durable string, err error) error {
+	switch {
+	case errors.Is(err, nats.ErrConnectionClosed):
+		return ErrClosed
+	case errors.Is(err, nats.ErrStreamNotFound):
+		return fmt.Errorf("%w: %s", ErrStreamNotFound, stream)
+	case errors.Is(err, nats.ErrConsumerNotFound):
+		return fmt.Errorf("%w: %s of stream %s", ErrConsumerNotFound, durable, stream)
+	default:
+		return fmt.Errorf("failed to subscribe to consumer %s of stream %s: %w", durable, stream, err)
+	}
+}
+
+func newJSMessage(msg *nats.Msg) *JSMessage {
+	res := &JSMessage{
+		Message: fromNatsMsg(msg),
+		msg:     msg,
+	}
+
+	if md, err := msg.Metadata(); err == nil {
+		res.Sequence = md.Sequence.Stream
+		res.NumDelivered = md.NumDelivered
+		res.Timestamp = md.Timestamp
+	}
+
+	return res
+}
+
+func ackError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, nats.ErrConnectionClosed) {
+		return ErrClosed
+	}
+
+	return fmt.Errorf("acknowledging message: %w", err)
+}
@@ -0,0 +1,260 @@
+package natslib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+type (
+	// StreamConfig describes a JetStream stream created or updated by Client.EnsureStream.
+	StreamConfig struct {
+		Name     string   `env:"NAME" json:"name" yaml:"name"`
+		Subjects []string `env:"SUBJECTS" envSeparator:"," json:"subjects" yaml:"subjects"`
+
+		// Storage is "file" (default) or "memory".
+		Storage string `env:"STORAGE" envDefault:"file" json:"storage" yaml:"storage" default:"file" validate:"oneof=file memory"`
+		// Retention is "limits" (default), "interest" or "workqueue".
+		Retention string `env:"RETENTION" envDefault:"limits" json:"retention" yaml:"retention" default:"limits" validate:"oneof=limits interest workqueue"`
+		Replicas  int    `env:"REPLICAS" envDefault:"1" json:"replicas" yaml:"replicas" default:"1"`
+
+		MaxAge   time.Duration `env:"MAX_AGE" json:"max_age" yaml:"max_age"`
+		MaxMsgs  int64         `env:"MAX_MSGS" json:"max_msgs" yaml:"max_msgs"`
+		MaxBytes int64         `env:"MAX_BYTES" json:"max_bytes" yaml:"max_bytes"`
+
+		// DuplicateWindow is how long published Msg-Ids are remembered for deduplication.
+		DuplicateWindow time.Duration `env:"DUPLICATE_WINDOW" envDefault:"2m" json:"duplicate_window" yaml:"duplicate_window" default:"2m"`
+	}
+
+	// ConsumerConfig describes a durable JetStream consumer created or updated by Client.EnsureConsumer.
+	ConsumerConfig struct {
+		Stream        string `env:"STREAM" json:"stream" yaml:"stream"`
+		Durable       string `env:"DURABLE" json:"durable" yaml:"durable"`
+		FilterSubject string `env:"FILTER_SUBJECT" json:"filter_subject" yaml:"filter_subject"`
+
+		// DeliverPolicy is "all" (default), "new" or "last".
+		DeliverPolicy string `env:"DELIVER_POLICY" envDefault:"all" json:"deliver_policy" yaml:"deliver_policy" default:"all" validate:"oneof=all new last"`
+
+		// DeliverSubject makes a push consumer, the consumer is a pull one when it is empty.
+		DeliverSubject string `env:"DELIVER_SUBJECT" json:"deliver_subject" yaml:"deliver_subject"`
+		// DeliverGroup is the queue group of the push consumer subscribers.
+		DeliverGroup string `env:"DELIVER_GROUP" json:"deliver_group" yaml:"deliver_group"`
+
+		AckWait time.Duration `env:"ACK_WAIT" envDefault:"30s" json:"ack_wait" yaml:"ack_wait" default:"30s"`
+		// MaxDeliver limits delivery attempts of a message, -1 for unlimited.
+		// Messages reaching the limit are reported to Client.HandleDeadLetters.
+		MaxDeliver    int             `env:"MAX_DELIVER" envDefault:"-1" json:"max_deliver" yaml:"max_deliver" default:"-1"`
+		MaxAckPending int             `env:"MAX_ACK_PENDING" envDefault:"1000" json:"max_ack_pending" yaml:"max_ack_pending" default:"1000"`
+		BackOff       []time.Duration `env:"BACKOFF" envSeparator:"," json:"backoff" yaml:"backoff"`
+	}
+
+	// PubAck is the stream acknowledgement of a published message.
+	PubAck struct {
+		Stream   string
+		Sequence uint64
+		// Duplicate is true when a message with the same Msg-Id was already stored.
+		Duplicate bool
+	}
+
+	PublishOption func(opts *[]nats.PubOpt)
+)
+
+var (
+	ErrStreamNotFound   = errors.New("stream not found")
+	ErrConsumerNotFound = errors.New("consumer not found")
+)
+
+// WithMsgID sets the Msg-Id the stream deduplicates messages by within its DuplicateWindow.
+func WithMsgID(id string) PublishOption {
+	return func(opts *[]nats.PubOpt) {
+		*opts = append(*opts, nats.MsgId(id))
+	}
+}
+
+// EnsureStream creates the stream or updates it to match the config.
+func (c *Client) EnsureStream(ctx context.Context, config StreamConfig) error {
+	streamConfig, err := config.natsConfig()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.js.StreamInfo(config.Name, nats.Context(ctx))
+	switch {
+	case errors.Is(err, nats.ErrStreamNotFound):
+		_, err = c.js.AddStream(streamConfig, nats.Context(ctx))
+	case err == nil:
+		_, err = c.js.UpdateStream(streamConfig, nats.Context(ctx))
+	}
+	if err != nil {
+		return fmt.Errorf("ensuring stream %s: %w", config.Name, err)
+	}
+
+	return nil
+}
+
+// updateStream applies the update to the config of the existing stream.
+func (c *Client) updateStream(ctx context.Context, name string, update func(*nats.StreamConfig)) error {
+	info, err := c.js.StreamInfo(name, nats.Context(ctx))
+	if err != nil {
+		return err
+	}
+
+	streamConfig := info.Config
+	update(&streamConfig)
+
+	_, err = c.js.UpdateStream(&streamConfig, nats.Context(ctx))
+
+	return err
+}
+
+// DeleteStream deletes the stream with all its messages and consumers.
+func (c *Client) DeleteStream(ctx context.Context, name string) error {
+	if err := c.js.DeleteStream(name, nats.Context(ctx)); err != nil {
+		if errors.Is(err, nats.ErrStreamNotFound) {
+			return fmt.Errorf("%w: %s", ErrStreamNotFound, name)
+		}
+		return fmt.Errorf("deleting stream %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// EnsureConsumer creates the durable consumer or updates it to match the config.
+func (c *Client) EnsureConsumer(ctx context.Context, config ConsumerConfig) error {
+	consumerConfig, err := config.natsConfig()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.js.ConsumerInfo(config.Stream, config.Durable, nats.Context(ctx))
+	switch {
+	case errors.Is(err, nats.ErrConsumerNotFound):
+		_, err = c.js.AddConsumer(config.Stream, consumerConfig, nats.Context(ctx))
+	case err == nil:
+		_, err = c.js.UpdateConsumer(config.Stream, consumerConfig, nats.Context(ctx))
+	}
+	if err != nil {
+		if errors.Is(err, nats.ErrStreamNotFound) {
+			return fmt.Errorf("%w: %s", ErrStreamNotFound, config.Stream)
+		}
+		return fmt.Errorf("ensuring consumer %s of stream %s: %w", config.Durable, config.Stream, err)
+	}
+
+	return nil
+}
+
+// PublishJS publishes the message to a stream and waits for the stream acknowledgement.
+func (c *Client) PublishJS(ctx context.Context, subject string, payload []byte, opts ...PublishOption) (PubAck, error) {
+	return c.PublishJSMsg(ctx, &Message{Subject: subject, Data: payload}, opts...)
+}
+
+// PublishJSMsg publishes the message with its headers to a stream and waits for the stream acknowledgement.
+func (c *Client) PublishJSMsg(ctx context.Context, msg *Message, opts ...PublishOption) (PubAck, error) {
+	pubOpts := []nats.PubOpt{nats.Context(ctx)}
+	for _, opt := range opts {
+		opt(&pubOpts)
+	}
+
+	natsMsg := toNatsMsg(msg)
+	// the options set headers, e.g. Nats-Msg-Id, the caller's ones are kept intact
+	natsMsg.Header = maps.Clone(msg.Header)
+
+	ack, err := c.js.PublishMsg(natsMsg, pubOpts...)
+	if err != nil {
+		if errors.Is(err, nats.ErrConnectionClosed) {
+			return PubAck{}, ErrClosed
+		}
+		return PubAck{}, fmt.Errorf("%w: %v", ErrPublishFailed, err)
+	}
+
+	return PubAck{
+		Stream:    ack.Stream,
+		Sequence:  ack.Sequence,
+		Duplicate: ack.Duplicate,
+	}, nil
+}
+
+func (c StreamConfig) natsConfig() (*nats.StreamConfig, error) {
+	res := &nats.StreamConfig{
+		Name:       c.Name,
+		Subjects:   c.Subjects,
+		Replicas:   c.Replicas,
+		MaxAge:     c.MaxAge,
+		MaxMsgs:    c.MaxMsgs,
+		MaxBytes:   c.MaxBytes,
+		Duplicates: c.DuplicateWindow,
+	}
+
+	if res.MaxMsgs == 0 {
+		res.MaxMsgs = -1
+	}
+	if res.MaxBytes == 0 {
+		res.MaxBytes = -1
+	}
+
+	storage, err := natsStorage(c.Storage)
+	if err != nil {
+		return nil, err
+	}
+	res.Storage = storage
+
+	switch c.Retention {
+	case "", "limits":
+		res.Retention = nats.LimitsPolicy
+	case "interest":
+		res.Retention = nats.InterestPolicy
+	case "workqueue":
+		res.Retention = nats.WorkQueuePolicy
+	default:
+		return nil, fmt.Errorf("unsupported stream retention %q", c.Retention)
+	}
+
+	return res, nil
+}
+
+func (c ConsumerConfig) natsConfig() (*nats.ConsumerConfig, error) {
+	res := &nats.ConsumerConfig{
+		Durable:        c.Durable,
+		FilterSubject:  c.FilterSubject,
+		DeliverSubject: c.DeliverSubject,
+		DeliverGroup:   c.DeliverGroup,
+		AckPolicy:      nats.AckExplicitPolicy,
+		AckWait:        c.AckWait,
+		MaxDeliver:     c.MaxDeliver,
+		MaxAckPending:  c.MaxAckPending,
+		BackOff:        c.BackOff,
+	}
+
+	if res.MaxDeliver == 0 {
+		res.MaxDeliver = -1
+	}
+
+	switch c.DeliverPolicy {
+	case "", "all":
+		res.DeliverPolicy = nats.DeliverAllPolicy
+	case "new":
+		res.DeliverPolicy = nats.DeliverNewPolicy
+	case "last":
+		res.DeliverPolicy = nats.DeliverLastPolicy
+	default:
+		return nil, fmt.Errorf("unsupported consumer deliver policy %q", c.DeliverPolicy)
+	}
+
+	return res, nil
+}
+
+// natsStorage maps the storage of StreamConfig, KVConfig and ObjectStoreConfig.
+func natsStorage(storage string) (nats.StorageType, error) {
+	switch storage {
+	case "", "file":
+		return nats.FileStorage, nil
+	case "memory":
+		return nats.MemoryStorage, nil
+	default:
+		return 0, fmt.Errorf("unsupported storage %q", storage)
+	}
+}
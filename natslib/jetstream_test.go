@@ -0,0 +1,198 @@
+package natslib
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_EnsureStream(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	client := connectTestClient(t, runTestServer(t))
+
+	config := StreamConfig{Name: "ORDERS", Subjects: []string{"orders.>"}, Storage: "memory"}
+	require.NoError(t, client.EnsureStream(ctx, config))
+
+	config.Subjects = append(config.Subjects, "refunds.>")
+	require.NoError(t, client.EnsureStream(ctx, config))
+
+	info, err := client.js.StreamInfo("ORDERS")
+	require.NoError(t, err)
+	require.Equal(t, []string{"orders.>", "refunds.>"}, info.Config.Subjects)
+
+	require.ErrorIs(t, client.EnsureConsumer(ctx, ConsumerConfig{Stream: "MISSING", Durable: "c"}), ErrStreamNotFound)
+
+	require.NoError(t, client.DeleteStream(ctx, "ORDERS"))
+	require.ErrorIs(t, client.DeleteStream(ctx, "ORDERS"), ErrStreamNotFound)
+}
+
+func TestClient_PublishJS(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	client := connectTestClient(t, runTestServer(t))
+
+	require.NoError(t, client.EnsureStream(ctx, StreamConfig{Name: "ORDERS", Subjects: []string{"orders.>"}}))
+
+	ack, err := client.PublishJS(ctx, "orders.new", []byte("1"), WithMsgID("order-1"))
+	require.NoError(t, err)
+	require.Equal(t, PubAck{Stream: "ORDERS", Sequence: 1}, ack)
+
+	ack, err = client.PublishJS(ctx, "orders.new", []byte("1"), WithMsgID("order-1"))
+	require.NoError(t, err)
+	require.True(t, ack.Duplicate)
+
+	_, err = client.PublishJS(ctx, "unknown", []byte("1"))
+	require.ErrorIs(t, err, ErrPublishFailed)
+}
+
+func TestPullConsumer(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := connectTestClient(t, runTestServer(t))
+	require.NoError(t, client.EnsureStream(ctx, StreamConfig{Name: "ORDERS", Subjects: []string{"orders.>"}}))
+	require.NoError(t, client.EnsureConsumer(ctx, ConsumerConfig{Stream: "ORDERS", Durable: "worker"}))
+
+	consumer, err := client.PullConsumer(ctx, "ORDERS", "worker")
+	require.NoError(t, err)
+
+	for _, payload := range []string{"1", "2"} {
+		_, err := client.PublishJS(ctx, "orders.new", []byte(payload))
+		require.NoError(t, err)
+	}
+
+	messages, err := consumer.Fetch(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, messages, 2)
+	require.Equal(t, uint64(1), messages[0].Sequence)
+
+	require.NoError(t, messages[0].NakWithDelay(100*time.Millisecond))
+	require.NoError(t, messages[1].Term())
+
+	start := time.Now()
+	messages, err = consumer.Fetch(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	require.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	require.Equal(t, "1", string(messages[0].Data))
+	require.Equal(t, uint64(2), messages[0].NumDelivered)
+
+	require.NoError(t, messages[0].InProgress())
+	require.NoError(t, messages[0].Ack(ctx))
+
+	fetchCtx, fetchCancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer fetchCancel()
+	_, err = consumer.Fetch(fetchCtx, 10)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	_, err = client.PullConsumer(ctx, "ORDERS", "missing")
+	require.ErrorIs(t, err, ErrConsumerNotFound)
+}
+
+func TestPushConsumer_DeadLetters(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := connectTestClient(t, runTestServer(t))
+	require.NoError(t, client.EnsureStream(ctx, StreamConfig{Name: "ORDERS", Subjects: []string{"orders.>"}}))
+	require.NoError(t, client.EnsureConsumer(ctx, ConsumerConfig{
+		Stream:         "ORDERS",
+		Durable:        "pusher",
+		DeliverSubject: "deliver.orders",
+		DeliverGroup:   "workers",
+		MaxDeliver:     2,
+	}))
+
+	chanDeadLetters := make(chan *DeadLetter, 1)
+	require.NoError(t, client.HandleDeadLetters(ctx, "ORDERS", "pusher", func(_ context.Context, msg *DeadLetter) error {
+		chanDeadLetters <- msg
+		return nil
+	}))
+
+	consumer, err := client.PushConsumer(ctx, "ORDERS", "pusher")
+	require.NoError(t, err)
+
+	_, err = client.PublishJS(ctx, "orders.bad", []byte("poison"))
+	require.NoError(t, err)
+
+	for i := 1; i <= 2; i++ {
+		msg, err := consumer.Receive(ctx)
+		require.NoError(t, err)
+		require.Equal(t, uint64(i), msg.NumDelivered)
+		require.NoError(t, msg.Nak())
+	}
+
+	select {
+	case deadLetter := <-chanDeadLetters:
+		require.Equal(t, &DeadLetter{
+			Stream:       "ORDERS",
+			Consumer:     "pusher",
+			Sequence:     1,
+			NumDelivered: 2,
+			Subject:      "orders.bad",
+			Data:         []byte("poison"),
+		}, deadLetter)
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "no dead letter")
+	}
+}
+
+func runTestServer(t *testing.T) string {
+	t.Helper()
+
+	return runTestServerWithOptions(t, &server.Options{JetStream: true, StoreDir: t.TempDir()})
+}
+
+func runTestServerWithOptions(t *testing.T, opts *server.Options) string {
+	t.Helper()
+
+	opts.Port = server.RANDOM_PORT
+
+	return startTestServer(t, opts).ClientURL()
+}
+
+// startTestServer starts the server on the opts.Port, so that it can be restarted on the same port.
+func startTestServer(t *testing.T, opts *server.Options) *server.Server {
+	t.Helper()
+
+	opts.Host = "127.0.0.1"
+	opts.NoLog = true
+	opts.NoSigs = true
+
+	srv, err := server.NewServer(opts)
+	require.NoError(t, err)
+
+	srv.Start()
+	t.Cleanup(srv.Shutdown)
+
+	require.True(t, srv.ReadyForConnections(5*time.Second))
+
+	return srv
+}
+
+func connectTestClient(t *testing.T, url string) *Client {
+	t.Helper()
+
+	client, err := Connect(context.Background(), Config{
+		URL:                              url,
+		DrainTimeout:                     time.Second,
+		SubCheckPeriod:                   time.Second,
+		ReconnectWait:                    100 * time.Millisecond,
+		SubscriptionPendingMessagesLimit: 1000,
+	}, slog.Default())
+	require.NoError(t, err)
+	t.Cleanup(client.Close)
+
+	return client
+}
@@ -0,0 +1,344 @@
+package natslib
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+type (
+	// KVConfig describes a JetStream Key-Value bucket created or updated by EnsureKV.
+	KVConfig struct {
+		Bucket      string `env:"BUCKET" json:"bucket" yaml:"bucket"`
+		Description string `env:"DESCRIPTION" json:"description" yaml:"description"`
+
+		// History is the number of revisions kept per key, 64 at most.
+		History uint8 `env:"HISTORY" envDefault:"1" json:"history" yaml:"history" default:"1"`
+		// TTL removes the keys not updated for the duration, the keys are kept forever when 0.
+		TTL      time.Duration `env:"TTL" json:"ttl" yaml:"ttl"`
+		MaxBytes int64         `env:"MAX_BYTES" json:"max_bytes" yaml:"max_bytes"`
+
+		// Storage is "file" (default) or "memory".
+		Storage  string `env:"STORAGE" envDefault:"file" json:"storage" yaml:"storage" default:"file"`
+		Replicas int    `env:"REPLICAS" envDefault:"1" json:"replicas" yaml:"replicas" default:"1"`
+	}
+
+	// KV is a Key-Value bucket with the values of type T stored as JSON.
+	// Get, Put, Create and Update are made on the stream of the bucket, since nats.KeyValue takes no context
+	// for them and the lease renewal must not outlive its TTL. Delete is bounded by the JetStream timeout.
+	KV[T any] struct {
+		kv     nats.KeyValue
+		js     nats.JetStreamContext
+		stream string
+		// prefix is the subject prefix of the keys
+		prefix string
+		logger *slog.Logger
+	}
+
+	KVEntry[T any] struct {
+		Key   string
+		Value T
+		// Revision is the revision to pass to KV.Update to replace this entry.
+		Revision uint64
+		Created  time.Time
+		// Deleted is true for the delete markers of History and Watch, Value is empty then.
+		Deleted bool
+	}
+)
+
+const (
+	// kvStreamPrefix is the prefix of the streams of the buckets.
+	kvStreamPrefix = "KV_"
+	// kvOperationHeader marks the deleted and purged keys, the values have no operation.
+	kvOperationHeader = "KV-Operation"
+)
+
+var (
+	ErrBucketNotFound = errors.New("bucket not found")
+	ErrKeyNotFound    = errors.New("key not found")
+	ErrKeyExists      = errors.New("key exists")
+	// ErrRevisionMismatch is returned by KV.Update when the key was changed after the given revision.
+	ErrRevisionMismatch = errors.New("revision mismatch")
+)
+
+// EnsureKV creates the bucket or updates it to match the config.
+func EnsureKV[T any](ctx context.Context, c *Client, config KVConfig) (*KV[T], error) {
+	storage, err := natsStorage(config.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	kv, err := c.js.CreateKeyValue(&nats.KeyValueConfig{
+		Bucket:      config.Bucket,
+		Description: config.Description,
+		History:     config.History,
+		TTL:         config.TTL,
+		MaxBytes:    config.MaxBytes,
+		Storage:     storage,
+		Replicas:    config.Replicas,
+	})
+	if errors.Is(err, nats.ErrStreamNameAlreadyInUse) {
+		// the bucket exists with another config, its stream is updated the way CreateKeyValue makes it
+		err = c.updateStream(ctx, kvStreamPrefix+config.Bucket, func(streamConfig *nats.StreamConfig) {
+			streamConfig.Description = config.Description
+			streamConfig.MaxMsgsPerSubject = int64(max(config.History, 1))
+			streamConfig.MaxAge = config.TTL
+			streamConfig.MaxBytes = config.MaxBytes
+			if streamConfig.MaxBytes == 0 {
+				streamConfig.MaxBytes = -1
+			}
+			streamConfig.Duplicates = 2 * time.Minute
+			if config.TTL > 0 && config.TTL < streamConfig.Duplicates {
+				streamConfig.Duplicates = config.TTL
+			}
+			streamConfig.Storage = storage
+			streamConfig.Replicas = max(config.Replicas, 1)
+		})
+		if err == nil {
+			kv, err = c.js.KeyValue(config.Bucket)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ensuring bucket %s: %w", config.Bucket, kvError(err))
+	}
+
+	return &KV[T]{
+		kv:     kv,
+		js:     c.js,
+		stream: kvStreamPrefix + config.Bucket,
+		prefix: "$KV." + config.Bucket + ".",
+		logger: c.logger.With("bucket", config.Bucket),
+	}, nil
+}
+
+// DeleteKV deletes the bucket with all its keys.
+func (c *Client) DeleteKV(ctx context.Context, bucket string) error {
+	if err := c.js.DeleteStream(kvStreamPrefix+bucket, nats.Context(ctx)); err != nil {
+		return fmt.Errorf("deleting bucket %s: %w", bucket, kvError(err))
+	}
+
+	return nil
+}
+
+// Get returns the last revision of the key, ErrKeyNotFound when the key does not exist or is deleted.
+func (kv *KV[T]) Get(ctx context.Context, key string) (KVEntry[T], error) {
+	msg, err := kv.js.GetLastMsg(kv.stream, kv.prefix+key, nats.Context(ctx))
+	if err == nil && isDeleteMarker(msg) {
+		err = nats.ErrKeyDeleted
+	}
+	if err != nil {
+		return KVEntry[T]{}, fmt.Errorf("getting key %s: %w", key, kvError(err))
+	}
+
+	return decodeKVEntry(KVEntry[T]{Key: key, Revision: msg.Sequence, Created: msg.Time}, msg.Data)
+}
+
+// Put sets the value of the key and returns the new revision.
+func (kv *KV[T]) Put(ctx context.Context, key string, value T) (uint64, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return 0, fmt.Errorf("encoding value of key %s: %w", key, err)
+	}
+
+	ack, err := kv.js.PublishMsg(&nats.Msg{Subject: kv.prefix + key, Data: data}, nats.Context(ctx))
+	if err != nil {
+		return 0, fmt.Errorf("putting key %s: %w", key, kvError(err))
+	}
+
+	return ack.Sequence, nil
+}
+
+// Create sets the value of the key only if it does not exist or is deleted, ErrKeyExists otherwise.
+func (kv *KV[T]) Create(ctx context.Context, key string, value T) (uint64, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return 0, fmt.Errorf("encoding value of key %s: %w", key, err)
+	}
+
+	revision, err := kv.update(ctx, key, data, 0)
+	if isWrongLastSequence(err) {
+		// the delete marker is replaced like a missing key
+		msg, getErr := kv.js.GetLastMsg(kv.stream, kv.prefix+key, nats.Context(ctx))
+		if getErr == nil && isDeleteMarker(msg) {
+			revision, err = kv.update(ctx, key, data, msg.Sequence)
+		}
+	}
+	if err != nil {
+		if isWrongLastSequence(err) {
+			return 0, fmt.Errorf("%w: %s", ErrKeyExists, key)
+		}
+		return 0, fmt.Errorf("creating key %s: %w", key, kvError(err))
+	}
+
+	return revision, nil
+}
+
+// Update sets the value of the key only if its last revision is the given one, ErrRevisionMismatch otherwise.
+func (kv *KV[T]) Update(ctx context.Context, key string, value T, revision uint64) (uint64, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return 0, fmt.Errorf("encoding value of key %s: %w", key, err)
+	}
+
+	revision, err = kv.update(ctx, key, data, revision)
+	if err != nil {
+		if isWrongLastSequence(err) {
+			return 0, fmt.Errorf("%w: %s", ErrRevisionMismatch, key)
+		}
+		return 0, fmt.Errorf("updating key %s: %w", key, kvError(err))
+	}
+
+	return revision, nil
+}
+
+// update publishes the value if the last revision of the key is the given one, 0 for a missing key.
+func (kv *KV[T]) update(ctx context.Context, key string, data []byte, revision uint64) (uint64, error) {
+	msg := nats.NewMsg(kv.prefix + key)
+	msg.Data = data
+	msg.Header.Set(nats.ExpectedLastSubjSeqHdr, strconv.FormatUint(revision, 10))
+
+	ack, err := kv.js.PublishMsg(msg, nats.Context(ctx))
+	if err != nil {
+		return 0, err
+	}
+
+	return ack.Sequence, nil
+}
+
+// Delete deletes the key, its history is kept until the delete marker is purged.
+func (kv *KV[T]) Delete(_ context.Context, key string) error {
+	if err := kv.kv.Delete(key); err != nil {
+		return fmt.Errorf("deleting key %s: %w", key, kvError(err))
+	}
+
+	return nil
+}
+
+// History returns up to KVConfig.History revisions of the key including the delete markers, the oldest first.
+func (kv *KV[T]) History(ctx context.Context, key string) ([]KVEntry[T], error) {
+	entries, err := kv.kv.History(key, nats.Context(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("getting history of key %s: %w", key, kvError(err))
+	}
+
+	res := make([]KVEntry[T], 0, len(entries))
+	for _, entry := range entries {
+		e, err := newKVEntry[T](entry)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, e)
+	}
+
+	return res, nil
+}
+
+// Keys returns the keys of the bucket, the deleted ones are skipped.
+func (kv *KV[T]) Keys(ctx context.Context) ([]string, error) {
+	keys, err := kv.kv.Keys(nats.Context(ctx))
+	if err != nil {
+		if errors.Is(err, nats.ErrNoKeysFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("listing keys: %w", kvError(err))
+	}
+
+	return keys, nil
+}
+
+// Watch sends the last revisions of the keys matching the pattern, e.g. "orders.*" or ">" for all,
+// and then their updates including the delete markers.
+// The channel is closed when ctx is done. The values which can't be decoded are logged and skipped.
+func (kv *KV[T]) Watch(ctx context.Context, pattern string) (<-chan KVEntry[T], error) {
+	watcher, err := kv.kv.Watch(pattern, nats.Context(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("watching keys %s: %w", pattern, kvError(err))
+	}
+
+	chanEntries := make(chan KVEntry[T])
+
+	go func() {
+		defer close(chanEntries)
+		defer func() {
+			_ = watcher.Stop()
+		}()
+
+		for {
+			select {
+			case entry, ok := <-watcher.Updates():
+				if !ok {
+					return
+				}
+				// nil marks the end of the initial values
+				if entry == nil {
+					continue
+				}
+
+				e, err := newKVEntry[T](entry)
+				if err != nil {
+					kv.logger.Error("failed to watch key", "key", entry.Key(), "error", err)
+					continue
+				}
+
+				select {
+				case chanEntries <- e:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return chanEntries, nil
+}
+
+func newKVEntry[T any](entry nats.KeyValueEntry) (KVEntry[T], error) {
+	return decodeKVEntry(KVEntry[T]{
+		Key:      entry.Key(),
+		Revision: entry.Revision(),
+		Created:  entry.Created(),
+		Deleted:  entry.Operation() != nats.KeyValuePut,
+	}, entry.Value())
+}
+
+// decodeKVEntry sets the value of the entry unless it is a delete marker.
+func decodeKVEntry[T any](entry KVEntry[T], data []byte) (KVEntry[T], error) {
+	if !entry.Deleted {
+		if err := json.Unmarshal(data, &entry.Value); err != nil {
+			return KVEntry[T]{}, fmt.Errorf("decoding value of key %s: %w", entry.Key, err)
+		}
+	}
+
+	return entry, nil
+}
+
+// isDeleteMarker reports whether the message of the key is a delete or purge marker.
+func isDeleteMarker(msg *nats.RawStreamMsg) bool {
+	return msg.Header.Get(kvOperationHeader) != ""
+}
+
+func kvError(err error) error {
+	switch {
+	case errors.Is(err, nats.ErrConnectionClosed):
+		return ErrClosed
+	case errors.Is(err, nats.ErrBucketNotFound), errors.Is(err, nats.ErrStreamNotFound):
+		return ErrBucketNotFound
+	case errors.Is(err, nats.ErrKeyNotFound), errors.Is(err, nats.ErrKeyDeleted), errors.Is(err, nats.ErrMsgNotFound):
+		return ErrKeyNotFound
+	default:
+		return err
+	}
+}
+
+func isWrongLastSequence(err error) bool {
+	var apiErr *nats.APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorCode == nats.JSErrCodeStreamWrongLastSequence
+}
@@ -0,0 +1,143 @@
+package natslib
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testSettings struct {
+	Limit   int    `json:"limit"`
+	Enabled bool   `json:"enabled"`
+	Owner   string `json:"owner"`
+}
+
+func TestKV(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	client := connectTestClient(t, runTestServer(t))
+
+	kv, err := EnsureKV[testSettings](ctx, client, KVConfig{Bucket: "settings", History: 5, Storage: "memory"})
+	require.NoError(t, err)
+
+	_, err = kv.Get(ctx, "service.a")
+	require.ErrorIs(t, err, ErrKeyNotFound)
+
+	revision, err := kv.Create(ctx, "service.a", testSettings{Limit: 1})
+	require.NoError(t, err)
+
+	_, err = kv.Create(ctx, "service.a", testSettings{Limit: 2})
+	require.ErrorIs(t, err, ErrKeyExists)
+
+	updated, err := kv.Update(ctx, "service.a", testSettings{Limit: 2, Enabled: true}, revision)
+	require.NoError(t, err)
+
+	_, err = kv.Update(ctx, "service.a", testSettings{Limit: 3}, revision)
+	require.ErrorIs(t, err, ErrRevisionMismatch)
+
+	entry, err := kv.Get(ctx, "service.a")
+	require.NoError(t, err)
+	require.Equal(t, testSettings{Limit: 2, Enabled: true}, entry.Value)
+	require.Equal(t, updated, entry.Revision)
+
+	_, err = kv.Put(ctx, "service.b", testSettings{Owner: "team"})
+	require.NoError(t, err)
+
+	keys, err := kv.Keys(ctx)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"service.a", "service.b"}, keys)
+
+	require.NoError(t, kv.Delete(ctx, "service.a"))
+	_, err = kv.Get(ctx, "service.a")
+	require.ErrorIs(t, err, ErrKeyNotFound)
+
+	history, err := kv.History(ctx, "service.a")
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+	require.Equal(t, testSettings{Limit: 1}, history[0].Value)
+	require.True(t, history[2].Deleted)
+
+	// a deleted key can be created again
+	_, err = kv.Create(ctx, "service.a", testSettings{Limit: 4})
+	require.NoError(t, err)
+
+	// the existing bucket is updated
+	kv, err = EnsureKV[testSettings](ctx, client, KVConfig{Bucket: "settings", History: 2, Storage: "memory"})
+	require.NoError(t, err)
+	history, err = kv.History(ctx, "service.a")
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+
+	require.NoError(t, client.DeleteKV(ctx, "settings"))
+	require.ErrorIs(t, client.DeleteKV(ctx, "settings"), ErrBucketNotFound)
+}
+
+func TestKV_TTL(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	client := connectTestClient(t, runTestServer(t))
+
+	kv, err := EnsureKV[string](ctx, client, KVConfig{Bucket: "sessions", TTL: time.Second, Storage: "memory"})
+	require.NoError(t, err)
+
+	_, err = kv.Put(ctx, "user1", "token")
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		_, err := kv.Get(ctx, "user1")
+		return err != nil
+	}, 5*time.Second, 100*time.Millisecond)
+}
+
+func TestKV_Watch(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := connectTestClient(t, runTestServer(t))
+
+	kv, err := EnsureKV[testSettings](ctx, client, KVConfig{Bucket: "settings", Storage: "memory"})
+	require.NoError(t, err)
+
+	_, err = kv.Put(ctx, "service.a", testSettings{Limit: 1})
+	require.NoError(t, err)
+
+	watchCtx, watchCancel := context.WithCancel(ctx)
+	chanEntries, err := kv.Watch(watchCtx, "service.*")
+	require.NoError(t, err)
+
+	requireEntry := func(key string, value testSettings, deleted bool) {
+		t.Helper()
+
+		select {
+		case entry := <-chanEntries:
+			require.Equal(t, key, entry.Key)
+			require.Equal(t, value, entry.Value)
+			require.Equal(t, deleted, entry.Deleted)
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "no update", key)
+		}
+	}
+
+	requireEntry("service.a", testSettings{Limit: 1}, false)
+
+	_, err = kv.Put(ctx, "service.b", testSettings{Limit: 2})
+	require.NoError(t, err)
+	_, err = kv.Put(ctx, "other", testSettings{Limit: 3})
+	require.NoError(t, err)
+	require.NoError(t, kv.Delete(ctx, "service.a"))
+
+	requireEntry("service.b", testSettings{Limit: 2}, false)
+	requireEntry("service.a", testSettings{}, true)
+
+	watchCancel()
+	require.Eventually(t, func() bool {
+		_, ok := <-chanEntries
+		return !ok
+	}, time.Second, 10*time.Millisecond)
+}
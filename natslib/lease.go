@@ -0,0 +1,300 @@
+package natslib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nuid"
+)
+
+type (
+	// LeaseConfig describes the KV bucket the leases of Election and Lock are kept in.
+	// All the leases of the bucket share the TTL.
+	LeaseConfig struct {
+		Bucket string `env:"BUCKET" envDefault:"leases" json:"bucket" yaml:"bucket" default:"leases"`
+
+		// TTL is how long the lease is valid without renewal. The holder considers the lease lost
+		// when it could not renew it within the TTL, so it stops acting before anybody else takes it over.
+		TTL time.Duration `env:"TTL" envDefault:"10s" json:"ttl" yaml:"ttl" default:"10s"`
+		// RenewInterval is how often the holder renews the lease, TTL/3 by default.
+		RenewInterval time.Duration `env:"RENEW_INTERVAL" json:"renew_interval" yaml:"renew_interval"`
+		// RetryInterval is how often the lease is tried to be acquired while it is held by others, TTL/3 by default.
+		RetryInterval time.Duration `env:"RETRY_INTERVAL" json:"retry_interval" yaml:"retry_interval"`
+
+		// Storage is "file" (default) or "memory".
+		Storage  string `env:"STORAGE" envDefault:"file" json:"storage" yaml:"storage" default:"file"`
+		Replicas int    `env:"REPLICAS" envDefault:"1" json:"replicas" yaml:"replicas" default:"1"`
+	}
+
+	// lease is a KV key held by one holder at a time. The key expires by the bucket TTL
+	// unless the holder renews it with the revision CAS.
+	lease struct {
+		kv     *KV[leaseValue]
+		key    string
+		holder string
+		config LeaseConfig
+		logger *slog.Logger
+
+		// onChange is called when the lease is acquired or lost
+		onChange func(held bool)
+
+		mux sync.Mutex
+		// token is the revision the lease was acquired with, 0 when the lease is not held
+		token    uint64
+		revision uint64
+		// chanLost is closed when the lease is lost or released
+		chanLost    chan struct{}
+		cancelRenew context.CancelFunc
+		renewDone   chan struct{}
+	}
+
+	leaseValue struct {
+		Holder string `json:"holder"`
+	}
+)
+
+// ErrLeaseNotHeld is returned when the lease to release is not held.
+var ErrLeaseNotHeld = errors.New("lease is not held")
+
+func (c *Client) newLease(ctx context.Context, config LeaseConfig, key, holder string) (*lease, error) {
+	config = config.withDefaults()
+
+	kv, err := EnsureKV[leaseValue](ctx, c, KVConfig{
+		Bucket:   config.Bucket,
+		History:  1,
+		TTL:      config.TTL,
+		Storage:  config.Storage,
+		Replicas: config.Replicas,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if holder == "" {
+		holder = nuid.Next()
+	}
+
+	return &lease{
+		kv:     kv,
+		key:    key,
+		holder: holder,
+		config: config,
+		logger: c.logger.With("lease", key, "holder", holder),
+	}, nil
+}
+
+func (c LeaseConfig) withDefaults() LeaseConfig {
+	if c.Bucket == "" {
+		c.Bucket = "leases"
+	}
+	if c.TTL <= 0 {
+		c.TTL = 10 * time.Second
+	}
+	if c.RenewInterval <= 0 {
+		c.RenewInterval = c.TTL / 3
+	}
+	if c.RetryInterval <= 0 {
+		c.RetryInterval = c.TTL / 3
+	}
+
+	return c
+}
+
+// acquire waits until the lease is acquired and returns the fencing token
+// and the channel closed when the lease is lost or released.
+func (l *lease) acquire(ctx context.Context) (uint64, <-chan struct{}, error) {
+	ticker := time.NewTicker(l.config.RetryInterval)
+	defer ticker.Stop()
+
+	for {
+		token, chanLost, ok, err := l.tryAcquire(ctx)
+		if ok || err != nil {
+			return token, chanLost, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return 0, nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// tryAcquire acquires the lease if it is free or left by the same holder, e.g. before a restart.
+func (l *lease) tryAcquire(ctx context.Context) (uint64, <-chan struct{}, bool, error) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	if l.token != 0 {
+		return l.token, l.chanLost, true, nil
+	}
+
+	started := time.Now()
+	value := leaseValue{Holder: l.holder}
+
+	revision, err := l.kv.Create(ctx, l.key, value)
+	if errors.Is(err, ErrKeyExists) {
+		revision, err = l.takeOver(ctx, value)
+	}
+	if err != nil {
+		if errors.Is(err, ErrKeyExists) || errors.Is(err, ErrRevisionMismatch) {
+			return 0, nil, false, nil
+		}
+		return 0, nil, false, fmt.Errorf("acquiring lease %s: %w", l.key, err)
+	}
+
+	l.token, l.revision = revision, revision
+	l.chanLost = make(chan struct{})
+
+	renewCtx, cancel := context.WithCancel(context.Background())
+	l.cancelRenew = cancel
+	l.renewDone = make(chan struct{})
+	go l.renew(renewCtx, started, l.chanLost, l.renewDone)
+
+	l.logger.Info("lease acquired", "token", revision)
+	if l.onChange != nil {
+		l.onChange(true)
+	}
+
+	return revision, l.chanLost, true, nil
+}
+
+func (l *lease) takeOver(ctx context.Context, value leaseValue) (uint64, error) {
+	entry, err := l.kv.Get(ctx, l.key)
+	if err != nil {
+		if errors.Is(err, ErrKeyNotFound) {
+			// expired or released meanwhile
+			return l.kv.Create(ctx, l.key, value)
+		}
+		return 0, err
+	}
+
+	if entry.Value.Holder != l.holder {
+		return 0, ErrKeyExists
+	}
+
+	return l.kv.Update(ctx, l.key, value, entry.Revision)
+}
+
+// renew keeps the lease until ctx is done, the lease is lost when it is changed by others
+// or it is not renewed within the TTL.
+func (l *lease) renew(ctx context.Context, renewed time.Time, chanLost chan struct{}, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(l.config.RenewInterval)
+	defer ticker.Stop()
+
+	deadline := renewed.Add(l.config.TTL)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(deadline)):
+			l.lose(chanLost, errors.New("not renewed within the TTL"))
+			return
+		case <-ticker.C:
+			started := time.Now()
+			if err := l.renewOnce(ctx); err != nil {
+				if errors.Is(err, ErrRevisionMismatch) || errors.Is(err, ErrKeyNotFound) {
+					l.lose(chanLost, err)
+					return
+				}
+				l.logger.Warn("failed to renew lease", "error", err)
+				continue
+			}
+
+			deadline = started.Add(l.config.TTL)
+		}
+	}
+}
+
+func (l *lease) renewOnce(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, l.config.RenewInterval)
+	defer cancel()
+
+	l.mux.Lock()
+	revision := l.revision
+	l.mux.Unlock()
+
+	revision, err := l.kv.Update(ctx, l.key, leaseValue{Holder: l.holder}, revision)
+	if err != nil {
+		return err
+	}
+
+	l.mux.Lock()
+	l.revision = revision
+	l.mux.Unlock()
+
+	return nil
+}
+
+// lose marks the lease lost, unless it was already released and acquired again.
+func (l *lease) lose(chanLost chan struct{}, reason error) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	if l.chanLost != chanLost {
+		return
+	}
+
+	l.logger.Warn("lease lost", "token", l.token, "reason", reason)
+	l.reset()
+}
+
+// release stops the renewal and deletes the key if it is still held.
+func (l *lease) release(ctx context.Context) error {
+	l.mux.Lock()
+	if l.token == 0 {
+		l.mux.Unlock()
+		return ErrLeaseNotHeld
+	}
+
+	l.cancelRenew()
+	renewDone := l.renewDone
+	l.mux.Unlock()
+
+	<-renewDone
+
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	if l.token == 0 {
+		// lost while the renewal was stopped
+		return ErrLeaseNotHeld
+	}
+
+	err := l.kv.kv.Delete(l.key, nats.LastRevision(l.revision))
+	l.logger.Info("lease released", "token", l.token)
+	l.reset()
+
+	if err != nil && !isWrongLastSequence(err) {
+		return fmt.Errorf("releasing lease %s: %w", l.key, kvError(err))
+	}
+
+	return nil
+}
+
+// reset must be called with the mux locked.
+func (l *lease) reset() {
+	l.cancelRenew()
+	l.token, l.revision = 0, 0
+	close(l.chanLost)
+	l.chanLost = nil
+
+	if l.onChange != nil {
+		l.onChange(false)
+	}
+}
+
+func (l *lease) heldToken() uint64 {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	return l.token
+}
@@ -0,0 +1,66 @@
+package natslib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+)
+
+// Message is a core NATS message.
+type Message struct {
+	// Subject is the subject the message was published to, not the wildcard subscription subject.
+	Subject string
+	// Reply is the subject to send the reply to, empty if no reply is expected.
+	Reply  string
+	Header nats.Header
+	Data   []byte
+}
+
+// PublishMsg publishes the message with its headers and reply subject.
+// While the client is reconnecting, the message is handled by the Config.DisconnectedPublish policy.
+func (c *Client) PublishMsg(ctx context.Context, msg *Message) error {
+	if c.conn.IsClosed() {
+		return ErrClosed
+	}
+
+	// The connection is not connected to the NATS server but is not closed
+	// and tries to reconnect until reaching the max reconnect attempts.
+	// The spilled messages go first, so the new ones are spilled until the replay is done.
+	if !c.conn.IsConnected() || c.spilling() {
+		return c.publishDisconnected(ctx, msg)
+	}
+
+	return c.publish(msg)
+}
+
+func (c *Client) publish(msg *Message) error {
+	if err := c.conn.PublishMsg(toNatsMsg(msg)); err != nil {
+		if errors.Is(err, nats.ErrConnectionClosed) {
+			return ErrClosed
+		}
+
+		return fmt.Errorf("%w: %v", ErrPublishFailed, err)
+	}
+
+	return nil
+}
+
+func toNatsMsg(msg *Message) *nats.Msg {
+	return &nats.Msg{
+		Subject: msg.Subject,
+		Reply:   msg.Reply,
+		Header:  msg.Header,
+		Data:    msg.Data,
+	}
+}
+
+func fromNatsMsg(msg *nats.Msg) Message {
+	return Message{
+		Subject: msg.Subject,
+		Reply:   msg.Reply,
+		Header:  msg.Header,
+		Data:    msg.Data,
+	}
+}
@@ -0,0 +1,42 @@
+package natslib
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_PublishMsg(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := connectTestClient(t, runTestServer(t))
+
+	sub, err := client.Subscribe(ctx, "orders.*.created")
+	require.NoError(t, err)
+	require.NoError(t, client.conn.Flush())
+
+	require.NoError(t, client.PublishMsg(ctx, &Message{
+		Subject: "orders.42.created",
+		Reply:   "replies.42",
+		Header:  nats.Header{"Trace-Id": []string{"abc"}},
+		Data:    []byte("order"),
+	}))
+
+	msg, err := sub.ReceiveMsg(ctx)
+	require.NoError(t, err)
+	require.Equal(t, &Message{
+		Subject: "orders.42.created",
+		Reply:   "replies.42",
+		Header:  nats.Header{"Trace-Id": []string{"abc"}},
+		Data:    []byte("order"),
+	}, msg)
+
+	wildcards, ok := SubjectWildcards("orders.*.created", msg.Subject)
+	require.True(t, ok)
+	require.Equal(t, []string{"42"}, wildcards)
+}
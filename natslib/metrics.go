@@ -0,0 +1,159 @@
+package natslib
+
+import (
+	"sync"
+
+	"github.com/nats-io/nats.go"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/yvyrovyi-cinemo/utils/metrics"
+)
+
+// clientsCollector reports the metrics of all the connected clients at scrape time,
+// the clients with the same Config.Name are summed up.
+type clientsCollector struct {
+	clients sync.Map
+
+	// removed keeps the counters of the closed clients, so the totals of their names do not go down
+	removedMux sync.Mutex
+	removed    map[string]nats.Statistics
+
+	statusDesc     *prometheus.Desc
+	reconnectsDesc *prometheus.Desc
+	inMsgsDesc     *prometheus.Desc
+	outMsgsDesc    *prometheus.Desc
+	inBytesDesc    *prometheus.Desc
+	outBytesDesc   *prometheus.Desc
+	bufferedDesc   *prometheus.Desc
+	pendingDesc    *prometheus.Desc
+	droppedDesc    *prometheus.Desc
+}
+
+type subscriptionKey struct {
+	client  string
+	subject string
+}
+
+type subscriptionStats struct {
+	pending int
+	dropped int
+}
+
+func initMetrics() (*clientsCollector, error) {
+	clientLabels := []string{"client"}
+	subscriptionLabels := []string{"client", "subject"}
+
+	return metrics.Register(&clientsCollector{
+		removed: map[string]nats.Statistics{},
+		statusDesc: prometheus.NewDesc("nats_connection_status",
+			"a number of connected clients, 1 when the client is connected", clientLabels, nil),
+		reconnectsDesc: prometheus.NewDesc("nats_reconnects_total",
+			"a number of reconnects to the server", clientLabels, nil),
+		inMsgsDesc: prometheus.NewDesc("nats_in_messages_total",
+			"a number of messages received from the server", clientLabels, nil),
+		outMsgsDesc: prometheus.NewDesc("nats_out_messages_total",
+			"a number of messages sent to the server", clientLabels, nil),
+		inBytesDesc: prometheus.NewDesc("nats_in_bytes_total",
+			"a size of messages received from the server", clientLabels, nil),
+		outBytesDesc: prometheus.NewDesc("nats_out_bytes_total",
+			"a size of messages sent to the server", clientLabels, nil),
+		bufferedDesc: prometheus.NewDesc("nats_reconnect_buffer_bytes",
+			"a size of messages published while reconnecting and kept in the reconnect buffer", clientLabels, nil),
+		pendingDesc: prometheus.NewDesc("nats_subscription_pending_messages",
+			"a number of messages received by the subscription but not processed yet", subscriptionLabels, nil),
+		droppedDesc: prometheus.NewDesc("nats_subscription_dropped_messages",
+			"a number of messages dropped by the active subscriptions because of the pending limits, "+
+				"it starts over when a subscription is re-created", subscriptionLabels, nil),
+	})
+}
+
+func (m *clientsCollector) add(c *Client) {
+	m.clients.Store(c, struct{}{})
+}
+
+func (m *clientsCollector) remove(c *Client) {
+	m.removedMux.Lock()
+	defer m.removedMux.Unlock()
+
+	m.removed[c.name] = addStats(m.removed[c.name], c.conn.Stats())
+	m.clients.Delete(c)
+}
+
+func (m *clientsCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{
+		m.statusDesc, m.reconnectsDesc, m.inMsgsDesc, m.outMsgsDesc,
+		m.inBytesDesc, m.outBytesDesc, m.bufferedDesc, m.pendingDesc, m.droppedDesc,
+	} {
+		ch <- desc
+	}
+}
+
+func (m *clientsCollector) Collect(ch chan<- prometheus.Metric) {
+	connected := map[string]int{}
+	buffered := map[string]int{}
+	subscriptions := map[subscriptionKey]subscriptionStats{}
+
+	m.removedMux.Lock()
+	defer m.removedMux.Unlock()
+
+	stats := make(map[string]nats.Statistics, len(m.removed))
+	for name, s := range m.removed {
+		stats[name] = s
+	}
+
+	m.clients.Range(func(key, _ any) bool {
+		c := key.(*Client)
+
+		if c.conn.IsConnected() {
+			connected[c.name]++
+		}
+
+		if n, err := c.conn.Buffered(); err == nil {
+			buffered[c.name] += n
+		}
+
+		stats[c.name] = addStats(stats[c.name], c.conn.Stats())
+
+		c.subscriptions.Range(func(key, _ any) bool {
+			sub := key.(*nats.Subscription)
+			k := subscriptionKey{client: c.name, subject: sub.Subject}
+			subscriptions[k] = addSubscriptionStats(subscriptions[k], sub)
+			return true
+		})
+
+		return true
+	})
+
+	for name, s := range stats {
+		ch <- prometheus.MustNewConstMetric(m.statusDesc, prometheus.GaugeValue, float64(connected[name]), name)
+		ch <- prometheus.MustNewConstMetric(m.reconnectsDesc, prometheus.CounterValue, float64(s.Reconnects), name)
+		ch <- prometheus.MustNewConstMetric(m.inMsgsDesc, prometheus.CounterValue, float64(s.InMsgs), name)
+		ch <- prometheus.MustNewConstMetric(m.outMsgsDesc, prometheus.CounterValue, float64(s.OutMsgs), name)
+		ch <- prometheus.MustNewConstMetric(m.inBytesDesc, prometheus.CounterValue, float64(s.InBytes), name)
+		ch <- prometheus.MustNewConstMetric(m.outBytesDesc, prometheus.CounterValue, float64(s.OutBytes), name)
+		ch <- prometheus.MustNewConstMetric(m.bufferedDesc, prometheus.GaugeValue, float64(buffered[name]), name)
+	}
+
+	for k, s := range subscriptions {
+		ch <- prometheus.MustNewConstMetric(m.pendingDesc, prometheus.GaugeValue, float64(s.pending), k.client, k.subject)
+		ch <- prometheus.MustNewConstMetric(m.droppedDesc, prometheus.GaugeValue, float64(s.dropped), k.client, k.subject)
+	}
+}
+
+func addStats(total, s nats.Statistics) nats.Statistics {
+	total.InMsgs += s.InMsgs
+	total.OutMsgs += s.OutMsgs
+	total.InBytes += s.InBytes
+	total.OutBytes += s.OutBytes
+	total.Reconnects += s.Reconnects
+	return total
+}
+
+func addSubscriptionStats(total subscriptionStats, sub *nats.Subscription) subscriptionStats {
+	pending, _, _ := sub.Pending()
+	dropped, _ := sub.Dropped()
+
+	total.pending += pending
+	total.dropped += dropped
+	return total
+}
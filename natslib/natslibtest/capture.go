@@ -0,0 +1,115 @@
+package natslibtest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/yvyrovyi-cinemo/utils/natslib"
+)
+
+// Capture records the messages published on a subject, it keeps recording across the server restarts.
+type Capture struct {
+	t testing.TB
+
+	mux      sync.Mutex
+	messages []natslib.Message
+}
+
+// WaitTimeout is how long the Capture assertions wait for the messages.
+var WaitTimeout = 5 * time.Second
+
+const pollInterval = 10 * time.Millisecond
+
+// Capture starts recording the messages published on the subject, which may contain wildcards.
+// The subscription is active when Capture returns and it is stopped by the test cleanup.
+func (s *Server) Capture(subject string) *Capture {
+	s.t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	client := s.Connect()
+
+	sub, err := client.Subscribe(ctx, subject)
+	require.NoError(s.t, err)
+	require.NoError(s.t, client.Ready(ctx))
+
+	c := &Capture{t: s.t}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.receive(ctx, sub)
+	}()
+
+	s.t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	return c
+}
+
+func (c *Capture) receive(ctx context.Context, sub *natslib.Subscription) {
+	for {
+		msg, err := sub.ReceiveMsg(ctx)
+		if err != nil {
+			if errors.Is(err, context.Canceled) || errors.Is(err, natslib.ErrClosed) {
+				return
+			}
+			continue
+		}
+
+		c.mux.Lock()
+		c.messages = append(c.messages, *msg)
+		c.mux.Unlock()
+	}
+}
+
+// Messages returns the messages recorded so far in the order they were received.
+func (c *Capture) Messages() []natslib.Message {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	return append([]natslib.Message(nil), c.messages...)
+}
+
+// Wait waits until at least n messages are recorded and returns all of them.
+// The test fails if they are not recorded within WaitTimeout.
+func (c *Capture) Wait(n int) []natslib.Message {
+	c.t.Helper()
+
+	var messages []natslib.Message
+	require.Eventually(c.t, func() bool {
+		messages = c.Messages()
+		return len(messages) >= n
+	}, WaitTimeout, pollInterval, "%d messages expected", n)
+
+	return messages
+}
+
+// RequirePublished waits for the payloads and checks they are recorded exactly and in order.
+func (c *Capture) RequirePublished(payloads ...string) {
+	c.t.Helper()
+
+	messages := c.Wait(len(payloads))
+
+	got := make([]string, len(messages))
+	for i, msg := range messages {
+		got[i] = string(msg.Data)
+	}
+
+	require.Equal(c.t, payloads, got)
+}
+
+// RequireNothing checks that nothing is recorded during the wait.
+func (c *Capture) RequireNothing(wait time.Duration) {
+	c.t.Helper()
+
+	time.Sleep(wait)
+	require.Empty(c.t, c.Messages())
+}
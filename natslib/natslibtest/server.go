@@ -0,0 +1,163 @@
+// Package natslibtest runs an embedded nats-server for testing code built on natslib.
+// The servers listen on random ports and are stopped when the test ends.
+package natslibtest
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yvyrovyi-cinemo/utils/natslib"
+)
+
+type (
+	// Server is an embedded nats-server which can be stopped and restarted on the same port,
+	// so that the clients reconnect to it. JetStream data survives the restart.
+	Server struct {
+		t    testing.TB
+		opts server.Options
+
+		mux sync.Mutex
+		srv *server.Server
+		// clients is the number of clients connected before the stop
+		clients int
+	}
+
+	Option func(opts *server.Options)
+)
+
+const (
+	readyTimeout  = 5 * time.Second
+	reconnectWait = 20 * time.Millisecond
+)
+
+// WithJetStream enables JetStream with the file storage in a temporary directory.
+func WithJetStream() Option {
+	return func(opts *server.Options) {
+		opts.JetStream = true
+	}
+}
+
+// WithServerOptions changes the server options, e.g. to enable authentication or TLS.
+func WithServerOptions(fn func(opts *server.Options)) Option {
+	return fn
+}
+
+// NewServer starts the server on a random port. It is shut down by the test cleanup.
+func NewServer(t testing.TB, opts ...Option) *Server {
+	t.Helper()
+
+	s := &Server{
+		t: t,
+		opts: server.Options{
+			Host:   "127.0.0.1",
+			Port:   server.RANDOM_PORT,
+			NoLog:  true,
+			NoSigs: true,
+		},
+	}
+
+	for _, opt := range opts {
+		opt(&s.opts)
+	}
+
+	if s.opts.JetStream && s.opts.StoreDir == "" {
+		s.opts.StoreDir = t.TempDir()
+	}
+
+	s.start()
+
+	// the restarts reuse the port
+	s.opts.Port = s.srv.Addr().(*net.TCPAddr).Port
+
+	t.Cleanup(s.Stop)
+
+	return s
+}
+
+// URL returns the client URL of the server, it does not change after the restart.
+func (s *Server) URL() string {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	return s.srv.ClientURL()
+}
+
+// Stop shuts the server down, the connected clients start reconnecting.
+func (s *Server) Stop() {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if s.srv.Running() {
+		s.clients = s.srv.NumClients()
+	}
+
+	s.srv.Shutdown()
+	s.srv.WaitForShutdown()
+}
+
+// Restart starts the stopped server again on the same port, it stops the running one first.
+// It waits a while for the clients connected before the stop to reconnect, so that their
+// subscriptions are restored.
+func (s *Server) Restart() {
+	s.t.Helper()
+
+	s.Stop()
+	s.start()
+
+	s.mux.Lock()
+	srv, clients := s.srv, s.clients
+	s.mux.Unlock()
+
+	for deadline := time.Now().Add(readyTimeout); time.Now().Before(deadline); {
+		if srv.NumClients() >= clients {
+			return
+		}
+		time.Sleep(reconnectWait)
+	}
+}
+
+// Config returns the client config for the server, which reconnects quickly and forever.
+func (s *Server) Config() natslib.Config {
+	return natslib.Config{
+		URL:                              s.URL(),
+		DrainTimeout:                     time.Second,
+		SubCheckPeriod:                   time.Second,
+		MaxReconnect:                     -1,
+		ReconnectWait:                    reconnectWait,
+		SubscriptionPendingMessagesLimit: 1000,
+	}
+}
+
+// Connect returns the client connected with the Config. It is closed by the test cleanup.
+func (s *Server) Connect() *natslib.Client {
+	s.t.Helper()
+
+	client, err := natslib.Connect(context.Background(), s.Config(), slog.Default())
+	require.NoError(s.t, err)
+	s.t.Cleanup(client.Close)
+
+	return client
+}
+
+func (s *Server) start() {
+	s.t.Helper()
+
+	opts := s.opts
+
+	srv, err := server.NewServer(&opts)
+	require.NoError(s.t, err)
+
+	srv.Start()
+	require.True(s.t, srv.ReadyForConnections(readyTimeout), "nats server is not ready")
+
+	s.mux.Lock()
+	s.srv = srv
+	s.mux.Unlock()
+}
@@ -0,0 +1,71 @@
+package natslibtest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/yvyrovyi-cinemo/utils/natslib"
+)
+
+func TestServer_Capture(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	srv := NewServer(t)
+	client := srv.Connect()
+
+	orders := srv.Capture("orders.*")
+	other := srv.Capture("other")
+
+	require.NoError(t, client.Publish(ctx, "orders.created", []byte("1")))
+	require.NoError(t, client.PublishMsg(ctx, &natslib.Message{
+		Subject: "orders.paid",
+		Header:  map[string][]string{"Trace": {"abc"}},
+		Data:    []byte("2"),
+	}))
+
+	orders.RequirePublished("1", "2")
+	messages := orders.Messages()
+	require.Equal(t, "orders.paid", messages[1].Subject)
+	require.Equal(t, "abc", messages[1].Header.Get("Trace"))
+
+	other.RequireNothing(100 * time.Millisecond)
+}
+
+func TestServer_Restart(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	srv := NewServer(t, WithJetStream())
+	url := srv.URL()
+	client := srv.Connect()
+	capture := srv.Capture("events")
+
+	kv, err := natslib.EnsureKV[string](ctx, client, natslib.KVConfig{Bucket: "state"})
+	require.NoError(t, err)
+	_, err = kv.Put(ctx, "key", "value")
+	require.NoError(t, err)
+
+	srv.Stop()
+	require.ErrorIs(t, client.Ready(ctx), natslib.ErrNotConnected)
+
+	srv.Restart()
+	require.Equal(t, url, srv.URL())
+
+	require.Eventually(t, func() bool {
+		return client.Ready(ctx) == nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// the file storage survives the restart
+	entry, err := kv.Get(ctx, "key")
+	require.NoError(t, err)
+	require.Equal(t, "value", entry.Value)
+
+	require.NoError(t, client.Publish(ctx, "events", []byte("after restart")))
+	capture.RequirePublished("after restart")
+}
@@ -0,0 +1,191 @@
+package natslib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+type (
+	// ObjectStoreConfig describes a JetStream Object Store bucket created or updated by Client.EnsureObjectStore.
+	ObjectStoreConfig struct {
+		Bucket      string `env:"BUCKET" json:"bucket" yaml:"bucket"`
+		Description string `env:"DESCRIPTION" json:"description" yaml:"description"`
+
+		// TTL removes the objects older than the duration, the objects are kept forever when 0.
+		TTL      time.Duration `env:"TTL" json:"ttl" yaml:"ttl"`
+		MaxBytes int64         `env:"MAX_BYTES" json:"max_bytes" yaml:"max_bytes"`
+
+		// ChunkSize is the size of the messages the objects are split into.
+		ChunkSize uint32 `env:"CHUNK_SIZE" envDefault:"131072" json:"chunk_size" yaml:"chunk_size" default:"131072"`
+
+		// Storage is "file" (default) or "memory".
+		Storage  string `env:"STORAGE" envDefault:"file" json:"storage" yaml:"storage" default:"file"`
+		Replicas int    `env:"REPLICAS" envDefault:"1" json:"replicas" yaml:"replicas" default:"1"`
+	}
+
+	// ObjectStore keeps large blobs split into chunks.
+	ObjectStore struct {
+		store     nats.ObjectStore
+		chunkSize uint32
+	}
+
+	ObjectInfo struct {
+		Name     string
+		Size     uint64
+		Chunks   uint32
+		Digest   string
+		Modified time.Time
+	}
+)
+
+// objectStreamPrefix is the prefix of the streams of the object stores.
+const objectStreamPrefix = "OBJ_"
+
+var ErrObjectNotFound = errors.New("object not found")
+
+// EnsureObjectStore creates the bucket or updates it to match the config.
+func (c *Client) EnsureObjectStore(ctx context.Context, config ObjectStoreConfig) (*ObjectStore, error) {
+	storage, err := natsStorage(config.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := c.js.CreateObjectStore(&nats.ObjectStoreConfig{
+		Bucket:      config.Bucket,
+		Description: config.Description,
+		TTL:         config.TTL,
+		MaxBytes:    config.MaxBytes,
+		Storage:     storage,
+		Replicas:    config.Replicas,
+	})
+	if errors.Is(err, nats.ErrStreamNameAlreadyInUse) {
+		// the bucket exists with another config, its stream is updated the way CreateObjectStore makes it
+		err = c.updateStream(ctx, objectStreamPrefix+config.Bucket, func(streamConfig *nats.StreamConfig) {
+			streamConfig.Description = config.Description
+			streamConfig.MaxAge = config.TTL
+			streamConfig.MaxBytes = config.MaxBytes
+			if streamConfig.MaxBytes == 0 {
+				streamConfig.MaxBytes = -1
+			}
+			streamConfig.Storage = storage
+			streamConfig.Replicas = max(config.Replicas, 1)
+		})
+		if err == nil {
+			store, err = c.js.ObjectStore(config.Bucket)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ensuring object store %s: %w", config.Bucket, objectError(err))
+	}
+
+	return &ObjectStore{store: store, chunkSize: config.ChunkSize}, nil
+}
+
+// DeleteObjectStore deletes the bucket with all its objects.
+func (c *Client) DeleteObjectStore(ctx context.Context, bucket string) error {
+	if err := c.js.DeleteStream(objectStreamPrefix+bucket, nats.Context(ctx)); err != nil {
+		return fmt.Errorf("deleting object store %s: %w", bucket, objectError(err))
+	}
+
+	return nil
+}
+
+// Put reads the object from the reader chunk by chunk and replaces the object with the same name.
+func (s *ObjectStore) Put(ctx context.Context, name string, r io.Reader) (ObjectInfo, error) {
+	info, err := s.store.Put(&nats.ObjectMeta{
+		Name: name,
+		Opts: &nats.ObjectMetaOptions{ChunkSize: s.chunkSize},
+	}, r, nats.Context(ctx))
+	if err != nil {
+		return ObjectInfo{}, fmt.Errorf("putting object %s: %w", name, objectError(err))
+	}
+
+	return newObjectInfo(info), nil
+}
+
+// Get writes the object to the writer chunk by chunk and verifies its digest.
+func (s *ObjectStore) Get(ctx context.Context, name string, w io.Writer) (ObjectInfo, error) {
+	res, err := s.store.Get(name, nats.Context(ctx))
+	if err != nil {
+		return ObjectInfo{}, fmt.Errorf("getting object %s: %w", name, objectError(err))
+	}
+	defer func() {
+		_ = res.Close()
+	}()
+
+	info, err := res.Info()
+	if err != nil {
+		return ObjectInfo{}, fmt.Errorf("getting object %s: %w", name, objectError(err))
+	}
+
+	if _, err := io.Copy(w, res); err != nil {
+		return ObjectInfo{}, fmt.Errorf("reading object %s: %w", name, objectError(err))
+	}
+
+	return newObjectInfo(info), nil
+}
+
+// Info returns the object info, ErrObjectNotFound when the object does not exist or is deleted.
+func (s *ObjectStore) Info(ctx context.Context, name string) (ObjectInfo, error) {
+	info, err := s.store.GetInfo(name, nats.Context(ctx))
+	if err != nil {
+		return ObjectInfo{}, fmt.Errorf("getting object %s info: %w", name, objectError(err))
+	}
+
+	return newObjectInfo(info), nil
+}
+
+// Delete deletes the object with all its chunks, it is bounded by the JetStream timeout instead of ctx.
+func (s *ObjectStore) Delete(_ context.Context, name string) error {
+	if err := s.store.Delete(name); err != nil {
+		return fmt.Errorf("deleting object %s: %w", name, objectError(err))
+	}
+
+	return nil
+}
+
+// List returns the objects of the bucket, the deleted ones are skipped.
+func (s *ObjectStore) List(ctx context.Context) ([]ObjectInfo, error) {
+	infos, err := s.store.List(nats.Context(ctx))
+	if err != nil {
+		if errors.Is(err, nats.ErrNoObjectsFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("listing objects: %w", objectError(err))
+	}
+
+	res := make([]ObjectInfo, 0, len(infos))
+	for _, info := range infos {
+		res = append(res, newObjectInfo(info))
+	}
+
+	return res, nil
+}
+
+func newObjectInfo(info *nats.ObjectInfo) ObjectInfo {
+	return ObjectInfo{
+		Name:     info.Name,
+		Size:     info.Size,
+		Chunks:   info.Chunks,
+		Digest:   info.Digest,
+		Modified: info.ModTime,
+	}
+}
+
+func objectError(err error) error {
+	switch {
+	case errors.Is(err, nats.ErrConnectionClosed):
+		return ErrClosed
+	case errors.Is(err, nats.ErrBucketNotFound), errors.Is(err, nats.ErrStreamNotFound):
+		return ErrBucketNotFound
+	case errors.Is(err, nats.ErrObjectNotFound):
+		return ErrObjectNotFound
+	default:
+		return err
+	}
+}
@@ -0,0 +1,57 @@
+package natslib
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestObjectStore(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	client := connectTestClient(t, runTestServer(t))
+
+	store, err := client.EnsureObjectStore(ctx, ObjectStoreConfig{Bucket: "blobs", ChunkSize: 1024, Storage: "memory"})
+	require.NoError(t, err)
+
+	blob := make([]byte, 10*1024+100)
+	_, err = rand.Read(blob)
+	require.NoError(t, err)
+
+	info, err := store.Put(ctx, "models/v1.bin", bytes.NewReader(blob))
+	require.NoError(t, err)
+	require.Equal(t, uint64(len(blob)), info.Size)
+	require.Equal(t, uint32(11), info.Chunks)
+
+	buf := &bytes.Buffer{}
+	info, err = store.Get(ctx, "models/v1.bin", buf)
+	require.NoError(t, err)
+	require.Equal(t, blob, buf.Bytes())
+	require.Equal(t, "models/v1.bin", info.Name)
+
+	list, err := store.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+
+	require.NoError(t, store.Delete(ctx, "models/v1.bin"))
+
+	_, err = store.Info(ctx, "models/v1.bin")
+	require.ErrorIs(t, err, ErrObjectNotFound)
+	_, err = store.Get(ctx, "models/v1.bin", buf)
+	require.ErrorIs(t, err, ErrObjectNotFound)
+
+	list, err = store.List(ctx)
+	require.NoError(t, err)
+	require.Empty(t, list)
+
+	// the existing bucket is updated
+	_, err = client.EnsureObjectStore(ctx, ObjectStoreConfig{Bucket: "blobs", Description: "models", Storage: "memory"})
+	require.NoError(t, err)
+
+	require.NoError(t, client.DeleteObjectStore(ctx, "blobs"))
+	require.ErrorIs(t, client.DeleteObjectStore(ctx, "blobs"), ErrBucketNotFound)
+}
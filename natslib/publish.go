@@ -0,0 +1,246 @@
+package natslib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/yvyrovyi-cinemo/utils/metrics"
+)
+
+type (
+	// DisconnectedPublishPolicy is the Publish behavior while the client is reconnecting.
+	DisconnectedPublishPolicy string
+
+	DisconnectedPublishConfig struct {
+		// Policy is one of fail, buffer, block or spill, fail by default.
+		Policy DisconnectedPublishPolicy `env:"POLICY" envDefault:"fail" json:"policy" yaml:"policy" default:"fail" validate:"oneof=fail buffer block spill"`
+
+		// BufferSize is the size of the nats reconnect buffer in bytes for the buffer policy.
+		BufferSize int `env:"BUFFER_SIZE" envDefault:"8388608" json:"buffer_size" yaml:"buffer_size" default:"8388608"`
+
+		// SpillFilePath is the file of the spill policy queue, required for the spill policy.
+		SpillFilePath string `env:"SPILL_FILE_PATH" json:"spill_file_path" yaml:"spill_file_path"`
+		// SpillMaxSize limits the spill file size in bytes, unlimited when 0.
+		SpillMaxSize int64 `env:"SPILL_MAX_SIZE" json:"spill_max_size" yaml:"spill_max_size"`
+	}
+
+	publishMetrics struct {
+		disconnectedCounterVec *prometheus.CounterVec
+		waitCounterVec         *prometheus.CounterVec
+		spillGaugeVec          *prometheus.GaugeVec
+	}
+)
+
+const (
+	// PublishFail returns ErrNotConnected.
+	PublishFail DisconnectedPublishPolicy = "fail"
+	// PublishBuffer keeps the messages in the nats reconnect buffer of BufferSize,
+	// ErrPublishFailed is returned once the buffer is full.
+	PublishBuffer DisconnectedPublishPolicy = "buffer"
+	// PublishBlock waits for the reconnect until ctx is done.
+	PublishBlock DisconnectedPublishPolicy = "block"
+	// PublishSpill writes the messages to the spill file and publishes them in order after the reconnect.
+	// A message may be published twice if the process stops during the replay.
+	PublishSpill DisconnectedPublishPolicy = "spill"
+)
+
+// connectedCheckPeriod is the period the block policy checks the connection with.
+const connectedCheckPeriod = 20 * time.Millisecond
+
+var ErrInvalidPublishPolicy = errors.New("invalid disconnected publish policy")
+
+func initPublishMetrics() (*publishMetrics, error) {
+	disconnectedCounterVec, err := metrics.Register(prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nats_disconnected_publish_total",
+			Help: "a number of messages published while disconnected by the outcome: failed, buffered, blocked, spilled or replayed",
+		},
+		[]string{"client", "policy", "outcome"},
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to register disconnected publish metrics: %w", err)
+	}
+
+	waitCounterVec, err := metrics.Register(prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nats_disconnected_publish_wait_seconds_total",
+			Help: "a time spent by the block policy waiting for the reconnect",
+		},
+		[]string{"client"},
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to register disconnected publish wait metrics: %w", err)
+	}
+
+	spillGaugeVec, err := metrics.Register(prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "nats_publish_spill_messages",
+			Help: "a number of messages in the spill file waiting for the replay",
+		},
+		[]string{"client"},
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to register spill metrics: %w", err)
+	}
+
+	return &publishMetrics{
+		disconnectedCounterVec: disconnectedCounterVec,
+		waitCounterVec:         waitCounterVec,
+		spillGaugeVec:          spillGaugeVec,
+	}, nil
+}
+
+func (c *Client) initPublishPolicy(config DisconnectedPublishConfig) ([]nats.Option, error) {
+	if config.Policy == "" {
+		config.Policy = PublishFail
+	}
+	c.publishPolicy = config.Policy
+
+	switch config.Policy {
+	case PublishFail, PublishBlock:
+		return nil, nil
+	case PublishBuffer:
+		return []nats.Option{nats.ReconnectBufSize(config.BufferSize)}, nil
+	case PublishSpill:
+		if config.SpillFilePath == "" {
+			return nil, fmt.Errorf("%w: spill file path is required", ErrInvalidPublishPolicy)
+		}
+
+		spill, err := openSpillQueue(config.SpillFilePath, config.SpillMaxSize)
+		if err != nil {
+			return nil, err
+		}
+		c.spill = spill
+
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrInvalidPublishPolicy, config.Policy)
+	}
+}
+
+func (c *Client) publishDisconnected(ctx context.Context, msg *Message) error {
+	switch c.publishPolicy {
+	case PublishBuffer:
+		c.publishMetrics.disconnected(c.name, c.publishPolicy, "buffered")
+		return c.publish(msg)
+	case PublishBlock:
+		if err := c.waitConnected(ctx); err != nil {
+			c.publishMetrics.disconnected(c.name, c.publishPolicy, "failed")
+			return err
+		}
+		c.publishMetrics.disconnected(c.name, c.publishPolicy, "blocked")
+		return c.publish(msg)
+	case PublishSpill:
+		return c.publishSpill(msg)
+	default:
+		c.publishMetrics.disconnected(c.name, c.publishPolicy, "failed")
+		return fmt.Errorf("%w: %w", ErrPublishFailed, ErrNotConnected)
+	}
+}
+
+func (c *Client) waitConnected(ctx context.Context) error {
+	start := time.Now()
+	defer func() {
+		c.publishMetrics.wait(c.name, time.Since(start))
+	}()
+
+	ticker := time.NewTicker(connectedCheckPeriod)
+	defer ticker.Stop()
+
+	for !c.conn.IsConnected() {
+		if c.conn.IsClosed() {
+			return ErrClosed
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w: %w", ErrPublishFailed, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+
+	return nil
+}
+
+func (c *Client) publishSpill(msg *Message) error {
+	if err := c.spill.push(msg); err != nil {
+		c.publishMetrics.disconnected(c.name, c.publishPolicy, "failed")
+		return fmt.Errorf("%w: %v", ErrPublishFailed, err)
+	}
+
+	c.publishMetrics.disconnected(c.name, c.publishPolicy, "spilled")
+	c.publishMetrics.setSpilled(c.name, c.spill.length())
+
+	// the connection may be restored while the message was written
+	if c.conn.IsConnected() {
+		go c.replaySpilled()
+	}
+
+	return nil
+}
+
+// replaySpilled publishes the spilled messages after the reconnect.
+// Only one replay runs at a time, the one started during the replay does nothing.
+func (c *Client) replaySpilled() {
+	for c.spill != nil && c.replaying.CompareAndSwap(false, true) {
+		err := c.replaySpilledOnce()
+		c.replaying.Store(false)
+
+		// the message spilled after the queue was found empty but before the flag was cleared
+		// is left by its own replay, so the queue is checked once more
+		if err != nil || c.spill.length() == 0 || !c.conn.IsConnected() {
+			return
+		}
+	}
+}
+
+func (c *Client) replaySpilledOnce() error {
+	replayed, err := c.spill.replay(c.publish)
+	if replayed > 0 {
+		c.publishMetrics.disconnectedAdd(c.name, c.publishPolicy, "replayed", replayed)
+		c.publishMetrics.setSpilled(c.name, c.spill.length())
+	}
+	if err != nil {
+		c.logger.Error("failed to replay spilled messages", "replayed", replayed, "error", err)
+	}
+
+	return err
+}
+
+// spilling reports whether the messages must be spilled to keep the order with the spilled ones.
+func (c *Client) spilling() bool {
+	return c.spill != nil && c.spill.length() > 0
+}
+
+func (m *publishMetrics) disconnected(client string, policy DisconnectedPublishPolicy, outcome string) {
+	m.disconnectedAdd(client, policy, outcome, 1)
+}
+
+func (m *publishMetrics) disconnectedAdd(client string, policy DisconnectedPublishPolicy, outcome string, n int) {
+	if m == nil {
+		return
+	}
+
+	m.disconnectedCounterVec.WithLabelValues(client, string(policy), outcome).Add(float64(n))
+}
+
+func (m *publishMetrics) wait(client string, d time.Duration) {
+	if m == nil {
+		return
+	}
+
+	m.waitCounterVec.WithLabelValues(client).Add(d.Seconds())
+}
+
+func (m *publishMetrics) setSpilled(client string, n int) {
+	if m == nil {
+		return
+	}
+
+	m.spillGaugeVec.WithLabelValues(client).Set(float64(n))
+}
@@ -0,0 +1,187 @@
+package natslib
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Publish_Disconnected(t *testing.T) {
+	t.Parallel()
+
+	t.Run("fail", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		client, _, restart := connectStoppedClient(t, DisconnectedPublishConfig{Policy: PublishFail})
+
+		err := client.Publish(ctx, "orders", []byte("1"))
+		require.ErrorIs(t, err, ErrNotConnected)
+		require.ErrorIs(t, err, ErrPublishFailed)
+
+		restart()
+	})
+
+	t.Run("buffer", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		client, sub, restart := connectStoppedClient(t, DisconnectedPublishConfig{Policy: PublishBuffer, BufferSize: 1024})
+
+		big := string(make([]byte, 2048))
+		require.NoError(t, client.Publish(ctx, "orders", []byte("1")))
+		require.NoError(t, client.Publish(ctx, "orders", []byte(big)))
+		require.ErrorIs(t, client.Publish(ctx, "orders", []byte("3")), ErrPublishFailed)
+
+		restart()
+		requireReceived(t, sub, "1", big)
+	})
+
+	t.Run("block", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		client, sub, restart := connectStoppedClient(t, DisconnectedPublishConfig{Policy: PublishBlock})
+
+		timeoutCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		err := client.Publish(timeoutCtx, "orders", []byte("0"))
+		require.ErrorIs(t, err, ErrPublishFailed)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+
+		chanErr := make(chan error)
+		go func() {
+			chanErr <- client.Publish(ctx, "orders", []byte("1"))
+		}()
+
+		select {
+		case <-chanErr:
+			require.Fail(t, "publish is not blocked")
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		restart()
+		require.NoError(t, <-chanErr)
+		requireReceived(t, sub, "1")
+	})
+
+	t.Run("spill", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		client, sub, restart := connectStoppedClient(t, DisconnectedPublishConfig{
+			Policy:        PublishSpill,
+			SpillFilePath: filepath.Join(t.TempDir(), "spill"),
+		})
+
+		for i := 1; i <= 3; i++ {
+			require.NoError(t, client.Publish(ctx, "orders", []byte(fmt.Sprint(i))))
+		}
+
+		restart()
+		requireReceived(t, sub, "1", "2", "3")
+
+		require.NoError(t, client.Publish(ctx, "orders", []byte("4")))
+		requireReceived(t, sub, "4")
+	})
+}
+
+func TestClient_Publish_SpillReplayedAfterRestart(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	publishConfig := DisconnectedPublishConfig{
+		Policy:        PublishSpill,
+		SpillFilePath: filepath.Join(t.TempDir(), "spill"),
+	}
+
+	client, _, restart := connectStoppedClient(t, publishConfig)
+	require.NoError(t, client.Publish(ctx, "orders", []byte("1")))
+	require.NoError(t, client.Publish(ctx, "orders", []byte("2")))
+	client.Close()
+
+	url := restart()
+
+	subscriber := connectTestClient(t, url)
+	sub, err := subscriber.Subscribe(ctx, "orders")
+	require.NoError(t, err)
+	require.NoError(t, subscriber.conn.Flush())
+
+	client, err = Connect(ctx, Config{
+		URL:                 url,
+		DrainTimeout:        time.Second,
+		SubCheckPeriod:      time.Second,
+		DisconnectedPublish: publishConfig,
+	}, slog.Default())
+	require.NoError(t, err)
+	defer client.Close()
+
+	requireReceived(t, sub, "1", "2")
+}
+
+// connectStoppedClient connects the client subscribed to the orders subject to the stopped server.
+// The restart starts the server on the same port and returns its URL.
+func connectStoppedClient(
+	t *testing.T,
+	publishConfig DisconnectedPublishConfig,
+) (*Client, *Subscription, func() string) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	srv := startTestServer(t, &server.Options{Port: server.RANDOM_PORT})
+	port := srv.Addr().(*net.TCPAddr).Port
+
+	client := connectRestartableClient(t, srv.ClientURL(), publishConfig)
+
+	sub, err := client.Subscribe(ctx, "orders")
+	require.NoError(t, err)
+
+	srv.Shutdown()
+	require.Eventually(t, func() bool { return !client.conn.IsConnected() }, time.Second, 10*time.Millisecond)
+
+	return client, sub, func() string {
+		return startTestServer(t, &server.Options{Port: port}).ClientURL()
+	}
+}
+
+// connectRestartableClient connects the client which reconnects forever, so it survives the server restarts.
+func connectRestartableClient(t *testing.T, url string, publishConfig DisconnectedPublishConfig) *Client {
+	t.Helper()
+
+	client, err := Connect(context.Background(), Config{
+		URL:                 url,
+		DrainTimeout:        time.Second,
+		SubCheckPeriod:      time.Second,
+		MaxReconnect:        -1,
+		ReconnectWait:       20 * time.Millisecond,
+		DisconnectedPublish: publishConfig,
+	}, slog.Default())
+	require.NoError(t, err)
+	t.Cleanup(client.Close)
+
+	return client
+}
+
+func requireReceived(t *testing.T, sub *Subscription, payloads ...string) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for _, payload := range payloads {
+		got, err := sub.Receive(ctx)
+		require.NoError(t, err)
+		require.Equal(t, payload, string(got))
+	}
+}
@@ -0,0 +1,247 @@
+package natslib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/micro"
+)
+
+type (
+	// RequestHandler returns the reply payload of a request.
+	// A returned *ServiceError is sent to the requester as is, other errors are sent with code 500.
+	RequestHandler func(ctx context.Context, subject string, payload []byte) ([]byte, error)
+
+	// ServiceError is an error reply. It is sent in the NATS micro error headers,
+	// so that replies are understood by other micro clients and the nats CLI.
+	ServiceError struct {
+		Code        string
+		Description string
+	}
+
+	// Reply is one of the replies collected by RequestMany.
+	Reply struct {
+		Payload []byte
+		// Err is a *ServiceError if the responder replied with an error.
+		Err error
+	}
+
+	// ServiceConfig identifies a NATS micro service in discovery, ping and stats requests.
+	ServiceConfig struct {
+		Name        string `env:"NAME" json:"name" yaml:"name"`
+		Version     string `env:"VERSION" json:"version" yaml:"version"`
+		Description string `env:"DESCRIPTION" json:"description" yaml:"description"`
+	}
+
+	// Service is a NATS micro service, its endpoints are added by Serve.
+	Service struct {
+		client  *Client
+		service micro.Service
+		ctx     context.Context
+	}
+)
+
+var ErrNoResponders = errors.New("no responders")
+
+var endpointNameInvalidChars = regexp.MustCompile(`[^A-Za-z0-9\-_]`)
+
+func NewServiceError(code, description string) *ServiceError {
+	return &ServiceError{Code: code, Description: description}
+}
+
+func (e *ServiceError) Error() string {
+	return fmt.Sprintf("service error %s: %s", e.Code, e.Description)
+}
+
+// Request sends the request and waits for a reply until ctx is done.
+// It returns ErrNoResponders when nobody listens to the subject and *ServiceError for error replies.
+func (c *Client) Request(ctx context.Context, subject string, payload []byte) ([]byte, error) {
+	msg, err := c.conn.RequestWithContext(ctx, subject, payload)
+	if err != nil {
+		switch {
+		case errors.Is(err, nats.ErrNoResponders):
+			return nil, fmt.Errorf("%w: %s", ErrNoResponders, subject)
+		case errors.Is(err, nats.ErrConnectionClosed):
+			return nil, ErrClosed
+		default:
+			return nil, fmt.Errorf("request: %w", err)
+		}
+	}
+
+	if err := replyError(msg); err != nil {
+		return nil, err
+	}
+
+	return msg.Data, nil
+}
+
+// RequestMany sends the request to all the subject listeners and collects their replies
+// until maxReplies are received or ctx is done, whichever happens first.
+// It returns ErrNoResponders when nobody listens to the subject and ErrClosed when the client is closed meanwhile.
+func (c *Client) RequestMany(ctx context.Context, subject string, payload []byte, maxReplies int) ([]Reply, error) {
+	inbox := c.conn.NewRespInbox()
+
+	natsSubscription, err := c.conn.SubscribeSync(inbox)
+	if err != nil {
+		if errors.Is(err, nats.ErrConnectionClosed) {
+			return nil, ErrClosed
+		}
+		return nil, fmt.Errorf("failed to subscribe: %w", err)
+	}
+	defer func() {
+		_ = natsSubscription.Unsubscribe()
+	}()
+
+	if err := c.conn.PublishRequest(subject, inbox, payload); err != nil {
+		if errors.Is(err, nats.ErrConnectionClosed) {
+			return nil, ErrClosed
+		}
+		return nil, fmt.Errorf("%w: %v", ErrPublishFailed, err)
+	}
+
+	var res []Reply
+	for len(res) < maxReplies {
+		msg, err := natsSubscription.NextMsgWithContext(ctx)
+		if err != nil {
+			switch {
+			case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+				return res, nil
+			case errors.Is(err, nats.ErrNoResponders):
+				return nil, fmt.Errorf("%w: %s", ErrNoResponders, subject)
+			case errors.Is(err, nats.ErrConnectionClosed), errors.Is(err, nats.ErrBadSubscription):
+				return nil, ErrClosed
+			default:
+				return nil, fmt.Errorf("receiving reply: %w", err)
+			}
+		}
+
+		res = append(res, Reply{Payload: msg.Data, Err: replyError(msg)})
+	}
+
+	return res, nil
+}
+
+// Serve replies to the requests of the subject with the handler until ctx is done.
+// With the queue, every request is handled by one member of the queue group only.
+func (c *Client) Serve(ctx context.Context, subject, queue string, handler RequestHandler) error {
+	natsSubscription, err := c.conn.QueueSubscribe(subject, queue, func(msg *nats.Msg) {
+		reply := &nats.Msg{}
+		payload, serviceErr := c.callRequestHandler(ctx, handler, msg.Subject, msg.Data)
+		if serviceErr != nil {
+			reply.Header = nats.Header{
+				micro.ErrorHeader:     []string{serviceErr.Description},
+				micro.ErrorCodeHeader: []string{serviceErr.Code},
+			}
+		} else {
+			reply.Data = payload
+		}
+
+		if err := msg.RespondMsg(reply); err != nil {
+			c.logger.Error("failed to reply", "subject", msg.Subject, "error", err)
+		}
+	})
+	if err != nil {
+		if errors.Is(err, nats.ErrConnectionClosed) {
+			return ErrClosed
+		}
+		return fmt.Errorf("failed to subscribe: %w", err)
+	}
+
+	c.watchSubscription(ctx, natsSubscription)
+
+	return nil
+}
+
+// AddService registers a NATS micro service, which answers discovery, ping and stats requests
+// until ctx is done.
+func (c *Client) AddService(ctx context.Context, config ServiceConfig) (*Service, error) {
+	service, err := micro.AddService(c.conn, micro.Config{
+		Name:        config.Name,
+		Version:     config.Version,
+		Description: config.Description,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to add service: %w", err)
+	}
+
+	go func() {
+		<-ctx.Done()
+		if err := service.Stop(); err != nil {
+			c.logger.Error("failed to stop service", "service", config.Name, "error", err)
+		}
+	}()
+
+	return &Service{client: c, service: service, ctx: ctx}, nil
+}
+
+// Serve adds the service endpoint replying to the requests of the subject with the handler.
+// The endpoint is named after the subject. Endpoints without the queue use the micro default queue group.
+func (s *Service) Serve(subject, queue string, handler RequestHandler) error {
+	opts := []micro.EndpointOpt{micro.WithEndpointSubject(subject)}
+	if queue != "" {
+		opts = append(opts, micro.WithEndpointQueueGroup(queue))
+	}
+
+	name := endpointNameInvalidChars.ReplaceAllString(subject, "_")
+
+	err := s.service.AddEndpoint(name, micro.HandlerFunc(func(req micro.Request) {
+		payload, serviceErr := s.client.callRequestHandler(s.ctx, handler, req.Subject(), req.Data())
+
+		var err error
+		if serviceErr != nil {
+			err = req.Error(serviceErr.Code, serviceErr.Description, nil)
+		} else {
+			err = req.Respond(payload)
+		}
+		if err != nil {
+			s.client.logger.Error("failed to reply", "subject", req.Subject(), "error", err)
+		}
+	}), opts...)
+	if err != nil {
+		return fmt.Errorf("failed to add endpoint %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// Stats returns the micro service stats: requests, errors and processing time per endpoint.
+func (s *Service) Stats() micro.Stats {
+	return s.service.Stats()
+}
+
+func (c *Client) callRequestHandler(
+	ctx context.Context,
+	handler RequestHandler,
+	subject string,
+	payload []byte,
+) (res []byte, serviceErr *ServiceError) {
+	defer func() {
+		if r := recover(); r != nil {
+			c.logger.Error("failed to handle request", "subject", subject, "error", fmt.Errorf("%w: %v", ErrHandlerPanic, r))
+			res, serviceErr = nil, NewServiceError("500", ErrHandlerPanic.Error())
+		}
+	}()
+
+	res, err := handler(ctx, subject, payload)
+	if err != nil {
+		if !errors.As(err, &serviceErr) {
+			serviceErr = NewServiceError("500", err.Error())
+		}
+		return nil, serviceErr
+	}
+
+	return res, nil
+}
+
+func replyError(msg *nats.Msg) error {
+	description := msg.Header.Get(micro.ErrorHeader)
+	code := msg.Header.Get(micro.ErrorCodeHeader)
+	if description == "" && code == "" {
+		return nil
+	}
+
+	return NewServiceError(code, description)
+}
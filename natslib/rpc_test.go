@@ -0,0 +1,156 @@
+package natslib
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Request(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := connectTestClient(t, runTestServer(t))
+
+	require.NoError(t, client.Serve(ctx, "users.get", "users", func(_ context.Context, _ string, payload []byte) ([]byte, error) {
+		switch string(payload) {
+		case "missing":
+			return nil, NewServiceError("404", "user not found")
+		case "broken":
+			return nil, errors.New("database is down")
+		case "slow":
+			time.Sleep(200 * time.Millisecond)
+		}
+		return append([]byte("user "), payload...), nil
+	}))
+	require.NoError(t, client.conn.Flush())
+
+	reply, err := client.Request(ctx, "users.get", []byte("1"))
+	require.NoError(t, err)
+	require.Equal(t, "user 1", string(reply))
+
+	_, err = client.Request(ctx, "users.get", []byte("missing"))
+	var serviceErr *ServiceError
+	require.ErrorAs(t, err, &serviceErr)
+	require.Equal(t, &ServiceError{Code: "404", Description: "user not found"}, serviceErr)
+
+	_, err = client.Request(ctx, "users.get", []byte("broken"))
+	require.ErrorAs(t, err, &serviceErr)
+	require.Equal(t, "500", serviceErr.Code)
+
+	_, err = client.Request(ctx, "orders.get", []byte("1"))
+	require.ErrorIs(t, err, ErrNoResponders)
+
+	requestCtx, requestCancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer requestCancel()
+	_, err = client.Request(requestCtx, "users.get", []byte("slow"))
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestClient_RequestMany(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := connectTestClient(t, runTestServer(t))
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, client.Serve(ctx, "nodes.status", "", func(context.Context, string, []byte) ([]byte, error) {
+			return []byte(fmt.Sprint(i)), nil
+		}))
+	}
+	require.NoError(t, client.conn.Flush())
+
+	replies, err := client.RequestMany(ctx, "nodes.status", nil, 2)
+	require.NoError(t, err)
+	require.Len(t, replies, 2)
+
+	gatherCtx, gatherCancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer gatherCancel()
+	replies, err = client.RequestMany(gatherCtx, "nodes.status", nil, 10)
+	require.NoError(t, err)
+
+	var payloads []string
+	for _, reply := range replies {
+		require.NoError(t, reply.Err)
+		payloads = append(payloads, string(reply.Payload))
+	}
+	sort.Strings(payloads)
+	require.Equal(t, []string{"0", "1", "2"}, payloads)
+
+	_, err = client.RequestMany(ctx, "nobody", nil, 10)
+	require.ErrorIs(t, err, ErrNoResponders)
+}
+
+func TestClient_RequestMany_Closed(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	url := runTestServer(t)
+	responder := connectTestClient(t, url)
+	client := connectTestClient(t, url)
+
+	require.NoError(t, responder.Serve(ctx, "nodes.status", "", func(context.Context, string, []byte) ([]byte, error) {
+		return []byte("up"), nil
+	}))
+	require.NoError(t, responder.conn.Flush())
+
+	// the connection closed while gathering fails the request rather than returning the partial replies
+	time.AfterFunc(50*time.Millisecond, client.conn.Close)
+	_, err := client.RequestMany(ctx, "nodes.status", nil, 10)
+	require.ErrorIs(t, err, ErrClosed)
+}
+
+func TestService(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := connectTestClient(t, runTestServer(t))
+
+	service, err := client.AddService(ctx, ServiceConfig{Name: "users", Version: "1.0.0"})
+	require.NoError(t, err)
+
+	require.NoError(t, service.Serve("users.get", "", func(_ context.Context, _ string, payload []byte) ([]byte, error) {
+		if len(payload) == 0 {
+			return nil, NewServiceError("400", "no user id")
+		}
+		return payload, nil
+	}))
+
+	reply, err := client.Request(ctx, "users.get", []byte("1"))
+	require.NoError(t, err)
+	require.Equal(t, "1", string(reply))
+
+	_, err = client.Request(ctx, "users.get", nil)
+	require.Equal(t, NewServiceError("400", "no user id"), err)
+
+	ping, err := client.Request(ctx, "$SRV.PING.users", nil)
+	require.NoError(t, err)
+
+	var pong struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+	require.NoError(t, json.Unmarshal(ping, &pong))
+	require.Equal(t, "users", pong.Name)
+	require.Equal(t, "1.0.0", pong.Version)
+
+	stats := service.Stats()
+	require.Len(t, stats.Endpoints, 1)
+	require.Equal(t, "users_get", stats.Endpoints[0].Name)
+	require.Equal(t, 2, stats.Endpoints[0].NumRequests)
+	require.Equal(t, 1, stats.Endpoints[0].NumErrors)
+}
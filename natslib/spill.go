@@ -0,0 +1,189 @@
+package natslib
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/nats-io/nats.go"
+)
+
+// spillQueue is an append-only file of the messages published while disconnected.
+// The file is truncated when all the messages are replayed, the messages left
+// by a previous process are replayed after connect.
+type spillQueue struct {
+	mux sync.Mutex
+
+	file    *os.File
+	maxSize int64
+
+	size       int64
+	readOffset int64
+	len        int
+}
+
+type spilledMessage struct {
+	Subject string      `json:"subject"`
+	Reply   string      `json:"reply,omitempty"`
+	Header  nats.Header `json:"header,omitempty"`
+	Data    []byte      `json:"data"`
+}
+
+// spillRecordHeaderSize is the size of the record length prefix.
+const spillRecordHeaderSize = 4
+
+var errSpillQueueFull = errors.New("spill queue is full")
+
+func openSpillQueue(path string, maxSize int64) (*spillQueue, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("opening spill file: %w", err)
+	}
+
+	q := &spillQueue{file: file, maxSize: maxSize}
+
+	// count the messages left by a previous process, a torn last record is dropped
+	for {
+		_, n, err := q.readAt(q.size)
+		if err != nil {
+			break
+		}
+		q.size += n
+		q.len++
+	}
+
+	if err := file.Truncate(q.size); err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("truncating spill file: %w", err)
+	}
+
+	return q, nil
+}
+
+func (q *spillQueue) push(msg *Message) error {
+	record, err := json.Marshal(spilledMessage(*msg))
+	if err != nil {
+		return fmt.Errorf("encoding message: %w", err)
+	}
+
+	buf := make([]byte, spillRecordHeaderSize, spillRecordHeaderSize+len(record))
+	binary.BigEndian.PutUint32(buf, uint32(len(record)))
+	buf = append(buf, record...)
+
+	q.mux.Lock()
+	defer q.mux.Unlock()
+
+	if q.maxSize > 0 && q.size+int64(len(buf)) > q.maxSize {
+		return errSpillQueueFull
+	}
+
+	if _, err := q.file.WriteAt(buf, q.size); err != nil {
+		return fmt.Errorf("writing spill file: %w", err)
+	}
+
+	q.size += int64(len(buf))
+	q.len++
+
+	return nil
+}
+
+// replay publishes the messages in order until the queue is empty or publish fails.
+// A message is removed after it is published, so the failed one is replayed next time.
+func (q *spillQueue) replay(publish func(*Message) error) (int, error) {
+	var replayed int
+
+	for {
+		msg, ok, err := q.peek()
+		if err != nil || !ok {
+			return replayed, err
+		}
+
+		if err := publish(msg); err != nil {
+			return replayed, err
+		}
+
+		if err := q.pop(); err != nil {
+			return replayed, err
+		}
+		replayed++
+	}
+}
+
+func (q *spillQueue) peek() (*Message, bool, error) {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+
+	if q.len == 0 {
+		return nil, false, nil
+	}
+
+	msg, _, err := q.readAt(q.readOffset)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return msg, true, nil
+}
+
+func (q *spillQueue) pop() error {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+
+	_, n, err := q.readAt(q.readOffset)
+	if err != nil {
+		return err
+	}
+
+	q.readOffset += n
+	q.len--
+
+	if q.len > 0 {
+		return nil
+	}
+
+	q.size, q.readOffset = 0, 0
+	if err := q.file.Truncate(0); err != nil {
+		return fmt.Errorf("truncating spill file: %w", err)
+	}
+
+	return nil
+}
+
+func (q *spillQueue) length() int {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+
+	return q.len
+}
+
+func (q *spillQueue) close() error {
+	return q.file.Close()
+}
+
+func (q *spillQueue) readAt(offset int64) (*Message, int64, error) {
+	r := bufio.NewReader(io.NewSectionReader(q.file, offset, 1<<62))
+
+	header := make([]byte, spillRecordHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, 0, fmt.Errorf("reading spill record: %w", err)
+	}
+
+	record := make([]byte, binary.BigEndian.Uint32(header))
+	if _, err := io.ReadFull(r, record); err != nil {
+		return nil, 0, fmt.Errorf("reading spill record: %w", err)
+	}
+
+	var msg spilledMessage
+	if err := json.Unmarshal(record, &msg); err != nil {
+		return nil, 0, fmt.Errorf("decoding spill record: %w", err)
+	}
+
+	res := Message(msg)
+
+	return &res, int64(spillRecordHeaderSize + len(record)), nil
+}
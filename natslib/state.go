@@ -0,0 +1,111 @@
+package natslib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+type (
+	// State is the connection state reported to the StateChangeHandler.
+	State string
+
+	StateChange struct {
+		State State
+		// URL is the server the client is connected to, empty when not connected.
+		URL string
+		// Err is the disconnect reason, nil for the other states.
+		Err error
+	}
+
+	// StateChangeHandler is called sequentially in the order of the state changes,
+	// so it must not block.
+	StateChangeHandler func(change StateChange)
+)
+
+const (
+	StateConnected    State = "connected"
+	StateDisconnected State = "disconnected"
+	StateReconnecting State = "reconnecting"
+	StateClosed       State = "closed"
+	// StateLameDuck is reported when the server is going to shut down and disconnect the client soon.
+	StateLameDuck State = "lame_duck"
+)
+
+// defaultReadyTimeout limits the Ready server round trip when ctx has no deadline.
+const defaultReadyTimeout = 5 * time.Second
+
+// ErrNotConnected is returned by Ready when the client is not connected to the server.
+var ErrNotConnected = errors.New("not connected")
+
+// SetStateChangeHandler sets the handler called on connection state changes.
+// The initial connect is not reported, Connect fails when it is not established.
+func (c *Client) SetStateChangeHandler(handler StateChangeHandler) {
+	c.stateChangeHandler.Store(&handler)
+}
+
+// Ready checks that the client is connected and the server replies within ctx.
+// It is compatible with the infraserver.Server.Run readiness checks.
+func (c *Client) Ready(ctx context.Context) error {
+	if status := c.conn.Status(); status != nats.CONNECTED {
+		return fmt.Errorf("%w: %s", ErrNotConnected, status)
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultReadyTimeout)
+		defer cancel()
+	}
+
+	if err := c.conn.FlushWithContext(ctx); err != nil {
+		return fmt.Errorf("%w: %v", ErrNotConnected, err)
+	}
+
+	return nil
+}
+
+func (c *Client) stateOptions() []nats.Option {
+	return []nats.Option{
+		// Reports the error in case when the connection is disconnected.
+		// In that case subscription.IsValid() returns true until max reconnect
+		// and reconnect wait will be exceeded, and we have to report the error
+		// immediately in that handler.
+		nats.DisconnectErrHandler(func(nc *nats.Conn, err error) {
+			c.changeState(StateDisconnected, "", err)
+			if nc.IsReconnecting() {
+				c.changeState(StateReconnecting, "", nil)
+			}
+		}),
+		nats.ReconnectHandler(func(nc *nats.Conn) {
+			c.changeState(StateConnected, nc.ConnectedUrlRedacted(), nil)
+			go c.replaySpilled()
+		}),
+		nats.ClosedHandler(func(*nats.Conn) {
+			c.connClosedOnce.Do(func() { close(c.connClosed) })
+			c.changeState(StateClosed, "", nil)
+		}),
+		nats.LameDuckModeHandler(func(nc *nats.Conn) {
+			c.changeState(StateLameDuck, nc.ConnectedUrlRedacted(), nil)
+		}),
+	}
+}
+
+func (c *Client) changeState(state State, url string, err error) {
+	switch {
+	case err != nil && !errors.Is(err, io.EOF):
+		// a client losing the connection is not an unexpected error
+		c.logger.Error("nats package disconnect error", "error", err)
+	case state == StateConnected:
+		c.logger.Info("nats connection restored", "url", url)
+	default:
+		c.logger.Debug("nats connection state changed", "state", state)
+	}
+
+	if handler := c.stateChangeHandler.Load(); handler != nil {
+		(*handler)(StateChange{State: state, URL: url, Err: err})
+	}
+}
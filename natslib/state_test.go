@@ -0,0 +1,105 @@
+package natslib
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_StateChange(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	srv := startTestServer(t, &server.Options{Port: server.RANDOM_PORT})
+	port := srv.Addr().(*net.TCPAddr).Port
+
+	// the counters of the closed clients are kept, so every run has its own name
+	name := "state-test-" + nuid.Next()
+	client, err := Connect(ctx, Config{
+		Name:                             name,
+		URL:                              srv.ClientURL(),
+		DrainTimeout:                     time.Second,
+		SubCheckPeriod:                   time.Second,
+		MaxReconnect:                     -1,
+		ReconnectWait:                    50 * time.Millisecond,
+		SubscriptionPendingMessagesLimit: 1000,
+	}, slog.Default())
+	require.NoError(t, err)
+
+	chanStates := make(chan State, 10)
+	client.SetStateChangeHandler(func(change StateChange) {
+		chanStates <- change.State
+	})
+
+	_, err = client.Subscribe(ctx, "orders")
+	require.NoError(t, err)
+	require.NoError(t, client.Publish(ctx, "orders", []byte("1")))
+	require.NoError(t, client.Ready(ctx))
+
+	srv.Shutdown()
+	requireStates(t, chanStates, StateDisconnected, StateReconnecting)
+	require.ErrorIs(t, client.Ready(ctx), ErrNotConnected)
+
+	startTestServer(t, &server.Options{Port: port})
+	requireStates(t, chanStates, StateConnected)
+	require.NoError(t, client.Ready(ctx))
+
+	metrics := gatherMetrics(t, name)
+	require.Equal(t, 1.0, metrics["nats_connection_status"])
+	require.Equal(t, 1.0, metrics["nats_reconnects_total"])
+	require.Equal(t, 1.0, metrics["nats_out_messages_total"])
+	require.Contains(t, metrics, "nats_subscription_pending_messages")
+
+	client.Close()
+	requireStates(t, chanStates, StateDisconnected, StateClosed)
+
+	// the counters of the closed client do not go down
+	metrics = gatherMetrics(t, name)
+	require.Zero(t, metrics["nats_connection_status"])
+	require.Equal(t, 1.0, metrics["nats_reconnects_total"])
+	require.Equal(t, 1.0, metrics["nats_out_messages_total"])
+	require.NotContains(t, metrics, "nats_subscription_pending_messages")
+}
+
+func requireStates(t *testing.T, chanStates <-chan State, states ...State) {
+	t.Helper()
+
+	for _, state := range states {
+		select {
+		case got := <-chanStates:
+			require.Equal(t, state, got)
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "no state change", "expected %s", state)
+		}
+	}
+}
+
+// gatherMetrics returns the values of the natslib metrics of the client by the metric name.
+func gatherMetrics(t *testing.T, client string) map[string]float64 {
+	t.Helper()
+
+	families, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+
+	res := map[string]float64{}
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "client" && label.GetValue() == client {
+					res[family.GetName()] = metric.GetGauge().GetValue() + metric.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+
+	return res
+}
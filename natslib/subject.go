@@ -0,0 +1,57 @@
+package natslib
+
+import (
+	"strings"
+)
+
+const (
+	subjectSeparator = "."
+	wildcardToken    = "*"
+	wildcardTail     = ">"
+)
+
+// SubjectTokens splits the subject into its dot-separated tokens.
+func SubjectTokens(subject string) []string {
+	return strings.Split(subject, subjectSeparator)
+}
+
+// MatchSubject reports whether the subject matches the pattern with * and > wildcards.
+func MatchSubject(pattern, subject string) bool {
+	_, ok := SubjectWildcards(pattern, subject)
+	return ok
+}
+
+// SubjectWildcards returns the subject tokens matched by the pattern wildcards in order,
+// e.g. ["42"] for the pattern "orders.*.created" and the subject "orders.42.created".
+// The tail matched by > is returned as one dot-separated value.
+// It returns false if the subject does not match the pattern.
+func SubjectWildcards(pattern, subject string) ([]string, bool) {
+	patternTokens := SubjectTokens(pattern)
+	subjectTokens := SubjectTokens(subject)
+
+	res := []string{}
+	for i, token := range patternTokens {
+		switch {
+		case token == wildcardTail && i == len(patternTokens)-1:
+			if i >= len(subjectTokens) {
+				return nil, false
+			}
+			return append(res, strings.Join(subjectTokens[i:], subjectSeparator)), true
+
+		case i >= len(subjectTokens):
+			return nil, false
+
+		case token == wildcardToken:
+			res = append(res, subjectTokens[i])
+
+		case token != subjectTokens[i]:
+			return nil, false
+		}
+	}
+
+	if len(subjectTokens) != len(patternTokens) {
+		return nil, false
+	}
+
+	return res, true
+}
@@ -0,0 +1,37 @@
+package natslib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubjectWildcards(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		pattern string
+		subject string
+		want    []string
+		ok      bool
+	}{
+		{pattern: "orders.*.created", subject: "orders.42.created", want: []string{"42"}, ok: true},
+		{pattern: "orders.*.*", subject: "orders.42.created", want: []string{"42", "created"}, ok: true},
+		{pattern: "orders.>", subject: "orders.42.items.7", want: []string{"42.items.7"}, ok: true},
+		{pattern: "*.*.>", subject: "orders.42.items.7", want: []string{"orders", "42", "items.7"}, ok: true},
+		{pattern: "orders.created", subject: "orders.created", want: []string{}, ok: true},
+		{pattern: "orders.>", subject: "orders"},
+		{pattern: "orders.*.created", subject: "orders.42.deleted"},
+		{pattern: "orders.*", subject: "orders.42.created"},
+		{pattern: "orders.*.created", subject: "orders.42"},
+	}
+
+	for _, tt := range tests {
+		got, ok := SubjectWildcards(tt.pattern, tt.subject)
+		require.Equal(t, tt.ok, ok, "%s %s", tt.pattern, tt.subject)
+		require.Equal(t, tt.want, got, "%s %s", tt.pattern, tt.subject)
+		require.Equal(t, tt.ok, MatchSubject(tt.pattern, tt.subject))
+	}
+
+	require.Equal(t, []string{"orders", "42", "created"}, SubjectTokens("orders.42.created"))
+}
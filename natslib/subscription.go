@@ -4,28 +4,235 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/nats-io/nats.go"
 )
 
-type Subscription struct {
-	natsSubscription *nats.Subscription
+type (
+	// Subscription receives the messages of a subject until its ctx is done or the client is closed.
+	// Done and Err report when and why it ended.
+	Subscription struct {
+		client  *Client
+		subject string
+		opts    SubscribeOptions
+
+		mux              sync.Mutex
+		natsSubscription *nats.Subscription
+		// chanResubscribed is closed when natsSubscription is replaced
+		chanResubscribed chan struct{}
+		resubscribes     int
+		err              error
+
+		delivered atomic.Uint64
+		done      chan struct{}
+	}
+
+	SubscribeOptions struct {
+		// Queue makes the subscription a member of the queue group, so every message is delivered to one member only.
+		Queue string
+		// Resubscribe re-creates the subscription when it is closed while the client is still open,
+		// e.g. by the server. The attempts are repeated every Config.SubCheckPeriod.
+		Resubscribe bool
+	}
+
+	SubscriptionStats struct {
+		// Delivered is the number of messages returned by Receive.
+		Delivered uint64
+		// Dropped is the number of messages dropped over the pending limits.
+		Dropped int
+		// Pending is the number of messages received but not returned by Receive yet.
+		Pending int
+		// Resubscribes is the number of times the subscription was re-created.
+		Resubscribes int
+	}
+)
+
+// ErrSubscriptionClosed is reported by Subscription.Err when the subscription was closed while the client is open.
+var ErrSubscriptionClosed = errors.New("subscription closed")
+
+// SubscribeWithOptions subscribes to the NATS server, the subscription is removed when ctx is done.
+func (c *Client) SubscribeWithOptions(ctx context.Context, topic string, opts SubscribeOptions) (*Subscription, error) {
+	natsSubscription, err := c.subscribeSync(topic, opts.Queue)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Subscription{
+		client:           c,
+		subject:          topic,
+		opts:             opts,
+		natsSubscription: natsSubscription,
+		chanResubscribed: make(chan struct{}),
+		done:             make(chan struct{}),
+	}
+
+	c.subscriptions.Store(natsSubscription, struct{}{})
+	chanClosed := subscriptionClosed(natsSubscription)
+
+	c.watchers.Add(1)
+	go func() {
+		defer c.watchers.Done()
+		s.run(ctx, chanClosed)
+	}()
+
+	return s, nil
 }
 
 func (s *Subscription) Receive(ctx context.Context) ([]byte, error) {
-	msg, err := s.natsSubscription.NextMsgWithContext(ctx)
+	msg, err := s.ReceiveMsg(ctx)
 	if err != nil {
-		if errors.Is(err, context.Canceled) {
-			return nil, err //nolint:wrapcheck // intentionally pass as-is
+		return nil, err
+	}
+	return msg.Data, nil
+}
+
+// ReceiveMsg waits for the next message and returns it with the subject, reply subject and headers.
+// With Resubscribe, it waits for the subscription to be re-created after it is closed.
+// Once the subscription ended, it returns the same error as Err.
+func (s *Subscription) ReceiveMsg(ctx context.Context) (*Message, error) {
+	for {
+		natsSubscription, chanResubscribed := s.current()
+
+		msg, err := natsSubscription.NextMsgWithContext(ctx)
+		if err == nil {
+			s.delivered.Add(1)
+
+			res := fromNatsMsg(msg)
+			return &res, nil
+		}
+
+		if ctx.Err() != nil || !errors.Is(err, nats.ErrBadSubscription) {
+			return nil, receiveError(err)
+		}
+
+		select {
+		case <-chanResubscribed:
+		case <-s.done:
+			return nil, s.Err()
+		case <-ctx.Done():
+			return nil, ctx.Err()
 		}
+	}
+}
+
+// Done is closed when the subscription ended, it is not closed while the subscription is being re-created.
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err returns why the subscription ended: the ctx error, ErrSubscriptionClosed or ErrClosed
+// when the client was closed. It is nil while the subscription is active.
+func (s *Subscription) Err() error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	return s.err
+}
+
+// Stats returns the subscription counters. Dropped and Pending start over when the subscription is re-created.
+func (s *Subscription) Stats() SubscriptionStats {
+	natsSubscription, _ := s.current()
 
-		// nats.ErrBadSubscription is used by the nats library when the subscription is closed or is nil.
-		// We assume "closed" in both cases.
-		if errors.Is(err, nats.ErrConnectionClosed) || errors.Is(err, nats.ErrBadSubscription) {
+	s.mux.Lock()
+	res := SubscriptionStats{
+		Delivered:    s.delivered.Load(),
+		Resubscribes: s.resubscribes,
+	}
+	s.mux.Unlock()
+
+	if dropped, err := natsSubscription.Dropped(); err == nil {
+		res.Dropped = dropped
+	}
+	if pending, _, err := natsSubscription.Pending(); err == nil {
+		res.Pending = pending
+	}
+
+	return res
+}
+
+func (s *Subscription) current() (*nats.Subscription, <-chan struct{}) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	return s.natsSubscription, s.chanResubscribed
+}
+
+// run waits for the subscription end and re-creates it with Resubscribe.
+func (s *Subscription) run(ctx context.Context, chanClosed <-chan struct{}) {
+	for {
+		natsSubscription, _ := s.current()
+
+		err := s.client.waitSubscriptionEnd(ctx, natsSubscription, chanClosed)
+		s.client.subscriptions.Delete(natsSubscription)
+
+		if errors.Is(err, ErrSubscriptionClosed) && s.opts.Resubscribe {
+			s.client.logger.Warn("subscription closed, resubscribing", "subject", s.subject)
+
+			if chanClosed, err = s.resubscribe(ctx); err == nil {
+				continue
+			}
+		}
+
+		s.mux.Lock()
+		s.err = err
+		s.mux.Unlock()
+		close(s.done)
+
+		return
+	}
+}
+
+// resubscribe tries to re-create the subscription until it succeeds, ctx is done or the client is closed.
+func (s *Subscription) resubscribe(ctx context.Context) (<-chan struct{}, error) {
+	ticker := time.NewTicker(s.client.subCheckPeriod)
+	defer ticker.Stop()
+
+	for {
+		natsSubscription, err := s.client.subscribeSync(s.subject, s.opts.Queue)
+		if err == nil {
+			s.client.subscriptions.Store(natsSubscription, struct{}{})
+			chanClosed := subscriptionClosed(natsSubscription)
+
+			s.mux.Lock()
+			s.natsSubscription = natsSubscription
+			close(s.chanResubscribed)
+			s.chanResubscribed = make(chan struct{})
+			s.resubscribes++
+			s.mux.Unlock()
+
+			return chanClosed, nil
+		}
+		if errors.Is(err, ErrClosed) {
 			return nil, ErrClosed
 		}
 
-		return nil, fmt.Errorf("subscription next msg: %w", err)
+		s.client.logger.Error("failed to resubscribe", "subject", s.subject, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-s.client.done:
+			return nil, ErrClosed
+		case <-s.client.connClosed:
+			return nil, ErrClosed
+		case <-ticker.C:
+		}
 	}
-	return msg.Data, nil
+}
+
+func receiveError(err error) error {
+	if errors.Is(err, context.Canceled) {
+		return err //nolint:wrapcheck // intentionally pass as-is
+	}
+
+	// nats.ErrBadSubscription is used by the nats library when the subscription is closed or is nil.
+	// We assume "closed" in both cases.
+	if errors.Is(err, nats.ErrConnectionClosed) || errors.Is(err, nats.ErrBadSubscription) {
+		return ErrClosed
+	}
+
+	return fmt.Errorf("subscription next msg: %w", err)
 }
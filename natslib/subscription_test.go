@@ -0,0 +1,124 @@
+package natslib
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscription_Resubscribe(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	url := runTestServer(t)
+	client := connectTestClient(t, url)
+	publisher := connectTestClient(t, url)
+
+	sub, err := client.SubscribeWithOptions(ctx, "events", SubscribeOptions{Resubscribe: true})
+	require.NoError(t, err)
+	require.NoError(t, client.Ready(ctx))
+
+	natsSubscription, _ := sub.current()
+	require.NoError(t, natsSubscription.Unsubscribe())
+
+	require.Eventually(t, func() bool {
+		return sub.Stats().Resubscribes == 1
+	}, 5*time.Second, time.Millisecond)
+	require.NoError(t, client.Ready(ctx))
+	require.NoError(t, sub.Err())
+
+	require.NoError(t, publisher.Publish(ctx, "events", []byte("event")))
+
+	data, err := sub.Receive(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []byte("event"), data)
+	require.Equal(t, uint64(1), sub.Stats().Delivered)
+
+	client.Close()
+
+	<-sub.Done()
+	require.ErrorIs(t, sub.Err(), ErrClosed)
+	requireNoSubscriptions(t, client)
+}
+
+func TestSubscription_Closed(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := connectTestClient(t, runTestServer(t))
+
+	sub, err := client.Subscribe(ctx, "events")
+	require.NoError(t, err)
+
+	natsSubscription, _ := sub.current()
+	require.NoError(t, natsSubscription.Unsubscribe())
+
+	<-sub.Done()
+	require.ErrorIs(t, sub.Err(), ErrSubscriptionClosed)
+
+	_, err = sub.Receive(ctx)
+	require.ErrorIs(t, err, ErrSubscriptionClosed)
+	requireNoSubscriptions(t, client)
+}
+
+func TestSubscription_Canceled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := connectTestClient(t, runTestServer(t))
+
+	subCtx, subCancel := context.WithCancel(ctx)
+	sub, err := client.SubscribeWithOptions(subCtx, "events", SubscribeOptions{Resubscribe: true})
+	require.NoError(t, err)
+
+	select {
+	case <-sub.Done():
+		require.Fail(t, "subscription ended")
+	default:
+	}
+	require.NoError(t, sub.Err())
+
+	subCancel()
+
+	<-sub.Done()
+	require.ErrorIs(t, sub.Err(), context.Canceled)
+	require.Zero(t, sub.Stats().Resubscribes)
+	requireNoSubscriptions(t, client)
+}
+
+func TestSubscription_Stats(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	url := runTestServer(t)
+	client := connectTestClient(t, url)
+	publisher := connectTestClient(t, url)
+
+	sub, err := client.Subscribe(ctx, "events")
+	require.NoError(t, err)
+	require.NoError(t, client.Ready(ctx))
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, publisher.Publish(ctx, "events", []byte("event")))
+	}
+	require.NoError(t, publisher.Ready(ctx))
+
+	require.Eventually(t, func() bool {
+		return sub.Stats().Pending == 3
+	}, time.Second, time.Millisecond)
+
+	_, err = sub.Receive(ctx)
+	require.NoError(t, err)
+
+	require.Equal(t, SubscriptionStats{Delivered: 1, Pending: 2}, sub.Stats())
+}
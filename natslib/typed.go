@@ -0,0 +1,139 @@
+package natslib
+
+import "context"
+
+type (
+	// TypedSubject publishes and receives the values of T encoded by the codec.
+	TypedSubject[T any] struct {
+		client  *Client
+		subject string
+		codec   Codec
+	}
+
+	// TypedSubscription receives the decoded messages of a TypedSubject.
+	TypedSubscription[T any] struct {
+		subscription *Subscription
+		codec        Codec
+	}
+
+	// TypedMessage is the received message with its decoded value.
+	TypedMessage[T any] struct {
+		Message
+		Value T
+	}
+
+	// TypedEndpoint sends the requests of Req and replies with Resp on the subject.
+	TypedEndpoint[Req, Resp any] struct {
+		client  *Client
+		subject string
+		codec   Codec
+	}
+
+	// TypedRequestHandler returns the reply of a request.
+	// A returned *ServiceError is sent to the requester as is, other errors are sent with code 500.
+	TypedRequestHandler[Req, Resp any] func(ctx context.Context, subject string, req Req) (Resp, error)
+)
+
+// NewTypedSubject returns the subject of T, the JSONCodec is used when the codec is nil.
+// The subject may contain wildcards for subscribing only.
+func NewTypedSubject[T any](client *Client, subject string, codec Codec) *TypedSubject[T] {
+	if codec == nil {
+		codec = JSONCodec{}
+	}
+
+	return &TypedSubject[T]{client: client, subject: subject, codec: codec}
+}
+
+// Publish encodes and publishes the value, encoding errors match ErrEncode.
+func (s *TypedSubject[T]) Publish(ctx context.Context, value T) error {
+	data, err := encode(s.codec, value)
+	if err != nil {
+		return err
+	}
+
+	return s.client.PublishMsg(ctx, &Message{Subject: s.subject, Data: data})
+}
+
+// Subscribe subscribes to the subject until ctx is done.
+func (s *TypedSubject[T]) Subscribe(ctx context.Context) (*TypedSubscription[T], error) {
+	return s.QueueSubscribe(ctx, "")
+}
+
+// QueueSubscribe subscribes to the subject as a member of the queue group until ctx is done.
+func (s *TypedSubject[T]) QueueSubscribe(ctx context.Context, queue string) (*TypedSubscription[T], error) {
+	sub, err := s.client.QueueSubscribe(ctx, s.subject, queue)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TypedSubscription[T]{subscription: sub, codec: s.codec}, nil
+}
+
+// Receive waits for the next message and decodes it.
+// A message which fails to decode is returned as *DecodeError, the next Receive continues with the following message.
+func (s *TypedSubscription[T]) Receive(ctx context.Context) (*TypedMessage[T], error) {
+	msg, err := s.subscription.ReceiveMsg(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := decode[T](s.codec, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TypedMessage[T]{Message: *msg, Value: value}, nil
+}
+
+// NewTypedEndpoint returns the request/reply subject, the JSONCodec is used when the codec is nil.
+func NewTypedEndpoint[Req, Resp any](client *Client, subject string, codec Codec) *TypedEndpoint[Req, Resp] {
+	if codec == nil {
+		codec = JSONCodec{}
+	}
+
+	return &TypedEndpoint[Req, Resp]{client: client, subject: subject, codec: codec}
+}
+
+// Request sends the request and waits for the reply until ctx is done.
+// Encoding errors match ErrEncode, a reply which fails to decode is returned as *DecodeError,
+// the other errors are the ones of Client.Request.
+func (e *TypedEndpoint[Req, Resp]) Request(ctx context.Context, req Req) (Resp, error) {
+	var res Resp
+
+	data, err := encode(e.codec, req)
+	if err != nil {
+		return res, err
+	}
+
+	reply, err := e.client.Request(ctx, e.subject, data)
+	if err != nil {
+		return res, err
+	}
+
+	return decode[Resp](e.codec, &Message{Subject: e.subject, Data: reply})
+}
+
+// Serve replies to the requests with the handler until ctx is done.
+// The requests which fail to decode are replied with the service error 400.
+// With the queue, every request is handled by one member of the queue group only.
+func (e *TypedEndpoint[Req, Resp]) Serve(ctx context.Context, queue string, handler TypedRequestHandler[Req, Resp]) error {
+	return e.client.Serve(ctx, e.subject, queue, func(ctx context.Context, subject string, payload []byte) ([]byte, error) {
+		req, err := decode[Req](e.codec, &Message{Subject: subject, Data: payload})
+		if err != nil {
+			return nil, NewServiceError("400", err.Error())
+		}
+
+		resp, err := handler(ctx, subject, req)
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := encode(e.codec, resp)
+		if err != nil {
+			e.client.logger.Error("failed to reply", "subject", subject, "error", err)
+			return nil, err
+		}
+
+		return data, nil
+	})
+}
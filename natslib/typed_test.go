@@ -0,0 +1,120 @@
+package natslib
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type testOrder struct {
+	ID    string `json:"id" msgpack:"id"`
+	Items int    `json:"items" msgpack:"items"`
+}
+
+func TestTypedSubject(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := connectTestClient(t, runTestServer(t))
+
+	for name, codec := range map[string]Codec{"json": nil, "msgpack": MsgpackCodec{}} {
+		subject := NewTypedSubject[testOrder](client, "orders."+name, codec)
+
+		sub, err := subject.Subscribe(ctx)
+		require.NoError(t, err)
+		require.NoError(t, client.conn.Flush())
+
+		require.NoError(t, client.Publish(ctx, "orders."+name, []byte("{broken")))
+		require.NoError(t, subject.Publish(ctx, testOrder{ID: "1", Items: 2}))
+
+		// the decode error is reported with the message and the subscription goes on
+		_, err = sub.Receive(ctx)
+		var decodeErr *DecodeError
+		require.ErrorAs(t, err, &decodeErr, name)
+		require.ErrorIs(t, err, ErrDecode)
+		require.Equal(t, "{broken", string(decodeErr.Message.Data))
+
+		msg, err := sub.Receive(ctx)
+		require.NoError(t, err, name)
+		require.Equal(t, testOrder{ID: "1", Items: 2}, msg.Value)
+		require.Equal(t, "orders."+name, msg.Subject)
+	}
+
+	err := NewTypedSubject[func()](client, "funcs", nil).Publish(ctx, func() {})
+	require.ErrorIs(t, err, ErrEncode)
+
+	client.Close()
+	err = NewTypedSubject[testOrder](client, "orders", nil).Publish(ctx, testOrder{})
+	require.ErrorIs(t, err, ErrClosed)
+	require.NotErrorIs(t, err, ErrEncode)
+}
+
+func TestTypedEndpoint(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := connectTestClient(t, runTestServer(t))
+
+	endpoint := NewTypedEndpoint[*wrapperspb.StringValue, *wrapperspb.Int64Value](client, "strings.len", ProtobufCodec{})
+	require.NoError(t, endpoint.Serve(ctx, "", func(_ context.Context, _ string, req *wrapperspb.StringValue) (*wrapperspb.Int64Value, error) {
+		if req.GetValue() == "" {
+			return nil, NewServiceError("422", "empty string")
+		}
+		return wrapperspb.Int64(int64(len(req.GetValue()))), nil
+	}))
+	require.NoError(t, client.conn.Flush())
+
+	resp, err := endpoint.Request(ctx, wrapperspb.String("hello"))
+	require.NoError(t, err)
+	require.Equal(t, int64(5), resp.GetValue())
+
+	_, err = endpoint.Request(ctx, wrapperspb.String(""))
+	var serviceErr *ServiceError
+	require.ErrorAs(t, err, &serviceErr)
+	require.Equal(t, "422", serviceErr.Code)
+
+	// a malformed request is rejected by the responder
+	_, err = client.Request(ctx, "strings.len", []byte{0xff})
+	require.ErrorAs(t, err, &serviceErr)
+	require.Equal(t, "400", serviceErr.Code)
+
+	// a malformed reply is a decode error of the requester
+	require.NoError(t, client.Serve(ctx, "strings.broken", "", func(context.Context, string, []byte) ([]byte, error) {
+		return []byte{0xff}, nil
+	}))
+	require.NoError(t, client.conn.Flush())
+
+	broken := NewTypedEndpoint[*wrapperspb.StringValue, *wrapperspb.Int64Value](client, "strings.broken", ProtobufCodec{})
+	_, err = broken.Request(ctx, wrapperspb.String("hello"))
+	require.ErrorIs(t, err, ErrDecode)
+
+	requestCtx, requestCancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer requestCancel()
+	_, err = NewTypedEndpoint[string, string](client, "nobody", nil).Request(requestCtx, "hello")
+	require.True(t, errors.Is(err, ErrNoResponders) || errors.Is(err, context.DeadlineExceeded))
+	require.NotErrorIs(t, err, ErrDecode)
+}
+
+func TestProtobufCodec(t *testing.T) {
+	t.Parallel()
+
+	data, err := ProtobufCodec{}.Marshal(wrapperspb.String("value"))
+	require.NoError(t, err)
+
+	// the message pointer is allocated
+	var msg *wrapperspb.StringValue
+	require.NoError(t, ProtobufCodec{}.Unmarshal(data, &msg))
+	require.Equal(t, "value", msg.GetValue())
+
+	_, err = ProtobufCodec{}.Marshal(testOrder{})
+	require.ErrorIs(t, err, ErrNotProtoMessage)
+	require.ErrorIs(t, ProtobufCodec{}.Unmarshal(data, &testOrder{}), ErrNotProtoMessage)
+}
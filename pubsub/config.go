@@ -0,0 +1,173 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/yvyrovyi-cinemo/utils/config"
+	"github.com/yvyrovyi-cinemo/utils/kafkalib"
+	"github.com/yvyrovyi-cinemo/utils/natslib"
+)
+
+const (
+	TransportKafka     = "kafka"
+	TransportNATS      = "nats"
+	TransportJetStream = "jetstream"
+	TransportMemory    = "memory"
+)
+
+type (
+	Config struct {
+		// Transport is "kafka", "nats", "jetstream" or "memory" (default).
+		// The memory transport delivers the messages within the process only.
+		Transport string `env:"TRANSPORT" envDefault:"memory" json:"transport" yaml:"transport" default:"memory" validate:"oneof=kafka nats jetstream memory"`
+
+		// Topics are the Kafka topics or the NATS subjects the subscriber reads.
+		// The NATS subjects may contain wildcards, JetStream filters at most one subject,
+		// all the stream subjects are read when it is empty.
+		Topics []string `env:"TOPICS" envSeparator:"," json:"topics" yaml:"topics"`
+
+		// Group shares the messages among the subscribers: the Kafka consumer group,
+		// the NATS queue group or the JetStream durable consumer.
+		Group string `env:"GROUP" json:"group" yaml:"group"`
+
+		// Kafka is validated with the kafka transport only, see Validate.
+		Kafka     kafkalib.Config `envPrefix:"KAFKA_" json:"kafka" yaml:"kafka" validate:"-"`
+		NATS      natslib.Config  `envPrefix:"NATS_" json:"nats" yaml:"nats"`
+		JetStream JetStreamConfig `envPrefix:"JETSTREAM_" json:"jetstream" yaml:"jetstream"`
+	}
+
+	JetStreamConfig struct {
+		// Stream is the existing stream the subscriber reads, its durable pull consumer Config.Group
+		// is created or updated by NewSubscriber.
+		Stream string `env:"STREAM" json:"stream" yaml:"stream"`
+		// Batch is the number of messages fetched at once.
+		Batch int `env:"BATCH" envDefault:"100" json:"batch" yaml:"batch" default:"100"`
+
+		// Consumer configures the durable consumer, its Stream, Durable and FilterSubject are taken from the Config.
+		Consumer natslib.ConsumerConfig `envPrefix:"CONSUMER_" json:"consumer" yaml:"consumer"`
+	}
+)
+
+// NewPublisher connects the publisher of the configured transport.
+func NewPublisher(ctx context.Context, config Config, logger *slog.Logger) (Publisher, error) {
+	switch config.transport() {
+	case TransportKafka:
+		producer, err := kafkalib.NewProducer(kafkalib.ProducerConfig{Config: config.Kafka}, logger)
+		if err != nil {
+			return nil, fmt.Errorf("creating kafka producer: %w", err)
+		}
+		return NewKafkaPublisher(producer), nil
+
+	case TransportNATS, TransportJetStream:
+		client, err := natslib.Connect(ctx, config.NATS, logger)
+		if err != nil {
+			return nil, fmt.Errorf("connecting to nats: %w", err)
+		}
+		if config.transport() == TransportNATS {
+			return NewNATSPublisher(client), nil
+		}
+		return NewJetStreamPublisher(client), nil
+
+	case TransportMemory:
+		return defaultBroker.NewPublisher(), nil
+
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownTransport, config.Transport)
+	}
+}
+
+// NewSubscriber connects the subscriber of the configured transport.
+// The NATS connection of the subscriber is closed when ctx is done.
+func NewSubscriber(ctx context.Context, config Config, logger *slog.Logger) (Subscriber, error) {
+	switch config.transport() {
+	case TransportKafka:
+		consumer, err := kafkalib.NewConsumer(kafkalib.ConsumerConfig{
+			Config:  config.Kafka,
+			GroupID: config.Group,
+			Topics:  config.Topics,
+		}, logger)
+		if err != nil {
+			return nil, fmt.Errorf("creating kafka consumer: %w", err)
+		}
+		return NewKafkaSubscriber(consumer, logger), nil
+
+	case TransportNATS:
+		client, err := connectNATS(ctx, config.NATS, logger)
+		if err != nil {
+			return nil, err
+		}
+		return NewNATSSubscriber(client, config.Topics, config.Group, logger), nil
+
+	case TransportJetStream:
+		return newJetStreamSubscriber(ctx, config, logger)
+
+	case TransportMemory:
+		return defaultBroker.NewSubscriber(config.Topics, config.Group, logger), nil
+
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownTransport, config.Transport)
+	}
+}
+
+func newJetStreamSubscriber(ctx context.Context, config Config, logger *slog.Logger) (*JetStreamSubscriber, error) {
+	if len(config.Topics) > 1 {
+		return nil, fmt.Errorf("jetstream consumer filters at most one subject, got %d", len(config.Topics))
+	}
+
+	client, err := connectNATS(ctx, config.NATS, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	consumerConfig := config.JetStream.Consumer
+	consumerConfig.Stream = config.JetStream.Stream
+	consumerConfig.Durable = config.Group
+	// pull consumer
+	consumerConfig.DeliverSubject, consumerConfig.DeliverGroup = "", ""
+	if len(config.Topics) == 1 {
+		consumerConfig.FilterSubject = config.Topics[0]
+	}
+
+	if err := client.EnsureConsumer(ctx, consumerConfig); err != nil {
+		return nil, fmt.Errorf("ensuring jetstream consumer: %w", err)
+	}
+
+	return NewJetStreamSubscriber(client, config.JetStream.Stream, config.Group, config.JetStream.Batch, logger), nil
+}
+
+func connectNATS(ctx context.Context, config natslib.Config, logger *slog.Logger) (*natslib.Client, error) {
+	client, err := natslib.Connect(ctx, config, logger)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to nats: %w", err)
+	}
+
+	go func() {
+		<-ctx.Done()
+		client.Close()
+	}()
+
+	return client, nil
+}
+
+// Validate implements config.Validator, the Kafka config is checked with the kafka transport only.
+func (c Config) Validate() error {
+	if c.transport() != TransportKafka {
+		return nil
+	}
+
+	if err := config.Validate(c.Kafka); err != nil {
+		return config.NewFieldError("kafka", err)
+	}
+
+	return nil
+}
+
+func (c Config) transport() string {
+	if c.Transport == "" {
+		return TransportMemory
+	}
+
+	return c.Transport
+}
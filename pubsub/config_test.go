@@ -0,0 +1,75 @@
+package pubsub
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/yvyrovyi-cinemo/utils/config"
+	"github.com/yvyrovyi-cinemo/utils/natslib"
+	"github.com/yvyrovyi-cinemo/utils/natslib/natslibtest"
+)
+
+func TestNewPublisher_Transports(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	srv := natslibtest.NewServer(t, natslibtest.WithJetStream())
+	require.NoError(t, srv.Connect().EnsureStream(ctx, natslib.StreamConfig{
+		Name:     "EVENTS",
+		Subjects: []string{"events.>"},
+		Storage:  "memory",
+	}))
+
+	for _, transport := range []string{TransportMemory, TransportNATS, TransportJetStream} {
+		config := Config{
+			Transport: transport,
+			Topics:    []string{"events." + transport},
+			Group:     "config-test",
+			NATS:      srv.Config(),
+			JetStream: JetStreamConfig{Stream: "EVENTS"},
+		}
+
+		subscriber, err := NewSubscriber(ctx, config, slog.Default())
+		require.NoError(t, err, transport)
+		chanMessages := runSubscriber(ctx, t, subscriber)
+
+		publisher, err := NewPublisher(ctx, config, slog.Default())
+		require.NoError(t, err, transport)
+
+		// core NATS and memory deliver to the running subscribers only
+		require.Eventually(t, func() bool {
+			require.NoError(t, publisher.Publish(ctx, &Message{Topic: "events." + transport, Payload: []byte(transport)}))
+			return len(chanMessages) > 0
+		}, 5*time.Second, 100*time.Millisecond, transport)
+
+		require.Equal(t, transport, string(requireMessage(t, chanMessages).Payload))
+		require.NoError(t, publisher.Close())
+	}
+}
+
+func TestNewPublisher_UnknownTransport(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewPublisher(context.Background(), Config{Transport: "amqp"}, slog.Default())
+	require.ErrorIs(t, err, ErrUnknownTransport)
+
+	_, err = NewSubscriber(context.Background(), Config{Transport: "amqp"}, slog.Default())
+	require.ErrorIs(t, err, ErrUnknownTransport)
+}
+
+func TestConfig_Validate(t *testing.T) {
+	t.Parallel()
+
+	// the kafka config is not required by the other transports
+	require.NoError(t, config.Validate(Config{Transport: TransportNATS}))
+
+	err := config.Validate(Config{Transport: TransportKafka})
+	require.ErrorIs(t, err, config.ErrRequired)
+	require.EqualError(t, err, "invalid config: kafka.brokers: required")
+}
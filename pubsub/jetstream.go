@@ -0,0 +1,134 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/yvyrovyi-cinemo/utils/natslib"
+)
+
+type (
+	JetStreamPublisher struct {
+		client *natslib.Client
+	}
+
+	JetStreamSubscriber struct {
+		client  *natslib.Client
+		stream  string
+		durable string
+		batch   int
+		logger  *slog.Logger
+	}
+)
+
+const (
+	// DefaultJetStreamBatch is the number of messages fetched at once.
+	DefaultJetStreamBatch = 100
+
+	// fetchTimeout limits a fetch waiting for new messages, so that the fetch requests are renewed.
+	fetchTimeout = 5 * time.Second
+)
+
+// NewJetStreamPublisher returns the JetStream publisher owning the client, it is closed by Close.
+// Publish waits for the stream acknowledgement.
+func NewJetStreamPublisher(client *natslib.Client) *JetStreamPublisher {
+	return &JetStreamPublisher{client: client}
+}
+
+func (p *JetStreamPublisher) Publish(ctx context.Context, msg *Message) error {
+	if _, err := p.client.PublishJSMsg(ctx, toNATSMessage(msg)); err != nil {
+		return natsError(err)
+	}
+
+	return nil
+}
+
+// Close drains the client, so the buffered messages are sent.
+func (p *JetStreamPublisher) Close() error {
+	if err := p.client.Shutdown(context.Background()); err != nil {
+		return natsError(err)
+	}
+
+	return nil
+}
+
+// NewJetStreamSubscriber returns the subscriber of the existing durable pull consumer of the stream.
+// The messages are fetched in batches of the size, DefaultJetStreamBatch when it is not positive.
+func NewJetStreamSubscriber(
+	client *natslib.Client,
+	stream, durable string,
+	batch int,
+	logger *slog.Logger,
+) *JetStreamSubscriber {
+	if batch <= 0 {
+		batch = DefaultJetStreamBatch
+	}
+
+	return &JetStreamSubscriber{
+		client:  client,
+		stream:  stream,
+		durable: durable,
+		batch:   batch,
+		logger:  logger.With(LogsLabelComponent, "pubsub-jetstream", "stream", stream, "consumer", durable),
+	}
+}
+
+// Run handles the messages of the consumer one by one. A handled message is acknowledged
+// and a failed one is redelivered, the handler errors are logged.
+func (s *JetStreamSubscriber) Run(ctx context.Context, handler Handler) error {
+	consumer, err := s.client.PullConsumer(ctx, s.stream, s.durable)
+	if err != nil {
+		return natsError(err)
+	}
+
+	for {
+		messages, err := s.fetch(ctx, consumer)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return natsError(err)
+		}
+
+		for _, jsMsg := range messages {
+			if err := s.handle(ctx, handler, jsMsg); err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return natsError(err)
+			}
+		}
+	}
+}
+
+func (s *JetStreamSubscriber) fetch(ctx context.Context, consumer *natslib.PullConsumer) ([]*natslib.JSMessage, error) {
+	fetchCtx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	defer cancel()
+
+	messages, err := consumer.Fetch(fetchCtx, s.batch)
+	if err != nil && errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+		// no new messages
+		return nil, nil
+	}
+
+	return messages, err
+}
+
+// handle returns the acknowledgement errors only.
+func (s *JetStreamSubscriber) handle(ctx context.Context, handler Handler, jsMsg *natslib.JSMessage) error {
+	msg := fromNATSMessage(&jsMsg.Message)
+	msg.Metadata = Metadata{
+		Offset:     int64(jsMsg.Sequence),
+		Timestamp:  jsMsg.Timestamp,
+		Deliveries: int(jsMsg.NumDelivered),
+	}
+
+	if err := handle(ctx, handler, msg, s.logger); err != nil {
+		s.logger.Error("failed to handle message", "topic", msg.Topic, "deliveries", jsMsg.NumDelivered, "error", err)
+		return jsMsg.Nak()
+	}
+
+	return jsMsg.Ack(ctx)
+}
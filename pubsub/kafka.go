@@ -0,0 +1,119 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"github.com/yvyrovyi-cinemo/utils/kafkalib"
+)
+
+type (
+	// KafkaProducer is satisfied by kafkalib.Producer and kafkalibtest.Producer.
+	KafkaProducer interface {
+		ProduceSync(ctx context.Context, msg *kafkalib.Message) (int32, int64, error)
+		Close() error
+	}
+
+	// KafkaConsumer is satisfied by kafkalib.Consumer and kafkalibtest.Consumer.
+	// The consumer must commit the offsets automatically, not with ManualCommit.
+	KafkaConsumer interface {
+		Run(ctx context.Context, handler kafkalib.MessageHandler) error
+	}
+
+	KafkaPublisher struct {
+		producer KafkaProducer
+	}
+
+	KafkaSubscriber struct {
+		consumer KafkaConsumer
+		logger   *slog.Logger
+	}
+)
+
+// NewKafkaPublisher returns the publisher owning the producer, it is closed by Close.
+func NewKafkaPublisher(producer KafkaProducer) *KafkaPublisher {
+	return &KafkaPublisher{producer: producer}
+}
+
+func (p *KafkaPublisher) Publish(ctx context.Context, msg *Message) error {
+	_, _, err := p.producer.ProduceSync(ctx, &kafkalib.Message{
+		Topic:   msg.Topic,
+		Key:     msg.Key,
+		Payload: msg.Payload,
+		Headers: toKafkaHeaders(msg.Headers),
+	})
+	if err != nil {
+		return fmt.Errorf("producing to %s: %w", msg.Topic, err)
+	}
+
+	return nil
+}
+
+func (p *KafkaPublisher) Close() error {
+	return p.producer.Close()
+}
+
+func NewKafkaSubscriber(consumer KafkaConsumer, logger *slog.Logger) *KafkaSubscriber {
+	return &KafkaSubscriber{
+		consumer: consumer,
+		logger:   logger.With(LogsLabelComponent, "pubsub-kafka"),
+	}
+}
+
+// Run consumes the topics of the consumer, the offset of a message is committed after it is handled.
+func (s *KafkaSubscriber) Run(ctx context.Context, handler Handler) error {
+	return s.consumer.Run(ctx, func(ctx context.Context, msg *kafkalib.Message) error {
+		res := &Message{
+			Topic:   msg.Topic,
+			Key:     msg.Key,
+			Headers: fromKafkaHeaders(msg.Headers),
+			Payload: msg.Payload,
+			Metadata: Metadata{
+				Offset:     msg.Offset,
+				Timestamp:  msg.Timestamp,
+				Deliveries: 1,
+			},
+		}
+		if msg.Partition != nil {
+			res.Metadata.Partition = *msg.Partition
+		}
+
+		return handle(ctx, handler, res, s.logger)
+	})
+}
+
+func toKafkaHeaders(headers map[string]string) []kafkalib.Header {
+	if len(headers) == 0 {
+		return nil
+	}
+
+	res := make([]kafkalib.Header, 0, len(headers))
+	for key, value := range headers {
+		res = append(res, kafkalib.Header{Key: key, Value: []byte(value)})
+	}
+
+	// keep the order stable
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Key < res[j].Key
+	})
+
+	return res
+}
+
+// fromKafkaHeaders keeps the first value of the repeated headers.
+func fromKafkaHeaders(headers []kafkalib.Header) map[string]string {
+	if len(headers) == 0 {
+		return nil
+	}
+
+	res := make(map[string]string, len(headers))
+	for _, h := range headers {
+		if _, ok := res[h.Key]; !ok {
+			res[h.Key] = string(h.Value)
+		}
+	}
+
+	return res
+}
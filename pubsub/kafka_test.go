@@ -0,0 +1,89 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/yvyrovyi-cinemo/utils/kafkalib"
+	"github.com/yvyrovyi-cinemo/utils/kafkalib/kafkalibtest"
+)
+
+func TestKafka(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cluster := kafkalibtest.NewCluster()
+	require.NoError(t, cluster.CreateTopic("orders", 1))
+
+	publisher := NewKafkaPublisher(cluster.NewProducer())
+	require.NoError(t, publisher.Publish(ctx, &Message{
+		Topic:   "orders",
+		Key:     []byte("order-1"),
+		Headers: map[string]string{"trace": "abc", "source": "test"},
+		Payload: []byte("1"),
+	}))
+	require.NoError(t, publisher.Publish(ctx, &Message{Topic: "orders", Payload: []byte("2")}))
+
+	stored := cluster.Messages("orders")
+	require.Len(t, stored, 2)
+	require.Equal(t, []kafkalib.Header{
+		{Key: "source", Value: []byte("test")},
+		{Key: "trace", Value: []byte("abc")},
+	}, stored[0].Headers)
+
+	consumerConfig := kafkalib.ConsumerConfig{GroupID: "billing", Topics: []string{"orders"}, InitialOffset: "oldest"}
+	subscriber := NewKafkaSubscriber(cluster.NewConsumer(consumerConfig), slog.Default())
+
+	chanMessages := runSubscriber(ctx, t, subscriber)
+
+	msg := requireMessage(t, chanMessages)
+	require.Equal(t, []byte("order-1"), msg.Key)
+	require.Equal(t, map[string]string{"trace": "abc", "source": "test"}, msg.Headers)
+	require.Equal(t, Metadata{Partition: 0, Offset: 0, Deliveries: 1, Timestamp: msg.Metadata.Timestamp}, msg.Metadata)
+	require.Equal(t, int64(1), requireMessage(t, chanMessages).Metadata.Offset)
+
+	offset, ok := cluster.CommittedOffset("billing", "orders", 0)
+	require.True(t, ok)
+	require.Equal(t, int64(2), offset)
+
+	require.NoError(t, publisher.Close())
+	require.Error(t, publisher.Publish(ctx, &Message{Topic: "orders"}))
+}
+
+func TestKafkaSubscriber_Errors(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	cluster := kafkalibtest.NewCluster()
+	require.NoError(t, cluster.CreateTopic("jobs", 1))
+
+	publisher := NewKafkaPublisher(cluster.NewProducer())
+	for _, payload := range []string{"poison", "fail"} {
+		require.NoError(t, publisher.Publish(ctx, &Message{Topic: "jobs", Payload: []byte(payload)}))
+	}
+
+	consumerConfig := kafkalib.ConsumerConfig{GroupID: "workers", Topics: []string{"jobs"}, InitialOffset: "oldest"}
+	subscriber := NewKafkaSubscriber(cluster.NewConsumer(consumerConfig), slog.Default())
+
+	errFailed := errors.New("failed")
+	err := subscriber.Run(ctx, func(_ context.Context, msg *Message) error {
+		if string(msg.Payload) == "poison" {
+			return Permanent(errFailed)
+		}
+		return errFailed
+	})
+	require.ErrorIs(t, err, errFailed)
+	require.NotErrorIs(t, err, ErrPermanent)
+
+	// the permanent failure is committed, the other one is consumed again
+	offset, ok := cluster.CommittedOffset("workers", "jobs", 0)
+	require.True(t, ok)
+	require.Equal(t, int64(1), offset)
+}
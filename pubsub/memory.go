@@ -0,0 +1,192 @@
+package pubsub
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/yvyrovyi-cinemo/utils/natslib"
+)
+
+type (
+	// MemoryBroker delivers the messages within the process, e.g. for tests and local runs.
+	// Like core NATS, a message is delivered to the subscribers running when it is published:
+	// to every subscriber without a group and to one subscriber of every group.
+	// Topics of the subscribers may contain NATS wildcards.
+	MemoryBroker struct {
+		mux           sync.Mutex
+		subscriptions []*memorySubscription
+		offsets       map[string]int64
+		// next is the round-robin counter of every group
+		next map[string]int
+	}
+
+	MemoryPublisher struct {
+		broker *MemoryBroker
+		closed atomic.Bool
+	}
+
+	MemorySubscriber struct {
+		broker *MemoryBroker
+		topics []string
+		group  string
+		logger *slog.Logger
+	}
+
+	memorySubscription struct {
+		topics       []string
+		group        string
+		chanMessages chan *Message
+		done         chan struct{}
+	}
+)
+
+// memoryQueueSize is the number of messages a subscriber may lag behind before Publish blocks.
+const memoryQueueSize = 1024
+
+// defaultBroker is used by the memory transport of NewPublisher and NewSubscriber.
+var defaultBroker = NewMemoryBroker()
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{
+		offsets: make(map[string]int64),
+		next:    make(map[string]int),
+	}
+}
+
+func (b *MemoryBroker) NewPublisher() *MemoryPublisher {
+	return &MemoryPublisher{broker: b}
+}
+
+// NewSubscriber returns the subscriber of the topics, the subscribers of the same non-empty group share the messages.
+func (b *MemoryBroker) NewSubscriber(topics []string, group string, logger *slog.Logger) *MemorySubscriber {
+	return &MemorySubscriber{
+		broker: b,
+		topics: topics,
+		group:  group,
+		logger: logger.With(LogsLabelComponent, "pubsub-memory"),
+	}
+}
+
+// Publish waits until the message is queued to the subscribers, it is dropped when there are none.
+func (p *MemoryPublisher) Publish(ctx context.Context, msg *Message) error {
+	if p.closed.Load() {
+		return ErrClosed
+	}
+
+	targets, metadata := p.broker.route(msg.Topic)
+
+	for _, sub := range targets {
+		res := &Message{
+			Topic:    msg.Topic,
+			Key:      slices.Clone(msg.Key),
+			Headers:  maps.Clone(msg.Headers),
+			Payload:  slices.Clone(msg.Payload),
+			Metadata: metadata,
+		}
+
+		select {
+		case sub.chanMessages <- res:
+		case <-sub.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+func (p *MemoryPublisher) Close() error {
+	p.closed.Store(true)
+	return nil
+}
+
+// Run handles the messages published while it runs.
+func (s *MemorySubscriber) Run(ctx context.Context, handler Handler) error {
+	sub := &memorySubscription{
+		topics:       s.topics,
+		group:        s.group,
+		chanMessages: make(chan *Message, memoryQueueSize),
+		done:         make(chan struct{}),
+	}
+
+	s.broker.subscribe(sub)
+	defer s.broker.unsubscribe(sub)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg := <-sub.chanMessages:
+			if err := handle(ctx, handler, msg, s.logger); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (b *MemoryBroker) subscribe(sub *memorySubscription) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	b.subscriptions = append(b.subscriptions, sub)
+}
+
+func (b *MemoryBroker) unsubscribe(sub *memorySubscription) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	close(sub.done)
+	b.subscriptions = slices.DeleteFunc(b.subscriptions, func(s *memorySubscription) bool {
+		return s == sub
+	})
+}
+
+// route returns the subscriptions the message of the topic is delivered to.
+func (b *MemoryBroker) route(topic string) ([]*memorySubscription, Metadata) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	b.offsets[topic]++
+	metadata := Metadata{
+		Offset:     b.offsets[topic],
+		Timestamp:  time.Now(),
+		Deliveries: 1,
+	}
+
+	var res []*memorySubscription
+	groups := make(map[string][]*memorySubscription)
+
+	for _, sub := range b.subscriptions {
+		if !sub.matches(topic) {
+			continue
+		}
+
+		if sub.group == "" {
+			res = append(res, sub)
+		} else {
+			groups[sub.group] = append(groups[sub.group], sub)
+		}
+	}
+
+	for group, members := range groups {
+		res = append(res, members[b.next[group]%len(members)])
+		b.next[group]++
+	}
+
+	return res, metadata
+}
+
+func (s *memorySubscription) matches(topic string) bool {
+	for _, pattern := range s.topics {
+		if natslib.MatchSubject(pattern, topic) {
+			return true
+		}
+	}
+
+	return false
+}
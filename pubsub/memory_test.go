@@ -0,0 +1,134 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryBroker(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	broker := NewMemoryBroker()
+
+	all := runSubscriber(ctx, t, broker.NewSubscriber([]string{"orders.>"}, "", slog.Default()))
+	groupA := runSubscriber(ctx, t, broker.NewSubscriber([]string{"orders.*"}, "billing", slog.Default()))
+	groupB := runSubscriber(ctx, t, broker.NewSubscriber([]string{"orders.*"}, "billing", slog.Default()))
+	waitSubscribers(t, broker, 3)
+
+	publisher := broker.NewPublisher()
+	for _, payload := range []string{"1", "2"} {
+		require.NoError(t, publisher.Publish(ctx, &Message{
+			Topic:   "orders.created",
+			Key:     []byte("order"),
+			Headers: map[string]string{"trace": "abc"},
+			Payload: []byte(payload),
+		}))
+	}
+	require.NoError(t, publisher.Publish(ctx, &Message{Topic: "other", Payload: []byte("3")}))
+
+	first := requireMessage(t, all)
+	require.Equal(t, "orders.created", first.Topic)
+	require.Equal(t, []byte("order"), first.Key)
+	require.Equal(t, map[string]string{"trace": "abc"}, first.Headers)
+	require.Equal(t, Metadata{Offset: 1, Timestamp: first.Metadata.Timestamp, Deliveries: 1}, first.Metadata)
+	require.Equal(t, "2", string(requireMessage(t, all).Payload))
+
+	// the group members share the messages
+	require.ElementsMatch(t, []string{"1", "2"}, []string{
+		string(requireMessage(t, groupA).Payload),
+		string(requireMessage(t, groupB).Payload),
+	})
+
+	require.NoError(t, publisher.Close())
+	require.ErrorIs(t, publisher.Publish(ctx, &Message{Topic: "orders.created"}), ErrClosed)
+}
+
+func TestMemorySubscriber_Errors(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	broker := NewMemoryBroker()
+	subscriber := broker.NewSubscriber([]string{"jobs"}, "", slog.Default())
+
+	errFailed := errors.New("failed")
+	var handled []string
+
+	chanErr := make(chan error)
+	go func() {
+		chanErr <- subscriber.Run(ctx, func(_ context.Context, msg *Message) error {
+			handled = append(handled, string(msg.Payload))
+
+			switch string(msg.Payload) {
+			case "poison":
+				return Permanent(errors.New("malformed"))
+			case "fail":
+				return errFailed
+			}
+			return nil
+		})
+	}()
+	waitSubscribers(t, broker, 1)
+
+	publisher := broker.NewPublisher()
+	for _, payload := range []string{"poison", "ok", "fail"} {
+		require.NoError(t, publisher.Publish(ctx, &Message{Topic: "jobs", Payload: []byte(payload)}))
+	}
+
+	select {
+	case err := <-chanErr:
+		require.ErrorIs(t, err, errFailed)
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "subscriber is not stopped")
+	}
+
+	require.Equal(t, []string{"poison", "ok", "fail"}, handled)
+	waitSubscribers(t, broker, 0)
+}
+
+// runSubscriber runs the subscriber until ctx is done and returns the received messages.
+func runSubscriber(ctx context.Context, t *testing.T, subscriber Subscriber) <-chan *Message {
+	t.Helper()
+
+	chanMessages := make(chan *Message, 100)
+	go func() {
+		err := subscriber.Run(ctx, func(_ context.Context, msg *Message) error {
+			chanMessages <- msg
+			return nil
+		})
+		require.NoError(t, err)
+	}()
+
+	return chanMessages
+}
+
+func requireMessage(t *testing.T, chanMessages <-chan *Message) *Message {
+	t.Helper()
+
+	select {
+	case msg := <-chanMessages:
+		return msg
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "no message")
+		return nil
+	}
+}
+
+func waitSubscribers(t *testing.T, broker *MemoryBroker, n int) {
+	t.Helper()
+
+	require.Eventually(t, func() bool {
+		broker.mux.Lock()
+		defer broker.mux.Unlock()
+
+		return len(broker.subscriptions) == n
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,167 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/yvyrovyi-cinemo/utils/natslib"
+)
+
+type (
+	NATSPublisher struct {
+		client *natslib.Client
+	}
+
+	NATSSubscriber struct {
+		client   *natslib.Client
+		subjects []string
+		queue    string
+		logger   *slog.Logger
+	}
+)
+
+// NewNATSPublisher returns the core NATS publisher owning the client, it is closed by Close.
+func NewNATSPublisher(client *natslib.Client) *NATSPublisher {
+	return &NATSPublisher{client: client}
+}
+
+func (p *NATSPublisher) Publish(ctx context.Context, msg *Message) error {
+	if err := p.client.PublishMsg(ctx, toNATSMessage(msg)); err != nil {
+		return natsError(err)
+	}
+
+	return nil
+}
+
+// Close drains the client, so the buffered messages are sent.
+func (p *NATSPublisher) Close() error {
+	if err := p.client.Shutdown(context.Background()); err != nil {
+		return natsError(err)
+	}
+
+	return nil
+}
+
+// NewNATSSubscriber returns the core NATS subscriber of the subjects,
+// the subscribers of the same non-empty queue group share the messages.
+func NewNATSSubscriber(client *natslib.Client, subjects []string, queue string, logger *slog.Logger) *NATSSubscriber {
+	return &NATSSubscriber{
+		client:   client,
+		subjects: subjects,
+		queue:    queue,
+		logger:   logger.With(LogsLabelComponent, "pubsub-nats"),
+	}
+}
+
+// Run handles the messages published while it runs, the handler errors are logged.
+// Every subject is handled in its own goroutine.
+func (s *NATSSubscriber) Run(ctx context.Context, handler Handler) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	subscriptions := make([]*natslib.Subscription, 0, len(s.subjects))
+	for _, subject := range s.subjects {
+		sub, err := s.client.QueueSubscribe(ctx, subject, s.queue)
+		if err != nil {
+			return natsError(err)
+		}
+		subscriptions = append(subscriptions, sub)
+	}
+
+	var (
+		wg      sync.WaitGroup
+		errOnce sync.Once
+		resErr  error
+	)
+
+	for _, sub := range subscriptions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if err := s.receive(ctx, sub, handler); err != nil {
+				errOnce.Do(func() { resErr = err })
+				cancel()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	return resErr
+}
+
+func (s *NATSSubscriber) receive(ctx context.Context, sub *natslib.Subscription, handler Handler) error {
+	for {
+		natsMsg, err := sub.ReceiveMsg(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return natsError(err)
+		}
+
+		msg := fromNATSMessage(natsMsg)
+		msg.Metadata.Deliveries = 1
+
+		if err := handle(ctx, handler, msg, s.logger); err != nil {
+			s.logger.Error("failed to handle message", "topic", msg.Topic, "error", err)
+		}
+	}
+}
+
+func toNATSMessage(msg *Message) *natslib.Message {
+	res := &natslib.Message{
+		Subject: msg.Topic,
+		Data:    msg.Payload,
+	}
+
+	if len(msg.Headers) > 0 || msg.Key != nil {
+		res.Header = make(map[string][]string, len(msg.Headers)+1)
+		for key, value := range msg.Headers {
+			res.Header.Set(key, value)
+		}
+		if msg.Key != nil {
+			res.Header.Set(KeyHeader, string(msg.Key))
+		}
+	}
+
+	return res
+}
+
+// fromNATSMessage keeps the first value of the repeated headers.
+func fromNATSMessage(msg *natslib.Message) *Message {
+	res := &Message{
+		Topic:   msg.Subject,
+		Payload: msg.Data,
+	}
+
+	for key, values := range msg.Header {
+		if len(values) == 0 {
+			continue
+		}
+
+		if key == KeyHeader {
+			res.Key = []byte(values[0])
+			continue
+		}
+
+		if res.Headers == nil {
+			res.Headers = make(map[string]string, len(msg.Header))
+		}
+		res.Headers[key] = values[0]
+	}
+
+	return res
+}
+
+func natsError(err error) error {
+	if errors.Is(err, natslib.ErrClosed) {
+		return ErrClosed
+	}
+
+	return fmt.Errorf("nats: %w", err)
+}
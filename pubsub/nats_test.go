@@ -0,0 +1,110 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/yvyrovyi-cinemo/utils/natslib"
+	"github.com/yvyrovyi-cinemo/utils/natslib/natslibtest"
+)
+
+func TestNATS(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	srv := natslibtest.NewServer(t)
+	capture := srv.Capture("orders.>")
+
+	chanMessages := runSubscriber(ctx, t, NewNATSSubscriber(srv.Connect(), []string{"orders.*"}, "", slog.Default()))
+	// the subscriptions are made when Run starts
+	time.Sleep(100 * time.Millisecond)
+
+	publisher := NewNATSPublisher(srv.Connect())
+	require.NoError(t, publisher.Publish(ctx, &Message{
+		Topic:   "orders.created",
+		Key:     []byte("order-1"),
+		Headers: map[string]string{"trace": "abc"},
+		Payload: []byte("1"),
+	}))
+
+	msg := requireMessage(t, chanMessages)
+	require.Equal(t, "orders.created", msg.Topic)
+	require.Equal(t, []byte("order-1"), msg.Key)
+	require.Equal(t, map[string]string{"trace": "abc"}, msg.Headers)
+	require.Equal(t, []byte("1"), msg.Payload)
+
+	captured := capture.Wait(1)
+	require.Equal(t, "order-1", captured[0].Header.Get(KeyHeader))
+
+	require.NoError(t, publisher.Close())
+	require.ErrorIs(t, publisher.Publish(ctx, &Message{Topic: "orders.created"}), ErrClosed)
+}
+
+func TestJetStream(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	srv := natslibtest.NewServer(t, natslibtest.WithJetStream())
+	client := srv.Connect()
+
+	require.NoError(t, client.EnsureStream(ctx, natslib.StreamConfig{
+		Name:     "JOBS",
+		Subjects: []string{"jobs.>"},
+		Storage:  "memory",
+	}))
+	require.NoError(t, client.EnsureConsumer(ctx, natslib.ConsumerConfig{
+		Stream:  "JOBS",
+		Durable: "workers",
+		AckWait: time.Second,
+	}))
+
+	publisher := NewJetStreamPublisher(srv.Connect())
+	for _, payload := range []string{"poison", "retry", "ok"} {
+		require.NoError(t, publisher.Publish(ctx, &Message{
+			Topic:   "jobs.run",
+			Headers: map[string]string{"attempt": "first"},
+			Payload: []byte(payload),
+		}))
+	}
+
+	chanMessages := make(chan *Message, 100)
+	subscriber := NewJetStreamSubscriber(client, "JOBS", "workers", 10, slog.Default())
+	go func() {
+		err := subscriber.Run(ctx, func(_ context.Context, msg *Message) error {
+			chanMessages <- msg
+
+			switch {
+			case string(msg.Payload) == "poison":
+				return Permanent(errors.New("malformed"))
+			case string(msg.Payload) == "retry" && msg.Metadata.Deliveries == 1:
+				return errors.New("not yet")
+			}
+			return nil
+		})
+		require.NoError(t, err)
+	}()
+
+	var got []string
+	for len(got) < 4 {
+		msg := requireMessage(t, chanMessages)
+		require.Equal(t, map[string]string{"attempt": "first"}, msg.Headers)
+		got = append(got, string(msg.Payload))
+	}
+
+	// only the failed message is redelivered
+	require.Equal(t, []string{"poison", "retry", "ok", "retry"}, got)
+	select {
+	case msg := <-chanMessages:
+		require.Fail(t, "unexpected redelivery", string(msg.Payload))
+	case <-time.After(1500 * time.Millisecond):
+	}
+}
@@ -0,0 +1,87 @@
+// Package pubsub publishes and subscribes through Kafka, NATS, NATS JetStream or memory
+// behind the same interfaces, so the transport is chosen by the config.
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+type (
+	// Message is the transport independent message.
+	Message struct {
+		// Topic is the Kafka topic or the NATS subject.
+		Topic string
+		// Key is the Kafka message key. NATS has no keys, so it is sent in the KeyHeader there.
+		Key     []byte
+		Headers map[string]string
+		Payload []byte
+
+		// Metadata is filled for the received messages only.
+		Metadata Metadata
+	}
+
+	// Metadata describes where the received message comes from, the fields unknown to the transport are zero.
+	Metadata struct {
+		// Partition is the Kafka partition.
+		Partition int32
+		// Offset is the Kafka offset, the JetStream stream sequence or the memory topic sequence.
+		Offset    int64
+		Timestamp time.Time
+		// Deliveries is the number of delivery attempts, starting from 1.
+		Deliveries int
+	}
+
+	// Handler handles a received message, nil acknowledges it.
+	//
+	// An error means the message is not handled: JetStream redelivers it,
+	// Kafka and memory stop Subscriber.Run with the error, so the message is not committed,
+	// core NATS only logs the error as it has no redelivery.
+	// An error wrapping ErrPermanent acknowledges the message without redelivery and is logged,
+	// e.g. for a message which can never be handled.
+	Handler func(ctx context.Context, msg *Message) error
+
+	Publisher interface {
+		// Publish sends the message and waits until the transport accepted it.
+		Publish(ctx context.Context, msg *Message) error
+		Close() error
+	}
+
+	Subscriber interface {
+		// Run calls the handler for the messages of the subscribed topics until ctx is done or the handling fails.
+		Run(ctx context.Context, handler Handler) error
+	}
+)
+
+// KeyHeader carries Message.Key in the NATS headers.
+const KeyHeader = "Pubsub-Key"
+
+const LogsLabelComponent = "component"
+
+var (
+	// ErrPermanent marks handler errors which must not cause redelivery.
+	ErrPermanent = errors.New("permanent failure")
+
+	ErrUnknownTransport = errors.New("unknown transport")
+	ErrClosed           = errors.New("pubsub is closed")
+)
+
+// Permanent wraps the handler error into ErrPermanent.
+func Permanent(err error) error {
+	return fmt.Errorf("%w: %w", ErrPermanent, err)
+}
+
+// handle calls the handler, the message must be acknowledged when it returns nil.
+// Permanent errors are logged and nil is returned for them.
+func handle(ctx context.Context, handler Handler, msg *Message, logger *slog.Logger) error {
+	err := handler(ctx, msg)
+	if err != nil && errors.Is(err, ErrPermanent) {
+		logger.Error("dropping message", "topic", msg.Topic, "error", err)
+		return nil
+	}
+
+	return err
+}